@@ -5,6 +5,9 @@ package main
 // @description SSO service API.
 // @BasePath /
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"log/slog"
 	"os"
 
@@ -18,6 +21,18 @@ func main() {
 		slog.Error("Could not load config", "err", err)
 		os.Exit(1)
 	}
+	// с аргументами - административная подкоманда вместо сервера
+	if len(os.Args) > 1 {
+		if err := internal.Command(cfg, os.Args[1:]); err != nil {
+			if errors.Is(err, internal.ErrUsage) || errors.Is(err, flag.ErrHelp) {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(2)
+			}
+			slog.Error("Command failed", "err", err)
+			os.Exit(1)
+		}
+		return
+	}
 	if err := internal.Run(cfg); err != nil {
 		slog.Error("Failed to run server", "err", err)
 		os.Exit(1)
@@ -15,8 +15,331 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/auth/logIn": {
+        "/.well-known/jwks.json": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "well-known"
+                ],
+                "summary": "Public keys for token signature verification",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_lib_jwt.JWKS"
+                        }
+                    }
+                }
+            }
+        },
+        "/.well-known/openid-configuration": {
+            "get": {
+                "description": "С ключом подписи HMAC OpenID Connect выключен: 404",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "well-known"
+                ],
+                "summary": "OpenID Connect discovery document",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_oidc.ProviderMetadata"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    }
+                }
+            }
+        },
+        "/admin/clients": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List OAuth clients",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-array_github_com_phenirain_sso_internal_dto_client_ClientResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Register OAuth client",
+                "parameters": [
+                    {
+                        "description": "Client settings",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_client.ClientRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_client_ClientResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/clients/{id}": {
+            "put": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Update OAuth client settings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Client id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Client settings",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_client.ClientRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_client_ClientResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/clients/{id}/disable": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Disable OAuth client",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Client id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_client_ClientResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/clients/{id}/enable": {
             "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Enable OAuth client",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Client id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_client_ClientResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/clients/{id}/secret": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Issue new secret for OAuth client",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Client id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_client_ClientResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/lockout/unlock": {
+            "post": {
+                "description": "Обнуляет счётчик неудачных попыток входа по логину и (или) с IP адреса",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Unlock login or IP address after failed login attempts",
+                "parameters": [
+                    {
+                        "description": "Login and/or IP address",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.UnlockRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/contact/resend": {
+            "post": {
+                "description": "Ответ одинаковый независимо от того, существует ли пользователь.\nПовторный код в тот же канал отправляется не чаще раза в минуту, запросы с одного IP ограничены: 429 и Retry-After",
                 "consumes": [
                     "application/json"
                 ],
@@ -26,15 +349,15 @@ const docTemplate = `{
                 "tags": [
                     "auth"
                 ],
-                "summary": "Login user",
+                "summary": "Send a new verification code",
                 "parameters": [
                     {
-                        "description": "Credentials",
+                        "description": "Login and channel",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.AuthRequest"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.ResendCodeRequest"
                         }
                     }
                 ],
@@ -42,33 +365,66 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.AuthResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
                         }
                     }
                 }
             }
         },
-        "/auth/refresh": {
+        "/auth/contact/verify": {
             "post": {
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "auth"
                 ],
-                "summary": "Refresh access token",
+                "summary": "Verify email or phone with code",
+                "parameters": [
+                    {
+                        "description": "Login, channel and code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.VerifyContactRequest"
+                        }
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.AuthResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
                         }
                     }
                 }
             }
         },
-        "/auth/signUp": {
+        "/auth/logIn": {
             "post": {
+                "description": "С подключённым TOTP или добавленным ключом вместо токенов возвращает mfa_token для /auth/mfa/verify или /auth/mfa/passkey/finish.\nПосле серии неверных паролей вход временно блокируется: 429 и заголовок Retry-After\nЕсли требуется подтверждение контактов, а пользователь их не подтвердил - 403",
                 "consumes": [
                     "application/json"
                 ],
@@ -78,7 +434,7 @@ const docTemplate = `{
                 "tags": [
                     "auth"
                 ],
-                "summary": "Register user",
+                "summary": "Login user",
                 "parameters": [
                     {
                         "description": "Credentials",
@@ -96,37 +452,2145 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.AuthResponse"
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "github_com_phenirain_sso_internal_dto_auth.AuthRequest": {
-            "type": "object",
-            "properties": {
-                "login": {
-                    "description": "Логин пользователя",
-                    "type": "string",
-                    "example": "user@example.com"
-                },
-                "password": {
-                    "description": "Пароль пользователя",
-                    "type": "string",
-                    "example": "P@ssw0rd!"
-                }
-            }
+        },
+        "/auth/logout": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Logout (revoke refresh token session)",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/logout-all": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Logout from all sessions of current user",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/mfa/passkey/begin": {
+            "post": {
+                "description": "Альтернатива коду: options для navigator.credentials.get по ключам пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Start two-factor check with passkey",
+                "parameters": [
+                    {
+                        "description": "mfa_token from logIn",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_passkey.MfaBeginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_passkey.RequestOptions"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/mfa/passkey/finish": {
+            "post": {
+                "description": "mfa_token одноразовый: после неудачной проверки нужно снова войти по паролю",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Finish login with passkey as second factor",
+                "parameters": [
+                    {
+                        "description": "mfa_token and result of navigator.credentials.get",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_passkey.MfaRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.AuthResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/mfa/recovery-codes": {
+            "post": {
+                "description": "Прежние резервные коды перестают действовать",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Replace recovery codes of current user",
+                "parameters": [
+                    {
+                        "description": "TOTP or recovery code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.MfaCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.RecoveryCodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/mfa/totp/confirm": {
+            "post": {
+                "description": "Включает 2FA и возвращает резервные коды, они показываются один раз",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Confirm TOTP enrollment with the first code",
+                "parameters": [
+                    {
+                        "description": "Code from authenticator app",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.MfaCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.RecoveryCodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/mfa/totp/disable": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Disable two-factor authentication of current user",
+                "parameters": [
+                    {
+                        "description": "TOTP or recovery code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.MfaCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/mfa/totp/enroll": {
+            "post": {
+                "description": "Возвращает секрет и otpauth:// адрес для QR кода. 2FA включится после /auth/mfa/totp/confirm",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Start TOTP enrollment of current user",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.MfaEnrollResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/mfa/verify": {
+            "post": {
+                "description": "mfa_token одноразовый: после неверного кода нужно снова войти по паролю.\nНеверные коды засчитываются в блокировку входа, после 10 подряд принимаются только резервные коды",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Finish login with two-factor code",
+                "parameters": [
+                    {
+                        "description": "mfa_token from logIn and code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.MfaVerifyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.AuthResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/passkeys": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "List passkeys of current user",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-array_github_com_phenirain_sso_internal_dto_passkey_PasskeyResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/passkeys/login/begin": {
+            "post": {
+                "description": "Возвращает options для navigator.credentials.get. allowCredentials всегда пуст: браузер предложит\nлюбой ключ этого сервиса, а по ответу нельзя узнать, зарегистрирован ли логин и какие у него ключи",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Start login with passkey",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_passkey.RequestOptions"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/passkeys/login/finish": {
+            "post": {
+                "description": "Вход по ключу заменяет пароль и код двухфакторной аутентификации",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Finish login with passkey",
+                "parameters": [
+                    {
+                        "description": "Result of navigator.credentials.get",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_passkey.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.AuthResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/passkeys/register/begin": {
+            "post": {
+                "description": "Возвращает options для navigator.credentials.create, действуют 5 минут",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Start passkey registration for current user",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_passkey.CreationOptions"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/passkeys/register/finish": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Finish passkey registration",
+                "parameters": [
+                    {
+                        "description": "Result of navigator.credentials.create",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_passkey.RegistrationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_passkey.PasskeyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/passkeys/{id}": {
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Delete passkey of current user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Passkey id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/password": {
+            "post": {
+                "description": "Завершает все сессии пользователя и возвращает новую пару токенов",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Change password of current user",
+                "parameters": [
+                    {
+                        "description": "Old and new password",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.ChangePasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.AuthResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/password/forgot": {
+            "post": {
+                "description": "Ответ одинаковый независимо от того, существует ли пользователь. Ссылка уходит на подтверждённую почту\nЗапросы с одного адреса ограничены так же, как попытки входа",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Send password reset link",
+                "parameters": [
+                    {
+                        "description": "Login",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.ForgotPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/password/reset": {
+            "post": {
+                "description": "Завершает все сессии пользователя и снимает блокировку входа после неудачных попыток",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Reset password with token from email",
+                "parameters": [
+                    {
+                        "description": "Token and new password",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.ResetPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/refresh": {
+            "post": {
+                "description": "Refresh токен архивного или удалённого пользователя - такой же недействительный токен: 401",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Refresh access token",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.AuthResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/signUp": {
+            "post": {
+                "description": "Если требуется подтверждение контактов - 202 с verification_required и без токенов:\nкоды уже отправлены, токены выдаст вход после /auth/contact/verify",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Register user",
+                "parameters": [
+                    {
+                        "description": "Credentials",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.AuthRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.AuthResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.AuthResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/verify": {
+            "get": {
+                "description": "Validates bearer token or access token cookie. On success returns X-User-Id, X-User-Role and X-User-Login headers (X-Client-Id for service tokens)",
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Forward auth for reverse proxies (nginx auth_request, Traefik ForwardAuth)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer access token",
+                        "name": "Authorization",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "302": {
+                        "description": "Redirect to login page for browsers, if login_url is configured"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    }
+                }
+            }
+        },
+        "/oauth/authorize": {
+            "get": {
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "oauth"
+                ],
+                "summary": "OAuth 2.0 authorization endpoint (authorization code + PKCE), renders login page",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "code",
+                        "name": "response_type",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client id",
+                        "name": "client_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Registered redirect URI",
+                        "name": "redirect_uri",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "PKCE challenge",
+                        "name": "code_challenge",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "S256",
+                        "name": "code_challenge_method",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Scopes, e.g. openid",
+                        "name": "scope",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "State",
+                        "name": "state",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "OpenID Connect nonce",
+                        "name": "nonce",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "post": {
+                "description": "Форма должна вернуть csrf_token, выданный вместе со страницей в cookie sso_csrf, иначе 403 и новая форма.\nОшибки входа показываются на форме на языке из Accept-Language",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "oauth"
+                ],
+                "summary": "Login form submit, redirects to client with authorization code",
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "429": {
+                        "description": "Too Many Requests"
+                    }
+                }
+            }
+        },
+        "/oauth/introspect": {
+            "post": {
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oauth"
+                ],
+                "summary": "OAuth 2.0 token introspection (RFC 7662)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Access or refresh token",
+                        "name": "token",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "access_token or refresh_token",
+                        "name": "token_type_hint",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client id",
+                        "name": "client_id",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client secret (client_secret_post)",
+                        "name": "client_secret",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "urn:ietf:params:oauth:client-assertion-type:jwt-bearer",
+                        "name": "client_assertion_type",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JWT signed with client private key (private_key_jwt)",
+                        "name": "client_assertion",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_oauth.IntrospectionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_oauth.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_oauth.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/oauth/revoke": {
+            "post": {
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "tags": [
+                    "oauth"
+                ],
+                "summary": "OAuth 2.0 token revocation (RFC 7009)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Access or refresh token",
+                        "name": "token",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "access_token or refresh_token",
+                        "name": "token_type_hint",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client id",
+                        "name": "client_id",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client secret (client_secret_post)",
+                        "name": "client_secret",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "urn:ietf:params:oauth:client-assertion-type:jwt-bearer",
+                        "name": "client_assertion_type",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JWT signed with client private key (private_key_jwt)",
+                        "name": "client_assertion",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_oauth.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_oauth.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/oauth/token": {
+            "post": {
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oauth"
+                ],
+                "summary": "OAuth 2.0 token endpoint (authorization_code, refresh_token, client_credentials)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization_code, refresh_token or client_credentials",
+                        "name": "grant_type",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization code",
+                        "name": "code",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Redirect URI used in authorize request",
+                        "name": "redirect_uri",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client id",
+                        "name": "client_id",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client secret (confidential clients, client_secret_post)",
+                        "name": "client_secret",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "PKCE verifier",
+                        "name": "code_verifier",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Refresh token",
+                        "name": "refresh_token",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Requested scopes (client_credentials)",
+                        "name": "scope",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "urn:ietf:params:oauth:client-assertion-type:jwt-bearer",
+                        "name": "client_assertion_type",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JWT signed with client private key (private_key_jwt)",
+                        "name": "client_assertion",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_oauth.TokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_oauth.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_oauth.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/userinfo": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "OpenID Connect userinfo of current user",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_oidc.UserInfo"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "github_com_phenirain_sso_internal_dto_auth.AuthRequest": {
+            "type": "object",
+            "properties": {
+                "client_id": {
+                    "description": "Клиент OpenID Connect. Если указан - в ответе будет ID токен для него; неизвестный или отключённый клиент - 400",
+                    "type": "string",
+                    "example": "grafana"
+                },
+                "login": {
+                    "description": "Логин пользователя",
+                    "type": "string",
+                    "example": "user@example.com"
+                },
+                "nonce": {
+                    "description": "nonce клиента OpenID Connect, попадёт в ID токен",
+                    "type": "string"
+                },
+                "password": {
+                    "description": "Пароль пользователя, не длиннее 72 байт",
+                    "type": "string",
+                    "example": "P@ssw0rd!"
+                },
+                "phone": {
+                    "description": "Телефон в формате E.164, только при регистрации. На него придёт код подтверждения",
+                    "type": "string",
+                    "example": "+79991234567"
+                }
+            }
         },
         "github_com_phenirain_sso_internal_dto_auth.AuthResponse": {
             "type": "object",
             "properties": {
-                "access_token": {
-                    "description": "Access Token для доступа к защищенным ресурсам",
+                "access_token": {
+                    "description": "Access Token для доступа к защищенным ресурсам",
+                    "type": "string"
+                },
+                "id_token": {
+                    "description": "ID Token OpenID Connect, если был указан client_id",
+                    "type": "string"
+                },
+                "mfa_token": {
+                    "description": "Нужен второй фактор (TOTP или ключ): токенов нет, вход завершается запросом\n/auth/mfa/verify с этим токеном и кодом или /auth/mfa/passkey/finish с ключом",
+                    "type": "string"
+                },
+                "refresh_token": {
+                    "description": "Refresh Token для обновления пары токенов",
+                    "type": "string"
+                },
+                "verification_required": {
+                    "description": "Регистрация прошла, но токены выдаются только после подтверждения почты или телефона:\nкоды уже отправлены, подтверждение - /auth/contact/verify, затем вход",
+                    "type": "boolean"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.ChangePasswordRequest": {
+            "type": "object",
+            "properties": {
+                "new_password": {
+                    "description": "Новый пароль, не длиннее 72 байт",
+                    "type": "string",
+                    "example": "N3wP@ssw0rd!"
+                },
+                "old_password": {
+                    "description": "Текущий пароль",
+                    "type": "string",
+                    "example": "P@ssw0rd!"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.ForgotPasswordRequest": {
+            "type": "object",
+            "properties": {
+                "login": {
+                    "description": "Логин пользователя, на него же придёт письмо",
+                    "type": "string",
+                    "example": "user@example.com"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.MfaCodeRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Код TOTP или резервный код",
+                    "type": "string",
+                    "example": "123456"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.MfaEnrollResponse": {
+            "type": "object",
+            "properties": {
+                "secret": {
+                    "description": "Секрет в base32 для ручного ввода",
+                    "type": "string",
+                    "example": "JBSWY3DPEHPK3PXP"
+                },
+                "uri": {
+                    "description": "otpauth:// адрес для QR кода",
+                    "type": "string",
+                    "example": "otpauth://totp/SSO:user@example.com?secret=JBSWY3DPEHPK3PXP\u0026issuer=SSO"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.MfaVerifyRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Код TOTP или резервный код",
+                    "type": "string",
+                    "example": "123456"
+                },
+                "mfa_token": {
+                    "description": "mfa_token из ответа logIn",
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.RecoveryCodesResponse": {
+            "type": "object",
+            "properties": {
+                "codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "abcd-efgh"
+                    ]
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.ResendCodeRequest": {
+            "type": "object",
+            "properties": {
+                "channel": {
+                    "description": "email или phone",
+                    "type": "string",
+                    "example": "email"
+                },
+                "login": {
+                    "description": "Логин пользователя",
+                    "type": "string",
+                    "example": "user@example.com"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.ResetPasswordRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "description": "Новый пароль, не длиннее 72 байт",
+                    "type": "string",
+                    "example": "N3wP@ssw0rd!"
+                },
+                "token": {
+                    "description": "Токен из ссылки в письме",
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.UnlockRequest": {
+            "type": "object",
+            "properties": {
+                "ip": {
+                    "description": "IP адрес, с которого вход заблокирован",
+                    "type": "string",
+                    "example": "203.0.113.10"
+                },
+                "login": {
+                    "description": "Логин, вход по которому заблокирован",
+                    "type": "string",
+                    "example": "user@example.com"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.VerifyContactRequest": {
+            "type": "object",
+            "properties": {
+                "channel": {
+                    "description": "email или phone",
+                    "type": "string",
+                    "example": "email"
+                },
+                "code": {
+                    "description": "Код из письма или SMS",
+                    "type": "string",
+                    "example": "123456"
+                },
+                "login": {
+                    "description": "Логин пользователя",
+                    "type": "string",
+                    "example": "user@example.com"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_client.ClientRequest": {
+            "type": "object",
+            "properties": {
+                "access_token_ttl": {
+                    "description": "Время жизни access токена в секундах, не указано - по умолчанию",
+                    "type": "integer",
+                    "example": 3600
+                },
+                "audience": {
+                    "description": "aud в access токенах клиента",
+                    "type": "string",
+                    "example": "https://api.example.com"
+                },
+                "grant_types": {
+                    "description": "Разрешённые гранты: authorization_code, refresh_token, client_credentials",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "authorization_code",
+                        "refresh_token"
+                    ]
+                },
+                "id": {
+                    "description": "Идентификатор клиента (client_id). Если не указан при создании - будет сгенерирован",
+                    "type": "string",
+                    "example": "grafana"
+                },
+                "is_confidential": {
+                    "description": "Конфиденциальный клиент получает секрет, учитывается только при создании",
+                    "type": "boolean"
+                },
+                "name": {
+                    "description": "Название приложения",
+                    "type": "string",
+                    "example": "Grafana"
+                },
+                "public_key": {
+                    "description": "PEM публичного ключа для аутентификации private_key_jwt, пустой - без ключа",
+                    "type": "string"
+                },
+                "redirect_uris": {
+                    "description": "Разрешённые redirect_uri, сравниваются точным совпадением",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "https://grafana.example.com/login/generic_oauth"
+                    ]
+                },
+                "refresh_token_ttl": {
+                    "description": "Время жизни refresh токена в секундах, не указано - по умолчанию",
+                    "type": "integer",
+                    "example": 2592000
+                },
+                "scopes": {
+                    "description": "Разрешённые права (scope)",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "openid",
+                        "profile"
+                    ]
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_client.ClientResponse": {
+            "type": "object",
+            "properties": {
+                "access_token_ttl": {
+                    "type": "integer"
+                },
+                "audience": {
+                    "type": "string"
+                },
+                "creation_datetime": {
+                    "type": "string"
+                },
+                "grant_types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "is_confidential": {
+                    "type": "boolean"
+                },
+                "is_disabled": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "public_key": {
+                    "type": "string"
+                },
+                "redirect_uris": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "refresh_token_ttl": {
+                    "type": "integer"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "description": "Секрет клиента. Возвращается только при создании и перевыпуске",
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_oauth.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "invalid_grant"
+                },
+                "error_description": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_oauth.IntrospectionResponse": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "aud": {
+                    "type": "string"
+                },
+                "client_id": {
+                    "type": "string"
+                },
+                "exp": {
+                    "type": "integer"
+                },
+                "iat": {
+                    "type": "integer"
+                },
+                "iss": {
+                    "type": "string"
+                },
+                "jti": {
+                    "type": "string"
+                },
+                "role_id": {
+                    "type": "integer"
+                },
+                "scope": {
+                    "type": "string"
+                },
+                "sub": {
+                    "type": "string"
+                },
+                "token_type": {
+                    "type": "string",
+                    "example": "access_token"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_oauth.TokenResponse": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "expires_in": {
+                    "type": "integer",
+                    "example": 3600
+                },
+                "id_token": {
+                    "type": "string"
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "scope": {
+                    "type": "string"
+                },
+                "token_type": {
+                    "type": "string",
+                    "example": "Bearer"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_oidc.ProviderMetadata": {
+            "type": "object",
+            "properties": {
+                "authorization_endpoint": {
+                    "type": "string"
+                },
+                "claims_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "code_challenge_methods_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "grant_types_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id_token_signing_alg_values_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "introspection_endpoint": {
+                    "type": "string"
+                },
+                "issuer": {
+                    "type": "string"
+                },
+                "jwks_uri": {
+                    "type": "string"
+                },
+                "response_types_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "revocation_endpoint": {
+                    "type": "string"
+                },
+                "scopes_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "subject_types_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "token_endpoint": {
+                    "type": "string"
+                },
+                "token_endpoint_auth_methods_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "token_endpoint_auth_signing_alg_values_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "userinfo_endpoint": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_oidc.UserInfo": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "description": "Почта и подтверждена ли она",
+                    "type": "string",
+                    "example": "user@example.com"
+                },
+                "email_verified": {
+                    "type": "boolean"
+                },
+                "phone_number": {
+                    "description": "Телефон и подтверждён ли он",
+                    "type": "string",
+                    "example": "+79991234567"
+                },
+                "phone_number_verified": {
+                    "type": "boolean"
+                },
+                "preferred_username": {
+                    "description": "Логин пользователя",
+                    "type": "string",
+                    "example": "user@example.com"
+                },
+                "role_id": {
+                    "description": "Роль пользователя",
+                    "type": "integer",
+                    "example": 1
+                },
+                "sub": {
+                    "description": "Идентификатор пользователя",
+                    "type": "string",
+                    "example": "42"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_passkey.AuthenticatorSelection": {
+            "type": "object",
+            "properties": {
+                "requireResidentKey": {
+                    "description": "То же для браузеров, которые не знают residentKey (WebAuthn Level 1)",
+                    "type": "boolean",
+                    "example": true
+                },
+                "residentKey": {
+                    "type": "string",
+                    "example": "required"
+                },
+                "userVerification": {
+                    "type": "string",
+                    "example": "required"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_passkey.CreationOptions": {
+            "type": "object",
+            "properties": {
+                "attestation": {
+                    "type": "string",
+                    "example": "none"
+                },
+                "authenticatorSelection": {
+                    "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_passkey.AuthenticatorSelection"
+                },
+                "challenge": {
+                    "description": "challenge, base64url",
                     "type": "string"
                 },
-                "refresh_token": {
-                    "description": "Refresh Token для обновления пары токенов",
+                "excludeCredentials": {
+                    "description": "Ключи, которые уже зарегистрированы - второй раз тот же аутентификатор не добавить",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_passkey.CredentialDescriptor"
+                    }
+                },
+                "pubKeyCredParams": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_passkey.CredentialParameter"
+                    }
+                },
+                "rp": {
+                    "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_passkey.RelyingParty"
+                },
+                "timeout": {
+                    "description": "Время на церемонию в миллисекундах",
+                    "type": "integer",
+                    "example": 300000
+                },
+                "user": {
+                    "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_passkey.User"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_passkey.Credential": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "description": "Идентификатор ключа, base64url",
+                    "type": "string"
+                },
+                "rawId": {
+                    "type": "string"
+                },
+                "response": {
+                    "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_passkey.CredentialResponse"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "public-key"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_passkey.CredentialDescriptor": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "description": "Идентификатор ключа, base64url",
+                    "type": "string"
+                },
+                "transports": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "type": {
+                    "type": "string",
+                    "example": "public-key"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_passkey.CredentialParameter": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "description": "Идентификатор алгоритма COSE: -7 ES256, -8 EdDSA, -257 RS256",
+                    "type": "integer",
+                    "example": -7
+                },
+                "type": {
+                    "type": "string",
+                    "example": "public-key"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_passkey.CredentialResponse": {
+            "type": "object",
+            "properties": {
+                "attestationObject": {
+                    "type": "string"
+                },
+                "authenticatorData": {
+                    "type": "string"
+                },
+                "clientDataJSON": {
+                    "type": "string"
+                },
+                "signature": {
+                    "type": "string"
+                },
+                "transports": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "userHandle": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_passkey.LoginRequest": {
+            "type": "object",
+            "properties": {
+                "client_id": {
+                    "description": "Клиент OpenID Connect. Если указан - в ответе будет ID токен для него; неизвестный или отключённый клиент - 400",
+                    "type": "string",
+                    "example": "grafana"
+                },
+                "credential": {
+                    "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_passkey.Credential"
+                },
+                "nonce": {
+                    "description": "nonce клиента OpenID Connect, попадёт в ID токен",
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_passkey.MfaBeginRequest": {
+            "type": "object",
+            "properties": {
+                "mfa_token": {
+                    "description": "mfa_token из ответа logIn",
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_passkey.MfaRequest": {
+            "type": "object",
+            "properties": {
+                "credential": {
+                    "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_passkey.Credential"
+                },
+                "mfa_token": {
+                    "description": "mfa_token из ответа logIn",
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_passkey.PasskeyResponse": {
+            "type": "object",
+            "properties": {
+                "creation_datetime": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_used_datetime": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_passkey.RegistrationRequest": {
+            "type": "object",
+            "properties": {
+                "credential": {
+                    "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_passkey.Credential"
+                },
+                "name": {
+                    "description": "Название ключа в списке, по умолчанию \"Passkey\"",
+                    "type": "string",
+                    "example": "MacBook"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_passkey.RelyingParty": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "description": "Домен, к которому привязаны ключи",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_passkey.RequestOptions": {
+            "type": "object",
+            "properties": {
+                "allowCredentials": {
+                    "description": "Ключи, которыми можно войти. Пустой список - браузер предложит любой ключ для этого сервиса",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_passkey.CredentialDescriptor"
+                    }
+                },
+                "challenge": {
+                    "description": "challenge, base64url",
+                    "type": "string"
+                },
+                "rpId": {
+                    "type": "string",
+                    "example": "sso.example.com"
+                },
+                "timeout": {
+                    "description": "Время на церемонию в миллисекундах",
+                    "type": "integer",
+                    "example": 300000
+                },
+                "userVerification": {
+                    "type": "string",
+                    "example": "required"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_passkey.User": {
+            "type": "object",
+            "properties": {
+                "displayName": {
+                    "type": "string"
+                },
+                "id": {
+                    "description": "user handle, base64url",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_response.ApiResponse-any": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Код ошибки для клиентов, не меняется вместе с текстом сообщения",
+                    "type": "string",
+                    "example": "INVALID_CREDENTIALS"
+                },
+                "correlation_id": {
+                    "description": "Идентификатор запроса для внутренней ошибки - по нему ищется запись в логах",
+                    "type": "string"
+                },
+                "data": {
+                    "description": "Данные ответа"
+                },
+                "details": {
+                    "description": "Детали ошибки",
+                    "type": "string"
+                },
+                "message": {
+                    "description": "Сообщение (комментарий) об ошибке",
+                    "type": "string"
+                },
+                "success": {
+                    "description": "Статус ответа",
+                    "type": "boolean"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_response.ApiResponse-array_github_com_phenirain_sso_internal_dto_client_ClientResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Код ошибки для клиентов, не меняется вместе с текстом сообщения",
+                    "type": "string",
+                    "example": "INVALID_CREDENTIALS"
+                },
+                "correlation_id": {
+                    "description": "Идентификатор запроса для внутренней ошибки - по нему ищется запись в логах",
+                    "type": "string"
+                },
+                "data": {
+                    "description": "Данные ответа",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_client.ClientResponse"
+                    }
+                },
+                "details": {
+                    "description": "Детали ошибки",
+                    "type": "string"
+                },
+                "message": {
+                    "description": "Сообщение (комментарий) об ошибке",
+                    "type": "string"
+                },
+                "success": {
+                    "description": "Статус ответа",
+                    "type": "boolean"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_response.ApiResponse-array_github_com_phenirain_sso_internal_dto_passkey_PasskeyResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Код ошибки для клиентов, не меняется вместе с текстом сообщения",
+                    "type": "string",
+                    "example": "INVALID_CREDENTIALS"
+                },
+                "correlation_id": {
+                    "description": "Идентификатор запроса для внутренней ошибки - по нему ищется запись в логах",
+                    "type": "string"
+                },
+                "data": {
+                    "description": "Данные ответа",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_passkey.PasskeyResponse"
+                    }
+                },
+                "details": {
+                    "description": "Детали ошибки",
+                    "type": "string"
+                },
+                "message": {
+                    "description": "Сообщение (комментарий) об ошибке",
+                    "type": "string"
+                },
+                "success": {
+                    "description": "Статус ответа",
+                    "type": "boolean"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_client_ClientResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Код ошибки для клиентов, не меняется вместе с текстом сообщения",
+                    "type": "string",
+                    "example": "INVALID_CREDENTIALS"
+                },
+                "correlation_id": {
+                    "description": "Идентификатор запроса для внутренней ошибки - по нему ищется запись в логах",
+                    "type": "string"
+                },
+                "data": {
+                    "description": "Данные ответа",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_client.ClientResponse"
+                        }
+                    ]
+                },
+                "details": {
+                    "description": "Детали ошибки",
+                    "type": "string"
+                },
+                "message": {
+                    "description": "Сообщение (комментарий) об ошибке",
+                    "type": "string"
+                },
+                "success": {
+                    "description": "Статус ответа",
+                    "type": "boolean"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_lib_jwt.JWK": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string"
+                },
+                "crv": {
+                    "description": "EC и OKP",
+                    "type": "string"
+                },
+                "e": {
+                    "type": "string"
+                },
+                "kid": {
                     "type": "string"
+                },
+                "kty": {
+                    "type": "string"
+                },
+                "n": {
+                    "description": "RSA",
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string"
+                },
+                "x": {
+                    "type": "string"
+                },
+                "y": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_lib_jwt.JWKS": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_phenirain_sso_internal_lib_jwt.JWK"
+                    }
                 }
             }
         }
@@ -0,0 +1,224 @@
+package apierrors
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/internal/dto/response"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	clientErrors "github.com/phenirain/sso/internal/errors/client"
+	jwtErrors "github.com/phenirain/sso/internal/errors/jwt"
+	"github.com/phenirain/sso/internal/lib/i18n"
+	"github.com/phenirain/sso/pkg/echomiddleware"
+)
+
+type apiError struct {
+	err    error
+	status int
+	code   string
+}
+
+// known — известные ошибки сервисов и их представление в API.
+// Детали ответа - перевод кода ошибки из каталога i18n, текст самой ошибки клиенту не уходит
+var known = []apiError{
+	{authErrors.ErrInvalidUserCredentials, http.StatusUnauthorized, response.CodeInvalidCredentials},
+	{authErrors.ErrUserAlreadyExists, http.StatusConflict, response.CodeUserExists},
+	{authErrors.ErrUserNotFound, http.StatusNotFound, response.CodeUserNotFound},
+	{authErrors.ErrInvalidRefreshToken, http.StatusUnauthorized, response.CodeInvalidRefreshToken},
+	{authErrors.ErrRefreshTokenReused, http.StatusUnauthorized, response.CodeRefreshTokenReused},
+	{authErrors.ErrInvalidAccessToken, http.StatusUnauthorized, response.CodeInvalidToken},
+	{domain.ErrInvalidOldPassword, http.StatusBadRequest, response.CodeInvalidOldPassword},
+	{domain.ErrPasswordTooLong, http.StatusBadRequest, response.CodePasswordTooLong},
+	{authErrors.ErrInvalidResetToken, http.StatusBadRequest, response.CodeInvalidResetToken},
+	{authErrors.ErrUnknownClient, http.StatusBadRequest, response.CodeUnknownClient},
+	{authErrors.ErrOpenIdUnavailable, http.StatusNotImplemented, response.CodeOpenIdUnavailable},
+	{authErrors.ErrInvalidPhone, http.StatusBadRequest, response.CodeInvalidPhone},
+	{authErrors.ErrContactNotVerified, http.StatusForbidden, response.CodeContactNotVerified},
+	{authErrors.ErrInvalidVerificationCode, http.StatusBadRequest, response.CodeInvalidVerificationCode},
+	{authErrors.ErrMfaRequired, http.StatusUnauthorized, response.CodeMfaRequired},
+	{authErrors.ErrMfaAlreadyEnabled, http.StatusConflict, response.CodeMfaAlreadyEnabled},
+	{authErrors.ErrMfaNotEnabled, http.StatusBadRequest, response.CodeMfaNotEnabled},
+	{authErrors.ErrMfaUnavailable, http.StatusNotImplemented, response.CodeMfaUnavailable},
+	{authErrors.ErrInvalidMfaCode, http.StatusBadRequest, response.CodeInvalidMfaCode},
+	{authErrors.ErrInvalidMfaToken, http.StatusUnauthorized, response.CodeInvalidMfaToken},
+	{authErrors.ErrInvalidPasskey, http.StatusUnauthorized, response.CodeInvalidPasskey},
+	{authErrors.ErrPasskeyNotFound, http.StatusNotFound, response.CodePasskeyNotFound},
+	{authErrors.ErrPasskeyCloned, http.StatusUnauthorized, response.CodePasskeyCloned},
+	{authErrors.ErrTooManyAttempts, http.StatusTooManyRequests, response.CodeTooManyAttempts},
+	{authErrors.ErrTooManyMfaCodes, http.StatusTooManyRequests, response.CodeTooManyMfaCodes},
+
+	{jwtErrors.ErrTokenExpired, http.StatusUnauthorized, response.CodeTokenExpired},
+	{jwtErrors.ErrInvalidTokenType, http.StatusUnauthorized, response.CodeInvalidTokenType},
+	{jwtErrors.ErrInvalidToken, http.StatusUnauthorized, response.CodeInvalidToken},
+	{echomiddleware.ErrTokenRevoked, http.StatusUnauthorized, response.CodeTokenRevoked},
+
+	{clientErrors.ErrClientNotFound, http.StatusNotFound, response.CodeClientNotFound},
+	{clientErrors.ErrClientAlreadyExists, http.StatusConflict, response.CodeClientExists},
+	{clientErrors.ErrUnknownGrantType, http.StatusBadRequest, response.CodeInvalidClientSettings},
+	{clientErrors.ErrRedirectUriRequired, http.StatusBadRequest, response.CodeInvalidClientSettings},
+	{clientErrors.ErrInvalidPublicKey, http.StatusBadRequest, response.CodeInvalidClientSettings},
+	{clientErrors.ErrInvalidTokenTTL, http.StatusBadRequest, response.CodeInvalidClientSettings},
+}
+
+// Error — ошибка обработчика: что не удалось сделать и почему. Ответ по ней строит ErrorHandler.
+// Message и Details - ключи каталога i18n, текст подставляется на языке запроса
+type Error struct {
+	// Что не удалось сделать, ключ сообщения
+	Message string
+	Err     error
+
+	// Заполняются только для ошибок самого обработчика (валидация), у которых нет Err
+	Status  int
+	Code    string
+	Details string
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Message + ": " + e.Details
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New — ошибка сервиса, код и статус ответа определяются по err
+func New(messageKey string, err error) *Error {
+	return &Error{Message: messageKey, Err: err}
+}
+
+// BadRequest — ошибка валидации запроса
+func BadRequest(code, messageKey, detailsKey string) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: code, Message: messageKey, Details: detailsKey}
+}
+
+// Unauthorized — запрос без нужных учётных данных
+func Unauthorized(code, messageKey, detailsKey string) *Error {
+	return &Error{Status: http.StatusUnauthorized, Code: code, Message: messageKey, Details: detailsKey}
+}
+
+// ErrorHandler — единая точка ответа на ошибки. Известные ошибки отдаются с кодом и безопасным
+// текстом, всё остальное логируется с идентификатором запроса, а клиент получает только его
+func ErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	status, body, hidden := resolve(err, i18n.FromContext(c.Request().Context()))
+	if hidden {
+		// текст ошибки остаётся в логе, клиент получает идентификатор запроса, чтобы его найти
+		requestId := c.Response().Header().Get(echo.HeaderXRequestID)
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		slog.Log(c.Request().Context(), level, "request failed",
+			slog.String("request_id", requestId),
+			slog.String("method", c.Request().Method),
+			slog.String("path", c.Path()),
+			"err", err,
+		)
+		body.CorrelationId = requestId
+	}
+	// блокировка входа: клиенту - когда можно повторить
+	var lockoutErr *authErrors.LockoutError
+	if errors.As(err, &lockoutErr) {
+		c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockoutErr.RetryAfter.Seconds()))))
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(status)
+	} else {
+		err = c.JSON(status, body)
+	}
+	if err != nil {
+		slog.Error("failed to write error response", "err", err)
+	}
+}
+
+// resolve строит ответ на ошибку. hidden - в ответ не попало ничего, кроме статуса:
+// причину нужно залогировать, а клиенту отдать идентификатор запроса
+func resolve(err error, lang string) (int, response.ApiResponse[any], bool) {
+	var message string
+	var handlerErr *Error
+	if errors.As(err, &handlerErr) {
+		message = handlerErr.Message
+		if handlerErr.Status != 0 {
+			return handlerErr.Status, response.NewBadResponse[any](handlerErr.Code, i18n.Text(lang, message), i18n.Text(lang, handlerErr.Details)), false
+		}
+	}
+
+	// echo.HTTPError разворачивается в Internal, так что ошибки middleware тоже найдутся
+	if known, ok := lookup(err); ok {
+		return known.status, response.NewBadResponse[any](known.code, orDefault(lang, message, known.status), i18n.Text(lang, known.code)), false
+	}
+
+	// ошибки echo и middleware, у которых есть только HTTP статус. Их текст (ошибка разбора json,
+	// внутренности роутера) клиенту не отдаём - только перевод кода
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) && httpErr.Code < http.StatusInternalServerError {
+		code := statusCode(httpErr.Code)
+		return httpErr.Code, response.NewBadResponse[any](code, orDefault(lang, message, httpErr.Code), i18n.Text(lang, code)), true
+	}
+
+	return http.StatusInternalServerError, response.NewBadResponse[any](response.CodeInternal, orDefault(lang, message, http.StatusInternalServerError), ""), true
+}
+
+func lookup(err error) (apiError, bool) {
+	for _, e := range known {
+		if errors.Is(err, e.err) {
+			return e, true
+		}
+	}
+	return apiError{}, false
+}
+
+// statusCode — код ApiResponse для ошибок echo, у которых есть только HTTP статус
+func statusCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return response.CodeInvalidRequest
+	case http.StatusUnauthorized:
+		return response.CodeUnauthorized
+	case http.StatusForbidden:
+		return response.CodeForbidden
+	case http.StatusNotFound:
+		return response.CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return response.CodeMethodNotAllowed
+	default:
+		return response.CodeInvalidRequest
+	}
+}
+
+// orDefault — сообщение обработчика, а если его нет - общее для статуса
+func orDefault(lang, messageKey string, status int) string {
+	if messageKey != "" {
+		return i18n.Text(lang, messageKey)
+	}
+	switch status {
+	case http.StatusBadRequest:
+		return i18n.Text(lang, i18n.MsgBadRequest)
+	case http.StatusUnauthorized:
+		return i18n.Text(lang, i18n.MsgUnauthorized)
+	case http.StatusForbidden:
+		return i18n.Text(lang, i18n.MsgForbidden)
+	case http.StatusNotFound:
+		return i18n.Text(lang, i18n.MsgNotFound)
+	case http.StatusMethodNotAllowed:
+		return i18n.Text(lang, i18n.MsgMethodNotAllowed)
+	case http.StatusConflict:
+		return i18n.Text(lang, i18n.MsgConflict)
+	case http.StatusInternalServerError:
+		return i18n.Text(lang, i18n.MsgInternal)
+	default:
+		return http.StatusText(status)
+	}
+}
@@ -0,0 +1,40 @@
+package apierrors
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/phenirain/sso/internal/dto/response"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	"github.com/phenirain/sso/internal/lib/i18n"
+)
+
+func TestResolve(t *testing.T) {
+	bindErr := echo.NewHTTPError(http.StatusBadRequest, "Syntax error: offset=12, error=invalid character").SetInternal(errors.New("invalid character"))
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+		wantHidden bool
+	}{
+		{"known service error", New(i18n.MsgAuthFailed, authErrors.ErrInvalidUserCredentials), http.StatusUnauthorized, response.CodeInvalidCredentials, false},
+		{"handler validation", BadRequest(response.CodeMissingArgument, i18n.MsgMissingArgument, i18n.MsgLoginRequired), http.StatusBadRequest, response.CodeMissingArgument, false},
+		{"echo bind error", New(i18n.MsgInvalidJson, bindErr), http.StatusBadRequest, response.CodeInvalidRequest, true},
+		{"router error", echo.ErrNotFound, http.StatusNotFound, response.CodeNotFound, true},
+		{"unknown error", errors.New("pq: connection refused"), http.StatusInternalServerError, response.CodeInternal, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body, hidden := resolve(tt.err, i18n.En)
+			if status != tt.wantStatus || body.Code != tt.wantCode || hidden != tt.wantHidden {
+				t.Fatalf("resolve = %d %s hidden=%v, want %d %s hidden=%v", status, body.Code, hidden, tt.wantStatus, tt.wantCode, tt.wantHidden)
+			}
+			if hidden && tt.wantStatus < http.StatusInternalServerError && body.Details != i18n.Text(i18n.En, tt.wantCode) {
+				t.Errorf("details = %q, want translation of %s", body.Details, tt.wantCode)
+			}
+		})
+	}
+}
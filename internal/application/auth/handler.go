@@ -5,13 +5,22 @@ import (
 	"net/http"
 
 	"github.com/labstack/echo/v4"
+	"github.com/phenirain/sso/internal/application/apierrors"
+	"github.com/phenirain/sso/internal/domain"
 	authModels "github.com/phenirain/sso/internal/dto/auth"
+	"github.com/phenirain/sso/internal/dto/oidc"
 	"github.com/phenirain/sso/internal/dto/response"
+	"github.com/phenirain/sso/internal/lib/i18n"
+	"github.com/phenirain/sso/pkg/contextkeys"
 )
 
 type AuthService interface {
 	Auth(ctx context.Context, request authModels.AuthRequest, isNew bool) (*authModels.AuthResponse, error)
 	Refresh(ctx context.Context, refreshToken string) (*authModels.AuthResponse, error)
+	Logout(ctx context.Context, refreshToken string) error
+	LogoutAll(ctx context.Context, userId int64) error
+	ChangePassword(ctx context.Context, userId int64, request authModels.ChangePasswordRequest) (*authModels.AuthResponse, error)
+	UserInfo(ctx context.Context, userId int64) (*oidc.UserInfo, error)
 }
 
 type Handler struct {
@@ -26,11 +35,18 @@ func NewHandler(auth AuthService) *Handler {
 
 // LogIn godoc
 // @Summary Login user
+// @Description С подключённым TOTP или добавленным ключом вместо токенов возвращает mfa_token для /auth/mfa/verify или /auth/mfa/passkey/finish.
+// @Description После серии неверных паролей вход временно блокируется: 429 и заголовок Retry-After
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param request body authModels.AuthRequest true "Credentials"
+// @Description Если требуется подтверждение контактов, а пользователь их не подтвердил - 403
 // @Success 200 {object} authModels.AuthResponse
+// @Failure 400 {object} response.ApiResponse[any]
+// @Failure 401 {object} response.ApiResponse[any]
+// @Failure 403 {object} response.ApiResponse[any]
+// @Failure 429 {object} response.ApiResponse[any]
 // @Router /auth/logIn [post]
 func (h *Handler) LogIn(c echo.Context) error {
 	return h.auth(c, false)
@@ -38,11 +54,16 @@ func (h *Handler) LogIn(c echo.Context) error {
 
 // SignUp godoc
 // @Summary Register user
+// @Description Если требуется подтверждение контактов - 202 с verification_required и без токенов:
+// @Description коды уже отправлены, токены выдаст вход после /auth/contact/verify
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param request body authModels.AuthRequest true "Credentials"
 // @Success 200 {object} authModels.AuthResponse
+// @Success 202 {object} authModels.AuthResponse
+// @Failure 400 {object} response.ApiResponse[any]
+// @Failure 409 {object} response.ApiResponse[any]
 // @Router /auth/signUp [post]
 func (h *Handler) SignUp(c echo.Context) error {
 	return h.auth(c, true)
@@ -50,53 +71,176 @@ func (h *Handler) SignUp(c echo.Context) error {
 
 // Refresh godoc
 // @Summary Refresh access token
+// @Description Refresh токен архивного или удалённого пользователя - такой же недействительный токен: 401
 // @Tags auth
 // @Produce json
 // @Success 200 {object} authModels.AuthResponse
+// @Failure 401 {object} response.ApiResponse[any]
 // @Router /auth/refresh [post]
 func (h *Handler) Refresh(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	// Получаем токен из заголовка Authorization
-	authHeader := c.Request().Header.Get("Authorization")
-	if authHeader == "" {
-		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует токен", "Заголовок Authorization обязателен"))
+	refreshToken, err := bearerToken(c)
+	if err != nil {
+		return err
 	}
 
-	// Проверяем формат "Bearer <token>"
-	if len(authHeader) < 7 || authHeader[:7] != "Bearer " {
-		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Неверный формат токена", "Используйте формат: Bearer <token>"))
+	result, err := h.s.Refresh(ctx, refreshToken)
+	if err != nil {
+		return apierrors.New(i18n.MsgRefreshFailed, err)
 	}
 
-	refreshToken := authHeader[7:] // Убираем "Bearer "
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
 
-	result, err := h.s.Refresh(ctx, refreshToken)
+// Logout godoc
+// @Summary Logout (revoke refresh token session)
+// @Tags auth
+// @Produce json
+// @Success 200 {object} response.ApiResponse[any]
+// @Failure 401 {object} response.ApiResponse[any]
+// @Router /auth/logout [post]
+func (h *Handler) Logout(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	refreshToken, err := bearerToken(c)
 	if err != nil {
-		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка обновления токена", err.Error()))
+		return err
+	}
+
+	if err := h.s.Logout(ctx, refreshToken); err != nil {
+		return apierrors.New(i18n.MsgLogoutFailed, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse[any](nil))
+}
+
+// LogoutAll godoc
+// @Summary Logout from all sessions of current user
+// @Tags auth
+// @Produce json
+// @Success 200 {object} response.ApiResponse[any]
+// @Router /auth/logout-all [post]
+func (h *Handler) LogoutAll(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, ok := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	if err := h.s.LogoutAll(ctx, userId); err != nil {
+		return apierrors.New(i18n.MsgLogoutAllFailed, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse[any](nil))
+}
+
+// ChangePassword godoc
+// @Summary Change password of current user
+// @Description Завершает все сессии пользователя и возвращает новую пару токенов
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body authModels.ChangePasswordRequest true "Old and new password"
+// @Success 200 {object} authModels.AuthResponse
+// @Failure 400 {object} response.ApiResponse[any]
+// @Failure 401 {object} response.ApiResponse[any]
+// @Router /auth/password [post]
+func (h *Handler) ChangePassword(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, ok := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	var req authModels.ChangePasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return apierrors.New(i18n.MsgInvalidJson, err)
+	}
+	if req.OldPassword == "" {
+		return apierrors.BadRequest(response.CodeMissingArgument, i18n.MsgMissingArgument, i18n.MsgOldPasswordRequired)
+	}
+	if req.NewPassword == "" {
+		return apierrors.BadRequest(response.CodeMissingArgument, i18n.MsgMissingArgument, i18n.MsgNewPasswordRequired)
+	}
+	if len(req.NewPassword) > domain.MaxPasswordBytes {
+		return apierrors.BadRequest(response.CodePasswordTooLong, i18n.MsgInvalidArgument, response.CodePasswordTooLong)
+	}
+
+	result, err := h.s.ChangePassword(ctx, userId, req)
+	if err != nil {
+		return apierrors.New(i18n.MsgChangePasswordFailed, err)
 	}
 
 	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
 }
 
+// UserInfo godoc
+// @Summary OpenID Connect userinfo of current user
+// @Tags oidc
+// @Produce json
+// @Success 200 {object} oidc.UserInfo
+// @Router /userinfo [get]
+func (h *Handler) UserInfo(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, ok := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	// ответ по спецификации OpenID Connect, без обёртки ApiResponse
+	result, err := h.s.UserInfo(ctx, userId)
+	if err != nil {
+		return echo.ErrUnauthorized
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
 func (h *Handler) auth(c echo.Context, isNew bool) error {
 	ctx := c.Request().Context()
 
 	var req authModels.AuthRequest
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+		return apierrors.New(i18n.MsgInvalidJson, err)
 	}
 
 	if req.Login == "" {
-		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "Логин обязателен"))
+		return apierrors.BadRequest(response.CodeMissingArgument, i18n.MsgMissingArgument, i18n.MsgLoginRequired)
 	}
 	if req.Password == "" {
-		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "Пароль обязателен"))
+		return apierrors.BadRequest(response.CodeMissingArgument, i18n.MsgMissingArgument, i18n.MsgPasswordRequired)
+	}
+	if len(req.Password) > domain.MaxPasswordBytes {
+		return apierrors.BadRequest(response.CodePasswordTooLong, i18n.MsgInvalidArgument, response.CodePasswordTooLong)
 	}
 
 	result, err := h.s.Auth(ctx, req, isNew)
 	if err != nil {
-		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка авторизации", err.Error()))
+		return apierrors.New(i18n.MsgAuthFailed, err)
+	}
+	if result.VerificationRequired {
+		return c.JSON(http.StatusAccepted, response.NewSuccessResponse(result))
 	}
 
 	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
 }
+
+// bearerToken достаёт токен из заголовка Authorization.
+// Если заголовка нет или он неверного формата - возвращает ошибку для ErrorHandler
+func bearerToken(c echo.Context) (string, error) {
+	authHeader := c.Request().Header.Get("Authorization")
+	if authHeader == "" {
+		return "", apierrors.Unauthorized(response.CodeMissingToken, i18n.MsgMissingToken, i18n.MsgHeaderRequired)
+	}
+
+	// Проверяем формат "Bearer <token>"
+	if len(authHeader) < 7 || authHeader[:7] != "Bearer " {
+		return "", apierrors.Unauthorized(response.CodeInvalidToken, i18n.MsgInvalidFormat, i18n.MsgBearerFormat)
+	}
+
+	return authHeader[7:], nil // Убираем "Bearer "
+}
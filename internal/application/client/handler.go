@@ -0,0 +1,162 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/phenirain/sso/internal/application/apierrors"
+	clientModels "github.com/phenirain/sso/internal/dto/client"
+	"github.com/phenirain/sso/internal/dto/response"
+	"github.com/phenirain/sso/internal/lib/i18n"
+)
+
+type ClientService interface {
+	List(ctx context.Context) ([]clientModels.ClientResponse, error)
+	Create(ctx context.Context, request clientModels.ClientRequest) (*clientModels.ClientResponse, error)
+	Update(ctx context.Context, id string, request clientModels.ClientRequest) (*clientModels.ClientResponse, error)
+	RotateSecret(ctx context.Context, id string) (*clientModels.ClientResponse, error)
+	SetDisabled(ctx context.Context, id string, disabled bool) (*clientModels.ClientResponse, error)
+}
+
+type Handler struct {
+	s ClientService
+}
+
+func NewHandler(clients ClientService) *Handler {
+	return &Handler{
+		s: clients,
+	}
+}
+
+// List godoc
+// @Summary List OAuth clients
+// @Tags admin
+// @Produce json
+// @Success 200 {object} response.ApiResponse[[]clientModels.ClientResponse]
+// @Failure 401 {object} response.ApiResponse[any]
+// @Failure 403 {object} response.ApiResponse[any]
+// @Router /admin/clients [get]
+func (h *Handler) List(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	result, err := h.s.List(ctx)
+	if err != nil {
+		return apierrors.New(i18n.MsgGetClientsFailed, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(&result))
+}
+
+// Create godoc
+// @Summary Register OAuth client
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body clientModels.ClientRequest true "Client settings"
+// @Success 200 {object} response.ApiResponse[clientModels.ClientResponse]
+// @Failure 400 {object} response.ApiResponse[any]
+// @Failure 409 {object} response.ApiResponse[any]
+// @Router /admin/clients [post]
+func (h *Handler) Create(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req clientModels.ClientRequest
+	if err := c.Bind(&req); err != nil {
+		return apierrors.New(i18n.MsgInvalidJson, err)
+	}
+	if req.Name == "" {
+		return apierrors.BadRequest(response.CodeMissingArgument, i18n.MsgMissingArgument, i18n.MsgClientNameRequired)
+	}
+
+	result, err := h.s.Create(ctx, req)
+	if err != nil {
+		return apierrors.New(i18n.MsgCreateClientFailed, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// Update godoc
+// @Summary Update OAuth client settings
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Client id"
+// @Param request body clientModels.ClientRequest true "Client settings"
+// @Success 200 {object} response.ApiResponse[clientModels.ClientResponse]
+// @Failure 400 {object} response.ApiResponse[any]
+// @Failure 404 {object} response.ApiResponse[any]
+// @Router /admin/clients/{id} [put]
+func (h *Handler) Update(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req clientModels.ClientRequest
+	if err := c.Bind(&req); err != nil {
+		return apierrors.New(i18n.MsgInvalidJson, err)
+	}
+	if req.Name == "" {
+		return apierrors.BadRequest(response.CodeMissingArgument, i18n.MsgMissingArgument, i18n.MsgClientNameRequired)
+	}
+
+	result, err := h.s.Update(ctx, c.Param("id"), req)
+	if err != nil {
+		return apierrors.New(i18n.MsgUpdateClientFailed, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// RotateSecret godoc
+// @Summary Issue new secret for OAuth client
+// @Tags admin
+// @Produce json
+// @Param id path string true "Client id"
+// @Success 200 {object} response.ApiResponse[clientModels.ClientResponse]
+// @Failure 404 {object} response.ApiResponse[any]
+// @Router /admin/clients/{id}/secret [post]
+func (h *Handler) RotateSecret(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	result, err := h.s.RotateSecret(ctx, c.Param("id"))
+	if err != nil {
+		return apierrors.New(i18n.MsgRotateSecretFailed, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// Disable godoc
+// @Summary Disable OAuth client
+// @Tags admin
+// @Produce json
+// @Param id path string true "Client id"
+// @Success 200 {object} response.ApiResponse[clientModels.ClientResponse]
+// @Failure 404 {object} response.ApiResponse[any]
+// @Router /admin/clients/{id}/disable [post]
+func (h *Handler) Disable(c echo.Context) error {
+	return h.setDisabled(c, true)
+}
+
+// Enable godoc
+// @Summary Enable OAuth client
+// @Tags admin
+// @Produce json
+// @Param id path string true "Client id"
+// @Success 200 {object} response.ApiResponse[clientModels.ClientResponse]
+// @Failure 404 {object} response.ApiResponse[any]
+// @Router /admin/clients/{id}/enable [post]
+func (h *Handler) Enable(c echo.Context) error {
+	return h.setDisabled(c, false)
+}
+
+func (h *Handler) setDisabled(c echo.Context, disabled bool) error {
+	ctx := c.Request().Context()
+
+	result, err := h.s.SetDisabled(ctx, c.Param("id"), disabled)
+	if err != nil {
+		return apierrors.New(i18n.MsgClientStatusFailed, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
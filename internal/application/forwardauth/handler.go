@@ -0,0 +1,131 @@
+package forwardauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/phenirain/sso/internal/config"
+	authModels "github.com/phenirain/sso/internal/dto/auth"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+)
+
+// defaultCookieName — cookie с access токеном, если в конфиге не задано другое
+const defaultCookieName = "sso_access_token"
+
+type Verifier interface {
+	Verify(ctx context.Context, accessToken string) (*authModels.Identity, error)
+}
+
+// Handler — проверка запросов для reverse proxy (nginx auth_request, Traefik ForwardAuth).
+// Прокси пересылает сюда заголовки исходного запроса и пускает его дальше только на 2xx
+type Handler struct {
+	s          Verifier
+	cookieName string
+	loginUrl   string
+}
+
+func NewHandler(s Verifier, cfg config.ForwardAuthConfig) *Handler {
+	cookieName := cfg.CookieName
+	if cookieName == "" {
+		cookieName = defaultCookieName
+	}
+	return &Handler{
+		s:          s,
+		cookieName: cookieName,
+		loginUrl:   cfg.LoginUrl,
+	}
+}
+
+// Verify godoc
+// @Summary Forward auth for reverse proxies (nginx auth_request, Traefik ForwardAuth)
+// @Description Validates bearer token or access token cookie. On success returns X-User-Id, X-User-Role and X-User-Login headers (X-Client-Id for service tokens)
+// @Tags auth
+// @Param Authorization header string false "Bearer access token"
+// @Success 200
+// @Failure 302 "Redirect to login page for browsers, if login_url is configured"
+// @Failure 401
+// @Router /auth/verify [get]
+func (h *Handler) Verify(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	token := h.token(c)
+	if token == "" {
+		return h.deny(c)
+	}
+
+	identity, err := h.s.Verify(ctx, token)
+	if err != nil {
+		if errors.Is(err, authErrors.ErrInvalidAccessToken) {
+			return h.deny(c)
+		}
+		return err
+	}
+
+	header := c.Response().Header()
+	if identity.UserId != 0 {
+		header.Set("X-User-Id", strconv.FormatInt(identity.UserId, 10))
+		header.Set("X-User-Role", strconv.FormatInt(identity.RoleId, 10))
+		header.Set("X-User-Login", identity.Login)
+	}
+	if identity.ClientId != "" {
+		header.Set("X-Client-Id", identity.ClientId)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// token берёт access токен из заголовка Authorization, иначе из cookie
+func (h *Handler) token(c echo.Context) string {
+	if authHeader := c.Request().Header.Get("Authorization"); authHeader != "" {
+		token, ok := strings.CutPrefix(authHeader, "Bearer ")
+		if !ok {
+			return ""
+		}
+		return token
+	}
+	if cookie, err := c.Cookie(h.cookieName); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// deny отвечает 401, а браузер отправляет на страницу входа, если она задана.
+// nginx auth_request понимает только 2xx/401/403 - для него редирект делается через error_page 401
+func (h *Handler) deny(c echo.Context) error {
+	if h.loginUrl == "" || !strings.Contains(c.Request().Header.Get("Accept"), "text/html") {
+		c.Response().Header().Set("WWW-Authenticate", `Bearer realm="sso"`)
+		return c.NoContent(http.StatusUnauthorized)
+	}
+
+	loginUrl, err := url.Parse(h.loginUrl)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if original := originalUrl(c.Request()); original != "" {
+		query := loginUrl.Query()
+		query.Set("rd", original)
+		loginUrl.RawQuery = query.Encode()
+	}
+	return c.Redirect(http.StatusFound, loginUrl.String())
+}
+
+// originalUrl восстанавливает адрес исходного запроса из заголовков прокси:
+// X-Original-URL (nginx) или X-Forwarded-Proto/Host/Uri (Traefik)
+func originalUrl(r *http.Request) string {
+	if original := r.Header.Get("X-Original-URL"); original != "" {
+		return original
+	}
+	host := r.Header.Get("X-Forwarded-Host")
+	if host == "" {
+		return ""
+	}
+	proto := r.Header.Get("X-Forwarded-Proto")
+	if proto == "" {
+		proto = "https"
+	}
+	return proto + "://" + host + r.Header.Get("X-Forwarded-Uri")
+}
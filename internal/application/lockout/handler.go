@@ -0,0 +1,56 @@
+package lockout
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/phenirain/sso/internal/application/apierrors"
+	authModels "github.com/phenirain/sso/internal/dto/auth"
+	"github.com/phenirain/sso/internal/dto/response"
+	"github.com/phenirain/sso/internal/lib/i18n"
+)
+
+type LockoutService interface {
+	Unlock(ctx context.Context, login, ip string) error
+}
+
+type Handler struct {
+	s LockoutService
+}
+
+func NewHandler(s LockoutService) *Handler {
+	return &Handler{
+		s: s,
+	}
+}
+
+// Unlock godoc
+// @Summary Unlock login or IP address after failed login attempts
+// @Description Обнуляет счётчик неудачных попыток входа по логину и (или) с IP адреса
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body authModels.UnlockRequest true "Login and/or IP address"
+// @Success 200 {object} response.ApiResponse[any]
+// @Failure 400 {object} response.ApiResponse[any]
+// @Failure 401 {object} response.ApiResponse[any]
+// @Failure 403 {object} response.ApiResponse[any]
+// @Router /admin/lockout/unlock [post]
+func (h *Handler) Unlock(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req authModels.UnlockRequest
+	if err := c.Bind(&req); err != nil {
+		return apierrors.New(i18n.MsgInvalidJson, err)
+	}
+	if req.Login == "" && req.Ip == "" {
+		return apierrors.BadRequest(response.CodeMissingArgument, i18n.MsgMissingArgument, i18n.MsgLoginOrIpRequired)
+	}
+
+	if err := h.s.Unlock(ctx, req.Login, req.Ip); err != nil {
+		return apierrors.New(i18n.MsgUnlockFailed, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse[any](nil))
+}
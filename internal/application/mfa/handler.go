@@ -0,0 +1,255 @@
+package mfa
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/phenirain/sso/internal/application/apierrors"
+	authModels "github.com/phenirain/sso/internal/dto/auth"
+	passkeyModels "github.com/phenirain/sso/internal/dto/passkey"
+	"github.com/phenirain/sso/internal/dto/response"
+	"github.com/phenirain/sso/internal/lib/i18n"
+	"github.com/phenirain/sso/pkg/contextkeys"
+)
+
+type MfaService interface {
+	Enroll(ctx context.Context, userId int64) (*authModels.MfaEnrollResponse, error)
+	Confirm(ctx context.Context, userId int64, code string) (*authModels.RecoveryCodesResponse, error)
+	Disable(ctx context.Context, userId int64, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userId int64, code string) (*authModels.RecoveryCodesResponse, error)
+	Verify(ctx context.Context, mfaToken, code string) (*authModels.AuthResponse, error)
+	PasskeyOptions(ctx context.Context, mfaToken string) (*passkeyModels.RequestOptions, error)
+	VerifyPasskey(ctx context.Context, mfaToken string, credential passkeyModels.Credential) (*authModels.AuthResponse, error)
+}
+
+type Handler struct {
+	s MfaService
+}
+
+func NewHandler(s MfaService) *Handler {
+	return &Handler{
+		s: s,
+	}
+}
+
+// Enroll godoc
+// @Summary Start TOTP enrollment of current user
+// @Description Возвращает секрет и otpauth:// адрес для QR кода. 2FA включится после /auth/mfa/totp/confirm
+// @Tags auth
+// @Produce json
+// @Success 200 {object} authModels.MfaEnrollResponse
+// @Failure 401 {object} response.ApiResponse[any]
+// @Failure 409 {object} response.ApiResponse[any]
+// @Router /auth/mfa/totp/enroll [post]
+func (h *Handler) Enroll(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, ok := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	result, err := h.s.Enroll(ctx, userId)
+	if err != nil {
+		return apierrors.New(i18n.MsgMfaEnrollFailed, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// Confirm godoc
+// @Summary Confirm TOTP enrollment with the first code
+// @Description Включает 2FA и возвращает резервные коды, они показываются один раз
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body authModels.MfaCodeRequest true "Code from authenticator app"
+// @Success 200 {object} authModels.RecoveryCodesResponse
+// @Failure 400 {object} response.ApiResponse[any]
+// @Failure 401 {object} response.ApiResponse[any]
+// @Router /auth/mfa/totp/confirm [post]
+func (h *Handler) Confirm(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, code, err := userCode(c)
+	if err != nil {
+		return err
+	}
+
+	result, err := h.s.Confirm(ctx, userId, code)
+	if err != nil {
+		return apierrors.New(i18n.MsgMfaConfirmFailed, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// Disable godoc
+// @Summary Disable two-factor authentication of current user
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body authModels.MfaCodeRequest true "TOTP or recovery code"
+// @Success 200 {object} response.ApiResponse[any]
+// @Failure 400 {object} response.ApiResponse[any]
+// @Failure 401 {object} response.ApiResponse[any]
+// @Router /auth/mfa/totp/disable [post]
+func (h *Handler) Disable(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, code, err := userCode(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.s.Disable(ctx, userId, code); err != nil {
+		return apierrors.New(i18n.MsgMfaDisableFailed, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse[any](nil))
+}
+
+// RecoveryCodes godoc
+// @Summary Replace recovery codes of current user
+// @Description Прежние резервные коды перестают действовать
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body authModels.MfaCodeRequest true "TOTP or recovery code"
+// @Success 200 {object} authModels.RecoveryCodesResponse
+// @Failure 400 {object} response.ApiResponse[any]
+// @Failure 401 {object} response.ApiResponse[any]
+// @Router /auth/mfa/recovery-codes [post]
+func (h *Handler) RecoveryCodes(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, code, err := userCode(c)
+	if err != nil {
+		return err
+	}
+
+	result, err := h.s.RegenerateRecoveryCodes(ctx, userId, code)
+	if err != nil {
+		return apierrors.New(i18n.MsgRecoveryCodesFailed, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// Verify godoc
+// @Summary Finish login with two-factor code
+// @Description mfa_token одноразовый: после неверного кода нужно снова войти по паролю.
+// @Description Неверные коды засчитываются в блокировку входа, после 10 подряд принимаются только резервные коды
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body authModels.MfaVerifyRequest true "mfa_token from logIn and code"
+// @Success 200 {object} authModels.AuthResponse
+// @Failure 400 {object} response.ApiResponse[any]
+// @Failure 401 {object} response.ApiResponse[any]
+// @Failure 429 {object} response.ApiResponse[any]
+// @Router /auth/mfa/verify [post]
+func (h *Handler) Verify(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req authModels.MfaVerifyRequest
+	if err := c.Bind(&req); err != nil {
+		return apierrors.New(i18n.MsgInvalidJson, err)
+	}
+	if req.MfaToken == "" {
+		return apierrors.BadRequest(response.CodeMissingArgument, i18n.MsgMissingArgument, i18n.MsgMfaTokenRequired)
+	}
+	if req.Code == "" {
+		return apierrors.BadRequest(response.CodeMissingArgument, i18n.MsgMissingArgument, i18n.MsgCodeRequired)
+	}
+
+	result, err := h.s.Verify(ctx, req.MfaToken, req.Code)
+	if err != nil {
+		return apierrors.New(i18n.MsgMfaVerifyFailed, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// PasskeyBegin godoc
+// @Summary Start two-factor check with passkey
+// @Description Альтернатива коду: options для navigator.credentials.get по ключам пользователя
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body passkeyModels.MfaBeginRequest true "mfa_token from logIn"
+// @Success 200 {object} passkeyModels.RequestOptions
+// @Failure 400 {object} response.ApiResponse[any]
+// @Failure 401 {object} response.ApiResponse[any]
+// @Failure 404 {object} response.ApiResponse[any]
+// @Router /auth/mfa/passkey/begin [post]
+func (h *Handler) PasskeyBegin(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req passkeyModels.MfaBeginRequest
+	if err := c.Bind(&req); err != nil {
+		return apierrors.New(i18n.MsgInvalidJson, err)
+	}
+	if req.MfaToken == "" {
+		return apierrors.BadRequest(response.CodeMissingArgument, i18n.MsgMissingArgument, i18n.MsgMfaTokenRequired)
+	}
+
+	result, err := h.s.PasskeyOptions(ctx, req.MfaToken)
+	if err != nil {
+		return apierrors.New(i18n.MsgPasskeyOptionsFailed, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// PasskeyFinish godoc
+// @Summary Finish login with passkey as second factor
+// @Description mfa_token одноразовый: после неудачной проверки нужно снова войти по паролю
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body passkeyModels.MfaRequest true "mfa_token and result of navigator.credentials.get"
+// @Success 200 {object} authModels.AuthResponse
+// @Failure 400 {object} response.ApiResponse[any]
+// @Failure 401 {object} response.ApiResponse[any]
+// @Failure 429 {object} response.ApiResponse[any]
+// @Router /auth/mfa/passkey/finish [post]
+func (h *Handler) PasskeyFinish(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req passkeyModels.MfaRequest
+	if err := c.Bind(&req); err != nil {
+		return apierrors.New(i18n.MsgInvalidJson, err)
+	}
+	if req.MfaToken == "" {
+		return apierrors.BadRequest(response.CodeMissingArgument, i18n.MsgMissingArgument, i18n.MsgMfaTokenRequired)
+	}
+	if req.Credential.RawId == "" {
+		return apierrors.BadRequest(response.CodeMissingArgument, i18n.MsgMissingArgument, i18n.MsgCredentialRequired)
+	}
+
+	result, err := h.s.VerifyPasskey(ctx, req.MfaToken, req.Credential)
+	if err != nil {
+		return apierrors.New(i18n.MsgMfaVerifyFailed, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// userCode — текущий пользователь и код из тела запроса
+func userCode(c echo.Context) (int64, string, error) {
+	userId, ok := c.Request().Context().Value(contextkeys.UserIDCtxKey).(int64)
+	if !ok {
+		return 0, "", echo.ErrUnauthorized
+	}
+
+	var req authModels.MfaCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return 0, "", apierrors.New(i18n.MsgInvalidJson, err)
+	}
+	if req.Code == "" {
+		return 0, "", apierrors.BadRequest(response.CodeMissingArgument, i18n.MsgMissingArgument, i18n.MsgCodeRequired)
+	}
+	return userId, req.Code, nil
+}
@@ -0,0 +1,389 @@
+package oauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/internal/dto/oauth"
+	passkeyModels "github.com/phenirain/sso/internal/dto/passkey"
+	"github.com/phenirain/sso/internal/dto/response"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	oauthErrors "github.com/phenirain/sso/internal/errors/oauth"
+	"github.com/phenirain/sso/internal/lib/i18n"
+	oauthService "github.com/phenirain/sso/internal/services/oauth"
+)
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+// text переводит подписи страниц: {{ text .Lang "login_label" }}
+var templates = template.Must(template.New("").Funcs(template.FuncMap{"text": i18n.Text}).ParseFS(templatesFS, "templates/*.html"))
+
+// csrfCookie — cookie с токеном, который форма входа отправляет обратно скрытым полем
+const csrfCookie = "sso_csrf"
+
+type loginError struct {
+	err    error
+	status int
+	// Ключ сообщения i18n
+	message string
+}
+
+// loginErrors — ошибки входа, которые показываются на той же форме
+var loginErrors = []loginError{
+	{authErrors.ErrInvalidUserCredentials, http.StatusUnauthorized, response.CodeInvalidCredentials},
+	{authErrors.ErrContactNotVerified, http.StatusForbidden, response.CodeContactNotVerified},
+	{authErrors.ErrMfaRequired, http.StatusUnauthorized, response.CodeMfaRequired},
+	{authErrors.ErrInvalidMfaCode, http.StatusUnauthorized, response.CodeInvalidMfaCode},
+	{authErrors.ErrInvalidPasskey, http.StatusUnauthorized, response.CodeInvalidPasskey},
+	{authErrors.ErrPasskeyCloned, http.StatusUnauthorized, response.CodePasskeyCloned},
+	{authErrors.ErrTooManyAttempts, http.StatusTooManyRequests, response.CodeTooManyAttempts},
+	{authErrors.ErrTooManyMfaCodes, http.StatusTooManyRequests, response.CodeTooManyMfaCodes},
+}
+
+type OAuthService interface {
+	ValidateAuthorize(ctx context.Context, request oauth.AuthorizeRequest) (*domain.Client, error)
+	Authorize(ctx context.Context, request oauth.AuthorizeRequest, login, password, otp string, passkey *passkeyModels.Credential) (string, error)
+	Token(ctx context.Context, request oauth.TokenRequest) (*oauth.TokenResponse, error)
+	Introspect(ctx context.Context, request oauth.IntrospectRequest) (*oauth.IntrospectionResponse, error)
+	Revoke(ctx context.Context, request oauth.RevokeRequest) error
+}
+
+type Handler struct {
+	s OAuthService
+}
+
+func NewHandler(s OAuthService) *Handler {
+	return &Handler{
+		s: s,
+	}
+}
+
+type loginPage struct {
+	// Язык страницы из Accept-Language
+	Lang       string
+	ClientName string
+	Request    oauth.AuthorizeRequest
+	Login      string
+	Error      string
+	// Показать поле для кода двухфакторной аутентификации
+	Mfa bool
+	// Предложить подтвердить вход ключом WebAuthn
+	PasskeyOptions *passkeyModels.RequestOptions
+	CsrfToken      string
+}
+
+type errorPage struct {
+	Lang  string
+	Error string
+}
+
+// Authorize godoc
+// @Summary OAuth 2.0 authorization endpoint (authorization code + PKCE), renders login page
+// @Tags oauth
+// @Produce html
+// @Param response_type query string true "code"
+// @Param client_id query string true "Client id"
+// @Param redirect_uri query string true "Registered redirect URI"
+// @Param code_challenge query string true "PKCE challenge"
+// @Param code_challenge_method query string true "S256"
+// @Param scope query string false "Scopes, e.g. openid"
+// @Param state query string false "State"
+// @Param nonce query string false "OpenID Connect nonce"
+// @Success 200
+// @Router /oauth/authorize [get]
+func (h *Handler) Authorize(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req oauth.AuthorizeRequest
+	if err := c.Bind(&req); err != nil {
+		return h.renderError(c, oauthErrors.ErrInvalidRequest)
+	}
+
+	client, err := h.s.ValidateAuthorize(ctx, req)
+	if err != nil {
+		return h.authorizeError(c, req, err)
+	}
+
+	return h.renderLogin(c, http.StatusOK, loginPage{ClientName: client.Name, Request: req})
+}
+
+// AuthorizeSubmit godoc
+// @Summary Login form submit, redirects to client with authorization code
+// @Description Форма должна вернуть csrf_token, выданный вместе со страницей в cookie sso_csrf, иначе 403 и новая форма.
+// @Description Ошибки входа показываются на форме на языке из Accept-Language
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce html
+// @Success 302
+// @Failure 401
+// @Failure 403
+// @Failure 429
+// @Router /oauth/authorize [post]
+func (h *Handler) AuthorizeSubmit(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var form oauth.LoginForm
+	if err := c.Bind(&form); err != nil {
+		return h.renderError(c, oauthErrors.ErrInvalidRequest)
+	}
+
+	lang := i18n.FromContext(ctx)
+	if !validCsrf(c, form.CsrfToken) {
+		client, err := h.s.ValidateAuthorize(ctx, form.AuthorizeRequest)
+		if err != nil {
+			return h.authorizeError(c, form.AuthorizeRequest, err)
+		}
+		return h.renderLogin(c, http.StatusForbidden, loginPage{
+			ClientName: client.Name,
+			Request:    form.AuthorizeRequest,
+			Login:      form.Login,
+			Error:      i18n.Text(lang, i18n.MsgLoginFormExpired),
+		})
+	}
+
+	var passkey *passkeyModels.Credential
+	if form.Passkey != "" {
+		passkey = &passkeyModels.Credential{}
+		if err := json.Unmarshal([]byte(form.Passkey), passkey); err != nil {
+			return h.renderError(c, oauthErrors.ErrInvalidRequest)
+		}
+	}
+
+	redirectUri, err := h.s.Authorize(ctx, form.AuthorizeRequest, form.Login, form.Password, form.Otp, passkey)
+	if err != nil {
+		if known, ok := lookupLoginError(err); ok {
+			client, validateErr := h.s.ValidateAuthorize(ctx, form.AuthorizeRequest)
+			if validateErr != nil {
+				return h.authorizeError(c, form.AuthorizeRequest, validateErr)
+			}
+			page := loginPage{
+				ClientName: client.Name,
+				Request:    form.AuthorizeRequest,
+				Login:      form.Login,
+				Error:      i18n.Text(lang, known.message),
+				Mfa:        errors.Is(err, authErrors.ErrMfaRequired) || errors.Is(err, authErrors.ErrInvalidMfaCode) || errors.Is(err, authErrors.ErrTooManyMfaCodes),
+			}
+			var secondFactorErr *oauthService.SecondFactorError
+			if errors.As(err, &secondFactorErr) {
+				page.Mfa = true
+				page.PasskeyOptions = secondFactorErr.PasskeyOptions
+			}
+			return h.renderLogin(c, known.status, page)
+		}
+		return h.authorizeError(c, form.AuthorizeRequest, err)
+	}
+
+	return c.Redirect(http.StatusFound, redirectUri)
+}
+
+// Token godoc
+// @Summary OAuth 2.0 token endpoint (authorization_code, refresh_token, client_credentials)
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "authorization_code, refresh_token or client_credentials"
+// @Param code formData string false "Authorization code"
+// @Param redirect_uri formData string false "Redirect URI used in authorize request"
+// @Param client_id formData string false "Client id"
+// @Param client_secret formData string false "Client secret (confidential clients, client_secret_post)"
+// @Param code_verifier formData string false "PKCE verifier"
+// @Param refresh_token formData string false "Refresh token"
+// @Param scope formData string false "Requested scopes (client_credentials)"
+// @Param client_assertion_type formData string false "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+// @Param client_assertion formData string false "JWT signed with client private key (private_key_jwt)"
+// @Success 200 {object} oauth.TokenResponse
+// @Failure 400 {object} oauth.ErrorResponse
+// @Failure 401 {object} oauth.ErrorResponse
+// @Router /oauth/token [post]
+func (h *Handler) Token(c echo.Context) error {
+	ctx := c.Request().Context()
+	c.Response().Header().Set("Cache-Control", "no-store")
+
+	var req oauth.TokenRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, oauth.ErrorResponse{Error: oauthErrors.ErrInvalidRequest.Code})
+	}
+	basicAuth(c, &req.ClientAuth)
+
+	result, err := h.s.Token(ctx, req)
+	if err != nil {
+		return tokenEndpointError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// Introspect godoc
+// @Summary OAuth 2.0 token introspection (RFC 7662)
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Access or refresh token"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Param client_id formData string false "Client id"
+// @Param client_secret formData string false "Client secret (client_secret_post)"
+// @Param client_assertion_type formData string false "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+// @Param client_assertion formData string false "JWT signed with client private key (private_key_jwt)"
+// @Success 200 {object} oauth.IntrospectionResponse
+// @Failure 400 {object} oauth.ErrorResponse
+// @Failure 401 {object} oauth.ErrorResponse
+// @Router /oauth/introspect [post]
+func (h *Handler) Introspect(c echo.Context) error {
+	ctx := c.Request().Context()
+	c.Response().Header().Set("Cache-Control", "no-store")
+
+	var req oauth.IntrospectRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, oauth.ErrorResponse{Error: oauthErrors.ErrInvalidRequest.Code})
+	}
+	basicAuth(c, &req.ClientAuth)
+
+	result, err := h.s.Introspect(ctx, req)
+	if err != nil {
+		return tokenEndpointError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// Revoke godoc
+// @Summary OAuth 2.0 token revocation (RFC 7009)
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Param token formData string true "Access or refresh token"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Param client_id formData string false "Client id"
+// @Param client_secret formData string false "Client secret (client_secret_post)"
+// @Param client_assertion_type formData string false "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+// @Param client_assertion formData string false "JWT signed with client private key (private_key_jwt)"
+// @Success 200
+// @Failure 400 {object} oauth.ErrorResponse
+// @Failure 401 {object} oauth.ErrorResponse
+// @Router /oauth/revoke [post]
+func (h *Handler) Revoke(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req oauth.RevokeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, oauth.ErrorResponse{Error: oauthErrors.ErrInvalidRequest.Code})
+	}
+	basicAuth(c, &req.ClientAuth)
+
+	if err := h.s.Revoke(ctx, req); err != nil {
+		return tokenEndpointError(c, err)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// basicAuth — client_secret_basic имеет приоритет над client_secret_post
+func basicAuth(c echo.Context, clientAuth *oauth.ClientAuth) {
+	if clientId, clientSecret, ok := c.Request().BasicAuth(); ok {
+		clientAuth.ClientId, clientAuth.ClientSecret = clientId, clientSecret
+	}
+}
+
+// tokenEndpointError отдаёт ошибку в формате RFC 6749: invalid_client - 401, остальное - 400
+func tokenEndpointError(c echo.Context, err error) error {
+	var oauthErr *oauthErrors.Error
+	if !errors.As(err, &oauthErr) {
+		return c.JSON(http.StatusInternalServerError, oauth.ErrorResponse{Error: "server_error"})
+	}
+
+	status := http.StatusBadRequest
+	if oauthErr.Code == oauthErrors.ErrInvalidClient.Code {
+		c.Response().Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		status = http.StatusUnauthorized
+	}
+	return c.JSON(status, oauth.ErrorResponse{
+		Error:            oauthErr.Code,
+		ErrorDescription: oauthErr.Description,
+	})
+}
+
+// authorizeError отдаёт ошибку клиенту редиректом, а если redirect_uri не подтверждён - страницей
+func (h *Handler) authorizeError(c echo.Context, req oauth.AuthorizeRequest, err error) error {
+	var oauthErr *oauthErrors.Error
+	if !errors.As(err, &oauthErr) {
+		oauthErr = &oauthErrors.Error{Code: "server_error", Description: "внутренняя ошибка сервера"}
+	}
+	if errors.Is(err, oauthErrors.ErrInvalidClient) || errors.Is(err, oauthErrors.ErrInvalidRedirectUri) {
+		return h.renderError(c, oauthErr)
+	}
+
+	redirectUri, buildErr := oauthService.RedirectUri(req.RedirectUri, map[string]string{
+		"error":             oauthErr.Code,
+		"error_description": oauthErr.Description,
+		"state":             req.State,
+	})
+	if buildErr != nil {
+		return h.renderError(c, oauthErr)
+	}
+	return c.Redirect(http.StatusFound, redirectUri)
+}
+
+func lookupLoginError(err error) (loginError, bool) {
+	for _, e := range loginErrors {
+		if errors.Is(err, e.err) {
+			return e, true
+		}
+	}
+	return loginError{}, false
+}
+
+// renderLogin отдаёт форму входа с новым CSRF токеном: в скрытом поле и в cookie.
+// Чужой сайт может отправить форму, но не может прочитать ни страницу, ни cookie
+func (h *Handler) renderLogin(c echo.Context, status int, page loginPage) error {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	page.CsrfToken = base64.RawURLEncoding.EncodeToString(raw)
+	c.SetCookie(&http.Cookie{
+		Name:     csrfCookie,
+		Value:    page.CsrfToken,
+		Path:     "/oauth/authorize",
+		HttpOnly: true,
+		Secure:   c.Scheme() == "https",
+		SameSite: http.SameSiteStrictMode,
+	})
+	page.Lang = i18n.FromContext(c.Request().Context())
+	return h.render(c, status, "login.html", page)
+}
+
+// validCsrf — токен из формы совпадает с cookie, выданной вместе с ней
+func validCsrf(c echo.Context, token string) bool {
+	cookie, err := c.Cookie(csrfCookie)
+	if err != nil || cookie.Value == "" || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(token)) == 1
+}
+
+func (h *Handler) renderError(c echo.Context, err *oauthErrors.Error) error {
+	return h.render(c, http.StatusBadRequest, "error.html", errorPage{
+		Lang:  i18n.FromContext(c.Request().Context()),
+		Error: err.Description,
+	})
+}
+
+func (h *Handler) render(c echo.Context, status int, name string, data any) error {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return err
+	}
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("X-Frame-Options", "DENY")
+	return c.HTMLBlob(status, buf.Bytes())
+}
@@ -0,0 +1,187 @@
+package passkey
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/phenirain/sso/internal/application/apierrors"
+	authModels "github.com/phenirain/sso/internal/dto/auth"
+	passkeyModels "github.com/phenirain/sso/internal/dto/passkey"
+	"github.com/phenirain/sso/internal/dto/response"
+	"github.com/phenirain/sso/internal/lib/i18n"
+	"github.com/phenirain/sso/pkg/contextkeys"
+)
+
+type PasskeyService interface {
+	RegisterBegin(ctx context.Context, userId int64) (*passkeyModels.CreationOptions, error)
+	RegisterFinish(ctx context.Context, userId int64, request passkeyModels.RegistrationRequest) (*passkeyModels.PasskeyResponse, error)
+	List(ctx context.Context, userId int64) ([]passkeyModels.PasskeyResponse, error)
+	Delete(ctx context.Context, userId int64, id string) error
+	LoginBegin(ctx context.Context) (*passkeyModels.RequestOptions, error)
+	LoginFinish(ctx context.Context, request passkeyModels.LoginRequest) (*authModels.AuthResponse, error)
+}
+
+type Handler struct {
+	s PasskeyService
+}
+
+func NewHandler(s PasskeyService) *Handler {
+	return &Handler{
+		s: s,
+	}
+}
+
+// RegisterBegin godoc
+// @Summary Start passkey registration for current user
+// @Description Возвращает options для navigator.credentials.create, действуют 5 минут
+// @Tags auth
+// @Produce json
+// @Success 200 {object} passkeyModels.CreationOptions
+// @Failure 401 {object} response.ApiResponse[any]
+// @Router /auth/passkeys/register/begin [post]
+func (h *Handler) RegisterBegin(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, ok := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	result, err := h.s.RegisterBegin(ctx, userId)
+	if err != nil {
+		return apierrors.New(i18n.MsgPasskeyOptionsFailed, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// RegisterFinish godoc
+// @Summary Finish passkey registration
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body passkeyModels.RegistrationRequest true "Result of navigator.credentials.create"
+// @Success 200 {object} passkeyModels.PasskeyResponse
+// @Failure 400 {object} response.ApiResponse[any]
+// @Failure 401 {object} response.ApiResponse[any]
+// @Router /auth/passkeys/register/finish [post]
+func (h *Handler) RegisterFinish(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, ok := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	var req passkeyModels.RegistrationRequest
+	if err := c.Bind(&req); err != nil {
+		return apierrors.New(i18n.MsgInvalidJson, err)
+	}
+	if req.Credential.RawId == "" {
+		return apierrors.BadRequest(response.CodeMissingArgument, i18n.MsgMissingArgument, i18n.MsgCredentialRequired)
+	}
+
+	result, err := h.s.RegisterFinish(ctx, userId, req)
+	if err != nil {
+		return apierrors.New(i18n.MsgPasskeyRegisterFailed, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// List godoc
+// @Summary List passkeys of current user
+// @Tags auth
+// @Produce json
+// @Success 200 {object} response.ApiResponse[[]passkeyModels.PasskeyResponse]
+// @Failure 401 {object} response.ApiResponse[any]
+// @Router /auth/passkeys [get]
+func (h *Handler) List(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, ok := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	result, err := h.s.List(ctx, userId)
+	if err != nil {
+		return apierrors.New(i18n.MsgGetPasskeysFailed, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(&result))
+}
+
+// Delete godoc
+// @Summary Delete passkey of current user
+// @Tags auth
+// @Produce json
+// @Param id path string true "Passkey id"
+// @Success 200 {object} response.ApiResponse[any]
+// @Failure 401 {object} response.ApiResponse[any]
+// @Failure 404 {object} response.ApiResponse[any]
+// @Router /auth/passkeys/{id} [delete]
+func (h *Handler) Delete(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, ok := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	if err := h.s.Delete(ctx, userId, c.Param("id")); err != nil {
+		return apierrors.New(i18n.MsgDeletePasskeyFailed, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse[any](nil))
+}
+
+// LoginBegin godoc
+// @Summary Start login with passkey
+// @Description Возвращает options для navigator.credentials.get. allowCredentials всегда пуст: браузер предложит
+// @Description любой ключ этого сервиса, а по ответу нельзя узнать, зарегистрирован ли логин и какие у него ключи
+// @Tags auth
+// @Produce json
+// @Success 200 {object} passkeyModels.RequestOptions
+// @Router /auth/passkeys/login/begin [post]
+func (h *Handler) LoginBegin(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	result, err := h.s.LoginBegin(ctx)
+	if err != nil {
+		return apierrors.New(i18n.MsgPasskeyOptionsFailed, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// LoginFinish godoc
+// @Summary Finish login with passkey
+// @Description Вход по ключу заменяет пароль и код двухфакторной аутентификации
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body passkeyModels.LoginRequest true "Result of navigator.credentials.get"
+// @Success 200 {object} authModels.AuthResponse
+// @Failure 400 {object} response.ApiResponse[any]
+// @Failure 401 {object} response.ApiResponse[any]
+// @Router /auth/passkeys/login/finish [post]
+func (h *Handler) LoginFinish(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req passkeyModels.LoginRequest
+	if err := c.Bind(&req); err != nil {
+		return apierrors.New(i18n.MsgInvalidJson, err)
+	}
+	if req.Credential.RawId == "" {
+		return apierrors.BadRequest(response.CodeMissingArgument, i18n.MsgMissingArgument, i18n.MsgCredentialRequired)
+	}
+
+	result, err := h.s.LoginFinish(ctx, req)
+	if err != nil {
+		return apierrors.New(i18n.MsgPasskeyLoginFailed, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
@@ -0,0 +1,92 @@
+package password
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/phenirain/sso/internal/application/apierrors"
+	"github.com/phenirain/sso/internal/domain"
+	authModels "github.com/phenirain/sso/internal/dto/auth"
+	"github.com/phenirain/sso/internal/dto/response"
+	"github.com/phenirain/sso/internal/lib/i18n"
+)
+
+type PasswordService interface {
+	Forgot(ctx context.Context, login string) error
+	Reset(ctx context.Context, token, password string) error
+}
+
+type Handler struct {
+	s PasswordService
+}
+
+func NewHandler(s PasswordService) *Handler {
+	return &Handler{
+		s: s,
+	}
+}
+
+// Forgot godoc
+// @Summary Send password reset link
+// @Description Ответ одинаковый независимо от того, существует ли пользователь. Ссылка уходит на подтверждённую почту
+// @Description Запросы с одного адреса ограничены так же, как попытки входа
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body authModels.ForgotPasswordRequest true "Login"
+// @Success 200 {object} response.ApiResponse[any]
+// @Failure 400 {object} response.ApiResponse[any]
+// @Failure 429 {object} response.ApiResponse[any]
+// @Router /auth/password/forgot [post]
+func (h *Handler) Forgot(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req authModels.ForgotPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return apierrors.New(i18n.MsgInvalidJson, err)
+	}
+	if req.Login == "" {
+		return apierrors.BadRequest(response.CodeMissingArgument, i18n.MsgMissingArgument, i18n.MsgLoginRequired)
+	}
+
+	if err := h.s.Forgot(ctx, req.Login); err != nil {
+		return apierrors.New(i18n.MsgForgotPasswordFailed, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse[any](nil))
+}
+
+// Reset godoc
+// @Summary Reset password with token from email
+// @Description Завершает все сессии пользователя и снимает блокировку входа после неудачных попыток
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body authModels.ResetPasswordRequest true "Token and new password"
+// @Success 200 {object} response.ApiResponse[any]
+// @Failure 400 {object} response.ApiResponse[any]
+// @Router /auth/password/reset [post]
+func (h *Handler) Reset(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req authModels.ResetPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return apierrors.New(i18n.MsgInvalidJson, err)
+	}
+	if req.Token == "" {
+		return apierrors.BadRequest(response.CodeMissingArgument, i18n.MsgMissingArgument, i18n.MsgResetTokenRequired)
+	}
+	if req.Password == "" {
+		return apierrors.BadRequest(response.CodeMissingArgument, i18n.MsgMissingArgument, i18n.MsgPasswordRequired)
+	}
+	if len(req.Password) > domain.MaxPasswordBytes {
+		return apierrors.BadRequest(response.CodePasswordTooLong, i18n.MsgInvalidArgument, response.CodePasswordTooLong)
+	}
+
+	if err := h.s.Reset(ctx, req.Token, req.Password); err != nil {
+		return apierrors.New(i18n.MsgResetPasswordFailed, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse[any](nil))
+}
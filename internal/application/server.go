@@ -1,22 +1,52 @@
 package application
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/phenirain/sso/internal/application/apierrors"
 	"github.com/phenirain/sso/internal/application/auth"
+	"github.com/phenirain/sso/internal/application/client"
+	"github.com/phenirain/sso/internal/application/forwardauth"
+	"github.com/phenirain/sso/internal/application/lockout"
+	"github.com/phenirain/sso/internal/application/oauth"
+	"github.com/phenirain/sso/internal/application/mfa"
+	"github.com/phenirain/sso/internal/application/passkey"
+	"github.com/phenirain/sso/internal/application/password"
+	"github.com/phenirain/sso/internal/application/verification"
+	"github.com/phenirain/sso/internal/application/wellknown"
 	"github.com/phenirain/sso/internal/config"
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/internal/lib/i18n"
 	_ "github.com/phenirain/sso/docs"
+	"github.com/phenirain/sso/pkg/contextkeys"
 	"github.com/phenirain/sso/pkg/echomiddleware"
 	echoSwagger "github.com/swaggo/echo-swagger"
 )
 
-func SetupHTTPServer(cfg *config.Config, authService auth.AuthService, jwt echomiddleware.Jwt) *echo.Echo {
+// Jwt — то, что нужно серверу от библиотеки токенов: проверка и публикация ключей
+type Jwt interface {
+	echomiddleware.Jwt
+	wellknown.KeySet
+}
+
+func SetupHTTPServer(cfg *config.Config, authService auth.AuthService, oauthService oauth.OAuthService, clientService client.ClientService, passwordService password.PasswordService, verificationService verification.VerificationService, mfaService mfa.MfaService, passkeyService passkey.PasskeyService, lockoutService lockout.LockoutService, verifier forwardauth.Verifier, jwt Jwt, denylist echomiddleware.Denylist) *echo.Echo {
 	e := echo.New()
+	e.HTTPErrorHandler = apierrors.ErrorHandler
+	e.IPExtractor = ipExtractor(cfg.HTTP.TrustProxyHeaders)
 
 	e.Pre(middleware.RemoveTrailingSlash())
-	e.Use(echomiddleware.JwtValidation(jwt))
+	e.Use(middleware.RequestIDWithConfig(middleware.RequestIDConfig{
+		RequestIDHandler: func(c echo.Context, requestId string) {
+			ctx := context.WithValue(c.Request().Context(), contextkeys.RequestIDCtxKey, requestId)
+			c.SetRequest(c.Request().WithContext(ctx))
+		},
+	}))
+	e.Use(echomiddleware.Language(i18n.Supported, defaultLanguage(cfg.DefaultLanguage)))
+	e.Use(echomiddleware.ClientIP())
+	e.Use(echomiddleware.JwtValidation(jwt, denylist))
 	e.Use(middleware.Recover())
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins: cfg.AllowedOrigins,
@@ -33,15 +63,101 @@ func SetupHTTPServer(cfg *config.Config, authService auth.AuthService, jwt echom
 		return c.String(http.StatusOK, "JWT IS VALID")
 	})
 
-	registerAuthRoutes(e, authService)
+	registerAuthRoutes(e, authService, passwordService, verificationService, mfaService, passkeyService)
+	registerForwardAuthRoutes(e, cfg.ForwardAuth, verifier)
+	registerOAuthRoutes(e, oauthService)
+	registerWellKnownRoutes(e, jwt)
+	registerAdminRoutes(e, clientService, lockoutService)
 
 	return e
 }
 
-func registerAuthRoutes(e *echo.Echo, authService auth.AuthService) {
+func registerAuthRoutes(e *echo.Echo, authService auth.AuthService, passwordService password.PasswordService, verificationService verification.VerificationService, mfaService mfa.MfaService, passkeyService passkey.PasskeyService) {
 	authHandler := auth.NewHandler(authService)
+	passwordHandler := password.NewHandler(passwordService)
+	verificationHandler := verification.NewHandler(verificationService)
+	mfaHandler := mfa.NewHandler(mfaService)
+	passkeyHandler := passkey.NewHandler(passkeyService)
 	auth := e.Group("/auth")
 	auth.POST("/logIn", authHandler.LogIn)
 	auth.POST("/signUp", authHandler.SignUp)
 	auth.POST("/refresh", authHandler.Refresh)
+	auth.POST("/logout", authHandler.Logout)
+	auth.POST("/logout-all", authHandler.LogoutAll)
+	auth.POST("/password", authHandler.ChangePassword)
+	auth.POST("/password/forgot", passwordHandler.Forgot)
+	auth.POST("/password/reset", passwordHandler.Reset)
+	auth.POST("/contact/verify", verificationHandler.Verify)
+	auth.POST("/contact/resend", verificationHandler.Resend)
+	auth.POST("/mfa/totp/enroll", mfaHandler.Enroll)
+	auth.POST("/mfa/totp/confirm", mfaHandler.Confirm)
+	auth.POST("/mfa/totp/disable", mfaHandler.Disable)
+	auth.POST("/mfa/recovery-codes", mfaHandler.RecoveryCodes)
+	auth.POST("/mfa/verify", mfaHandler.Verify)
+	auth.POST("/mfa/passkey/begin", mfaHandler.PasskeyBegin)
+	auth.POST("/mfa/passkey/finish", mfaHandler.PasskeyFinish)
+	auth.GET("/passkeys", passkeyHandler.List)
+	auth.DELETE("/passkeys/:id", passkeyHandler.Delete)
+	auth.POST("/passkeys/register/begin", passkeyHandler.RegisterBegin)
+	auth.POST("/passkeys/register/finish", passkeyHandler.RegisterFinish)
+	auth.POST("/passkeys/login/begin", passkeyHandler.LoginBegin)
+	auth.POST("/passkeys/login/finish", passkeyHandler.LoginFinish)
+
+	e.GET("/userinfo", authHandler.UserInfo)
+	e.POST("/userinfo", authHandler.UserInfo)
+}
+
+// registerForwardAuthRoutes — прокси проверяет любой метод исходного запроса
+func registerForwardAuthRoutes(e *echo.Echo, cfg config.ForwardAuthConfig, verifier forwardauth.Verifier) {
+	forwardAuthHandler := forwardauth.NewHandler(verifier, cfg)
+	e.Any("/auth/verify", forwardAuthHandler.Verify)
+}
+
+func registerOAuthRoutes(e *echo.Echo, oauthService oauth.OAuthService) {
+	oauthHandler := oauth.NewHandler(oauthService)
+	oauth := e.Group("/oauth")
+	oauth.GET("/authorize", oauthHandler.Authorize)
+	oauth.POST("/authorize", oauthHandler.AuthorizeSubmit)
+	oauth.POST("/token", oauthHandler.Token)
+	oauth.POST("/introspect", oauthHandler.Introspect)
+	oauth.POST("/revoke", oauthHandler.Revoke)
+}
+
+func registerWellKnownRoutes(e *echo.Echo, keys wellknown.KeySet) {
+	wellKnownHandler := wellknown.NewHandler(keys)
+	wellKnown := e.Group("/.well-known")
+	wellKnown.GET("/jwks.json", wellKnownHandler.JWKS)
+	wellKnown.GET("/openid-configuration", wellKnownHandler.OpenIDConfiguration)
+}
+
+func registerAdminRoutes(e *echo.Echo, clientService client.ClientService, lockoutService lockout.LockoutService) {
+	clientHandler := client.NewHandler(clientService)
+	lockoutHandler := lockout.NewHandler(lockoutService)
+	admin := e.Group("/admin", echomiddleware.RequireRoles(domain.RoleAdmin))
+	admin.GET("/clients", clientHandler.List)
+	admin.POST("/clients", clientHandler.Create)
+	admin.PUT("/clients/:id", clientHandler.Update)
+	admin.POST("/clients/:id/secret", clientHandler.RotateSecret)
+	admin.POST("/clients/:id/disable", clientHandler.Disable)
+	admin.POST("/clients/:id/enable", clientHandler.Enable)
+	admin.POST("/lockout/unlock", lockoutHandler.Unlock)
+}
+
+// ipExtractor — откуда брать адрес клиента. X-Forwarded-For подделывается клиентом,
+// поэтому ему верим, только если сервис доступен лишь через прокси
+func ipExtractor(trustProxyHeaders bool) echo.IPExtractor {
+	if trustProxyHeaders {
+		return echo.ExtractIPFromXFFHeader()
+	}
+	return echo.ExtractIPDirect()
+}
+
+// defaultLanguage — язык из конфига, если на нём есть сообщения, иначе i18n.Fallback
+func defaultLanguage(lang string) string {
+	for _, supported := range i18n.Supported {
+		if lang == supported {
+			return lang
+		}
+	}
+	return i18n.Fallback
 }
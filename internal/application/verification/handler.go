@@ -0,0 +1,98 @@
+package verification
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/phenirain/sso/internal/application/apierrors"
+	"github.com/phenirain/sso/internal/domain"
+	authModels "github.com/phenirain/sso/internal/dto/auth"
+	"github.com/phenirain/sso/internal/dto/response"
+	"github.com/phenirain/sso/internal/lib/i18n"
+)
+
+type VerificationService interface {
+	Verify(ctx context.Context, login, channel, code string) error
+	Resend(ctx context.Context, login, channel string) error
+}
+
+type Handler struct {
+	s VerificationService
+}
+
+func NewHandler(s VerificationService) *Handler {
+	return &Handler{
+		s: s,
+	}
+}
+
+// Verify godoc
+// @Summary Verify email or phone with code
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body authModels.VerifyContactRequest true "Login, channel and code"
+// @Success 200 {object} response.ApiResponse[any]
+// @Failure 400 {object} response.ApiResponse[any]
+// @Router /auth/contact/verify [post]
+func (h *Handler) Verify(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req authModels.VerifyContactRequest
+	if err := c.Bind(&req); err != nil {
+		return apierrors.New(i18n.MsgInvalidJson, err)
+	}
+	if err := validate(req.Login, req.Channel); err != nil {
+		return err
+	}
+	if req.Code == "" {
+		return apierrors.BadRequest(response.CodeMissingArgument, i18n.MsgMissingArgument, i18n.MsgCodeRequired)
+	}
+
+	if err := h.s.Verify(ctx, req.Login, req.Channel, req.Code); err != nil {
+		return apierrors.New(i18n.MsgVerifyContactFailed, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse[any](nil))
+}
+
+// Resend godoc
+// @Summary Send a new verification code
+// @Description Ответ одинаковый независимо от того, существует ли пользователь.
+// @Description Повторный код в тот же канал отправляется не чаще раза в минуту, запросы с одного IP ограничены: 429 и Retry-After
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body authModels.ResendCodeRequest true "Login and channel"
+// @Success 200 {object} response.ApiResponse[any]
+// @Failure 400 {object} response.ApiResponse[any]
+// @Failure 429 {object} response.ApiResponse[any]
+// @Router /auth/contact/resend [post]
+func (h *Handler) Resend(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req authModels.ResendCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return apierrors.New(i18n.MsgInvalidJson, err)
+	}
+	if err := validate(req.Login, req.Channel); err != nil {
+		return err
+	}
+
+	if err := h.s.Resend(ctx, req.Login, req.Channel); err != nil {
+		return apierrors.New(i18n.MsgResendCodeFailed, err)
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse[any](nil))
+}
+
+func validate(login, channel string) error {
+	if login == "" {
+		return apierrors.BadRequest(response.CodeMissingArgument, i18n.MsgMissingArgument, i18n.MsgLoginRequired)
+	}
+	if channel != domain.ChannelEmail && channel != domain.ChannelPhone {
+		return apierrors.BadRequest(response.CodeInvalidRequest, i18n.MsgBadRequest, i18n.MsgChannelRequired)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package wellknown
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/phenirain/sso/internal/dto/oidc"
+	"github.com/phenirain/sso/internal/lib/jwt"
+)
+
+type KeySet interface {
+	JWKS() jwt.JWKS
+	Issuer() string
+	SigningAlgorithms() []string
+	SupportsIdTokens() bool
+}
+
+type Handler struct {
+	keys KeySet
+}
+
+func NewHandler(keys KeySet) *Handler {
+	return &Handler{
+		keys: keys,
+	}
+}
+
+// OpenIDConfiguration godoc
+// @Summary OpenID Connect discovery document
+// @Tags well-known
+// @Produce json
+// @Description С ключом подписи HMAC OpenID Connect выключен: 404
+// @Success 200 {object} oidc.ProviderMetadata
+// @Failure 404
+// @Router /.well-known/openid-configuration [get]
+func (h *Handler) OpenIDConfiguration(c echo.Context) error {
+	// ID токен, подписанный общим секретом, клиент проверить не сможет - не объявляем OpenID Connect
+	if !h.keys.SupportsIdTokens() {
+		return echo.ErrNotFound
+	}
+	issuer := strings.TrimSuffix(h.keys.Issuer(), "/")
+
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, oidc.ProviderMetadata{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/oauth/authorize",
+		TokenEndpoint:                     issuer + "/oauth/token",
+		IntrospectionEndpoint:             issuer + "/oauth/introspect",
+		RevocationEndpoint:                issuer + "/oauth/revoke",
+		JwksUri:                           issuer + "/.well-known/jwks.json",
+		UserinfoEndpoint:                  issuer + "/userinfo",
+		ScopesSupported:                   []string{"openid", "profile"},
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code", "refresh_token", "client_credentials"},
+		SubjectTypesSupported:             []string{"public"},
+		IdTokenSigningAlgValuesSupported:  h.keys.SigningAlgorithms(),
+		TokenEndpointAuthMethodsSupported: []string{"none", "client_secret_basic", "client_secret_post", "private_key_jwt"},
+		TokenEndpointAuthSigningAlgValuesSupported: jwt.ClientAssertionAlgorithms,
+		CodeChallengeMethodsSupported:              []string{"S256"},
+		ClaimsSupported:                            []string{"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "preferred_username", "role_id", "email", "email_verified", "phone_number", "phone_number_verified"},
+	})
+}
+
+// JWKS godoc
+// @Summary Public keys for token signature verification
+// @Tags well-known
+// @Produce json
+// @Success 200 {object} jwt.JWKS
+// @Router /.well-known/jwks.json [get]
+func (h *Handler) JWKS(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, h.keys.JWKS())
+}
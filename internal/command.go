@@ -0,0 +1,115 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/phenirain/sso/internal/config"
+	"github.com/phenirain/sso/internal/lib/jwt"
+	"github.com/phenirain/sso/internal/repository/client"
+	"github.com/phenirain/sso/internal/repository/denylist"
+	"github.com/phenirain/sso/internal/repository/passkey"
+	"github.com/phenirain/sso/internal/repository/recoverycode"
+	"github.com/phenirain/sso/internal/repository/refreshtoken"
+	"github.com/phenirain/sso/internal/repository/user"
+	"github.com/phenirain/sso/internal/repository/verification"
+	"github.com/phenirain/sso/internal/services/auth"
+	denylistService "github.com/phenirain/sso/internal/services/denylist"
+	"github.com/phenirain/sso/internal/services/mfa"
+	"github.com/phenirain/sso/pkg/database"
+	"github.com/phenirain/sso/pkg/logger"
+)
+
+const commandUsage = `usage:
+  sso                                                          запустить сервер
+  sso migrate up | down [steps] | status                       миграции схемы
+  sso user create --login <login> [--password <p>] [--role buyer|admin]
+  sso user archive <login>
+  sso user reset-password <login> [--password <p>]
+  sso user reset-mfa <login>
+  sso keys rotate [--alg ES256] [--dir config/keys] [--id <kid>]
+  sso token issue --user <id> [--client <id>] [--scope <scope>]
+
+Если пароль не указан - он генерируется и печатается один раз`
+
+// ErrUsage — подкоманда вызвана неправильно, текст ошибки - справка
+var ErrUsage = errors.New(commandUsage)
+
+// Command выполняет административную подкоманду. args - аргументы бинаря без его имени
+func Command(cfg *config.Config, args []string) error {
+	if err := logger.Setup(cfg.Env); err != nil {
+		return fmt.Errorf("failed to setup logger: %w", err)
+	}
+	if len(args) == 0 {
+		return ErrUsage
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "migrate":
+		db := database.MustInitDb(cfg.ConnectionString)
+		defer db.Close()
+		return migrate(ctx, db, args[1:])
+	case "user":
+		db := database.MustInitDb(cfg.ConnectionString)
+		defer db.Close()
+		return userCommand(ctx, newCommandServices(cfg, db), args[1:])
+	case "keys":
+		return keysCommand(cfg, args[1:])
+	case "token":
+		db := database.MustInitDb(cfg.ConnectionString)
+		defer db.Close()
+		return tokenCommand(ctx, newCommandServices(cfg, db), args[1:])
+	case "help", "-h", "--help":
+		fmt.Println(commandUsage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n%w", args[0], ErrUsage)
+	}
+}
+
+// commandServices — то, что нужно подкомандам: те же сервисы, что и серверу
+type commandServices struct {
+	users   *user.UserRepository
+	clients *client.ClientRepository
+	auth    *auth.Auth
+	mfa     *mfa.Mfa
+}
+
+func newCommandServices(cfg *config.Config, db *sqlx.DB) *commandServices {
+	usersRepository := user.New(db)
+	clientsRepository := client.New(db)
+	jwtLib := jwt.NewJwtLib(accessTokenTTL, mustLoadKeySet(cfg, refreshTokenTTL), cfg.OIDC.Issuer)
+	tokenDenylist := denylistService.New(usersRepository, denylist.New(db), time.Second*30)
+	loginLockout := newLockout(cfg.Lockout, db)
+	contactVerifier := newVerification(cfg.Verification, usersRepository, verification.New(db), loginLockout, mustInitNotifier(cfg.Notifier))
+	authService := auth.New(usersRepository, refreshtoken.New(db), clientsRepository, tokenDenylist, contactVerifier, loginLockout, jwtLib, refreshTokenTTL, mustRequireVerified(cfg.Verification))
+
+	return &commandServices{
+		users:   usersRepository,
+		clients: clientsRepository,
+		auth:    authService,
+		mfa:     newMfa(cfg.Mfa, usersRepository, recoverycode.New(db), authService, jwtLib, tokenDenylist, newPasskey(cfg, passkey.New(db), usersRepository, authService), loginLockout),
+	}
+}
+
+// parseArgs разбирает флаги подкоманды. Позиционный аргумент (логин) можно указать
+// как до флагов, так и после них
+func parseArgs(fs *flag.FlagSet, args []string) (string, error) {
+	var positional string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		positional, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if positional == "" {
+		positional = fs.Arg(0)
+	}
+	return positional, nil
+}
@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/phenirain/sso/internal/config"
+	"github.com/phenirain/sso/internal/lib/jwt"
+)
+
+// keysCommand — "sso keys rotate": создаёт новый ключ подписи и печатает, как включить его в конфиге.
+// Сам конфиг не меняется: ключ сначала раскатывается на все экземпляры, и только потом становится текущим
+func keysCommand(cfg *config.Config, args []string) error {
+	if len(args) == 0 || args[0] != "rotate" {
+		return ErrUsage
+	}
+
+	fs := flag.NewFlagSet("keys rotate", flag.ContinueOnError)
+	algorithm := fs.String("alg", "ES256", "алгоритм: RS256, PS256, ES256, ES384, ES512, EdDSA, ...")
+	dir := fs.String("dir", "config/keys", "каталог для PEM файла")
+	id := fs.String("id", "", "kid ключа, по умолчанию - по текущему времени")
+	if _, err := parseArgs(fs, args[1:]); err != nil {
+		return err
+	}
+	if *id == "" {
+		*id = "key-" + time.Now().UTC().Format("20060102-150405")
+	}
+
+	_, pemData, err := jwt.GenerateKey(*id, *algorithm)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(*dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create keys dir: %w", err)
+	}
+	path := filepath.Join(*dir, *id+".pem")
+	// O_EXCL - не перезаписываем существующий ключ
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create key file: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(pemData); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+
+	previous := cfg.Jwt.CurrentKeyId
+	if previous == "" {
+		previous = "default"
+	}
+	fmt.Printf(`ключ сохранён в %s
+
+1. добавьте его в jwt.keys и раскатите конфиг на все экземпляры:
+    - id: %q
+      algorithm: %s
+      private_key_path: %q
+2. сделайте его текущим: jwt.current_key_id: %q
+   и проставьте ключу %q retired_at - момент переключения (RFC3339)
+3. когда истекут выпущенные им токены, ключ %q можно удалить из конфига
+`, path, *id, *algorithm, path, *id, previous, previous)
+	return nil
+}
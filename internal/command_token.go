@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	"github.com/phenirain/sso/internal/domain"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	clientErrors "github.com/phenirain/sso/internal/errors/client"
+	oauthErrors "github.com/phenirain/sso/internal/errors/oauth"
+)
+
+// tokenCommand — "sso token issue": выпускает пользователю пару токенов, как при входе,
+// при необходимости - с настройками OAuth клиента
+func tokenCommand(ctx context.Context, s *commandServices, args []string) error {
+	if len(args) == 0 || args[0] != "issue" {
+		return ErrUsage
+	}
+
+	fs := flag.NewFlagSet("token issue", flag.ContinueOnError)
+	userId := fs.Int64("user", 0, "id пользователя")
+	clientId := fs.String("client", "", "OAuth клиент, чьи TTL и audience применить")
+	scope := fs.String("scope", "", "scope токена, только вместе с --client")
+	if _, err := parseArgs(fs, args[1:]); err != nil {
+		return err
+	}
+	if *userId == 0 {
+		return fmt.Errorf("--user is required\n%w", ErrUsage)
+	}
+
+	user, err := s.users.GetUserWithId(ctx, *userId)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.IsArchived {
+		return authErrors.ErrUserNotFound
+	}
+
+	var client *domain.Client
+	if *clientId != "" {
+		client, err = s.clients.GetClient(ctx, *clientId)
+		if err != nil {
+			return err
+		}
+		if client == nil {
+			return clientErrors.ErrClientNotFound
+		}
+		resolved, ok := client.ResolveScope(*scope)
+		if !ok {
+			return oauthErrors.ErrInvalidScope
+		}
+		*scope = resolved
+	}
+
+	result, err := s.auth.IssueTokens(ctx, user, client, *scope, nil)
+	if err != nil {
+		return err
+	}
+
+	out, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
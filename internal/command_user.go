@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+
+	"github.com/phenirain/sso/internal/domain"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+)
+
+var roles = map[string]int64{
+	"buyer": domain.RoleBuyer,
+	"admin": domain.RoleAdmin,
+}
+
+// userCommand — "sso user create|archive|reset-password|reset-mfa"
+func userCommand(ctx context.Context, s *commandServices, args []string) error {
+	if len(args) == 0 {
+		return ErrUsage
+	}
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("user create", flag.ContinueOnError)
+		login := fs.String("login", "", "логин")
+		password := fs.String("password", "", "пароль, по умолчанию генерируется")
+		roleName := fs.String("role", "buyer", "роль: buyer или admin")
+		if _, err := parseArgs(fs, args[1:]); err != nil {
+			return err
+		}
+		if *login == "" {
+			return fmt.Errorf("--login is required\n%w", ErrUsage)
+		}
+		roleId, ok := roles[*roleName]
+		if !ok {
+			return fmt.Errorf("unknown role %q", *roleName)
+		}
+
+		generated := *password == ""
+		if generated {
+			*password = generatePassword()
+		}
+		user, err := s.auth.CreateUser(ctx, *login, *password, roleId)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("пользователь создан: id=%d login=%s role=%s\n", user.Id, user.Login, *roleName)
+		if generated {
+			fmt.Printf("пароль: %s\n", *password)
+		}
+	case "archive":
+		fs := flag.NewFlagSet("user archive", flag.ContinueOnError)
+		login, err := parseArgs(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		if login == "" {
+			return fmt.Errorf("login is required\n%w", ErrUsage)
+		}
+
+		if err := s.auth.ArchiveUser(ctx, login); err != nil {
+			return err
+		}
+		fmt.Printf("пользователь %s архивирован, его сессии завершены\n", login)
+	case "reset-password":
+		fs := flag.NewFlagSet("user reset-password", flag.ContinueOnError)
+		password := fs.String("password", "", "новый пароль, по умолчанию генерируется")
+		login, err := parseArgs(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		if login == "" {
+			return fmt.Errorf("login is required\n%w", ErrUsage)
+		}
+
+		generated := *password == ""
+		if generated {
+			*password = generatePassword()
+		}
+		if err := s.auth.ResetPassword(ctx, login, *password); err != nil {
+			return err
+		}
+		fmt.Printf("пароль пользователя %s изменён, его сессии завершены\n", login)
+		if generated {
+			fmt.Printf("пароль: %s\n", *password)
+		}
+	case "reset-mfa":
+		fs := flag.NewFlagSet("user reset-mfa", flag.ContinueOnError)
+		login, err := parseArgs(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		if login == "" {
+			return fmt.Errorf("login is required\n%w", ErrUsage)
+		}
+
+		user, err := s.users.GetUserByLogin(ctx, login)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return authErrors.ErrUserNotFound
+		}
+		if err := s.mfa.Reset(ctx, user.Id); err != nil {
+			return err
+		}
+		fmt.Printf("двухфакторная аутентификация пользователя %s отключена\n", login)
+	default:
+		return fmt.Errorf("unknown user command %q\n%w", args[0], ErrUsage)
+	}
+	return nil
+}
+
+func generatePassword() string {
+	b := make([]byte, 18)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -8,16 +8,153 @@ import (
 )
 
 type Config struct {
-	Env              string     `mapstructure:"env"`
-	ConnectionString string     `mapstructure:"connection_string"`
-	AllowedOrigins   []string   `mapstructure:"allowed_origins"`
-	Secret           string     `mapstructure:"secret"`
-	HTTP             HTTPConfig `mapstructure:"http"`
+	Env              string              `mapstructure:"env"`
+	ConnectionString string              `mapstructure:"connection_string"`
+	AutoMigrate      bool                `mapstructure:"auto_migrate"`
+	AllowedOrigins   []string            `mapstructure:"allowed_origins"`
+	DefaultLanguage  string              `mapstructure:"default_language"`
+	Secret           string              `mapstructure:"secret"`
+	Jwt              JwtConfig           `mapstructure:"jwt"`
+	OIDC             OIDCConfig          `mapstructure:"oidc"`
+	ForwardAuth      ForwardAuthConfig   `mapstructure:"forward_auth"`
+	Notifier         NotifierConfig      `mapstructure:"notifier"`
+	PasswordReset    PasswordResetConfig `mapstructure:"password_reset"`
+	Verification     VerificationConfig  `mapstructure:"verification"`
+	Mfa              MfaConfig           `mapstructure:"mfa"`
+	WebAuthn         WebAuthnConfig      `mapstructure:"webauthn"`
+	Lockout          LockoutConfig       `mapstructure:"lockout"`
+	HTTP             HTTPConfig          `mapstructure:"http"`
+}
+
+type ForwardAuthConfig struct {
+	// cookie с access токеном, если прокси не передаёт заголовок Authorization
+	CookieName string `mapstructure:"cookie_name"`
+	// Страница входа для браузера без токена, пусто - всегда 401
+	LoginUrl string `mapstructure:"login_url"`
+}
+
+type NotifierConfig struct {
+	// smtp - отправка писем, file - запись в FilePath или в лог (для разработки)
+	Type     string     `mapstructure:"type"`
+	FilePath string     `mapstructure:"file_path"`
+	SMTP     SMTPConfig `mapstructure:"smtp"`
+}
+
+type SMTPConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	// Адрес отправителя
+	From string `mapstructure:"from"`
+}
+
+type PasswordResetConfig struct {
+	// Страница сброса пароля во фронтенде, токен добавляется параметром token
+	Url string `mapstructure:"url"`
+	// Время жизни ссылки
+	TTL time.Duration `mapstructure:"ttl"`
+}
+
+// Режимы подтверждения контактов
+const (
+	// Вход разрешён, в токенах email_verified и phone_number_verified
+	VerificationModeClaim = "claim"
+	// Вход только после подтверждения почты или телефона
+	VerificationModeRequire = "require"
+)
+
+type VerificationConfig struct {
+	// claim или require, по умолчанию claim
+	Mode string `mapstructure:"mode"`
+	// Страница подтверждения во фронтенде, ссылка в письме - url?login=...&channel=email&code=...
+	Url string `mapstructure:"url"`
+	// Время жизни ссылки из письма
+	EmailTTL time.Duration `mapstructure:"email_ttl"`
+	// Время жизни кода из SMS
+	PhoneTTL time.Duration `mapstructure:"phone_ttl"`
+	// Минимальный интервал между отправками кода в один канал
+	ResendInterval time.Duration `mapstructure:"resend_interval"`
+}
+
+type MfaConfig struct {
+	// Название сервиса в приложении-аутентификаторе
+	Issuer string `mapstructure:"issuer"`
+	// Ключ шифрования секретов TOTP в базе, 32 байта в base64. Без него 2FA не подключить
+	EncryptionKey string `mapstructure:"encryption_key"`
+}
+
+type WebAuthnConfig struct {
+	// Домен, к которому привязываются ключи, пусто - хост из oidc.issuer.
+	// Менять нельзя: ключи, созданные для другого домена, перестанут работать
+	RpId string `mapstructure:"rp_id"`
+	// Название сервиса в окне выбора ключа
+	RpName string `mapstructure:"rp_name"`
+	// Адреса страниц, с которых идёт вход по ключу, пусто - адрес из oidc.issuer
+	Origins []string `mapstructure:"origins"`
+}
+
+// Хранилища счётчиков неудачных попыток входа
+const (
+	// В памяти процесса, только для одной реплики
+	LockoutStoreMemory = "memory"
+	// В Postgres, общие для всех реплик
+	LockoutStorePostgres = "postgres"
+)
+
+type LockoutConfig struct {
+	// memory или postgres, по умолчанию memory
+	Store string `mapstructure:"store"`
+	// Через сколько после последней неудачи счётчик обнуляется
+	Window time.Duration `mapstructure:"window"`
+	// Попытки с одним логином
+	Login LockoutPolicyConfig `mapstructure:"login"`
+	// Попытки с одного IP адреса с любыми логинами
+	Ip LockoutPolicyConfig `mapstructure:"ip"`
+}
+
+// LockoutPolicyConfig — нулевые значения заменяются значениями по умолчанию
+type LockoutPolicyConfig struct {
+	// Сколько неудач подряд проходят без задержки
+	FreeAttempts int `mapstructure:"free_attempts"`
+	// После стольких неудач - блокировка на lockout_duration
+	MaxFailures int `mapstructure:"max_failures"`
+	// Задержка после первой неудачи сверх free_attempts, дальше удваивается
+	BaseDelay time.Duration `mapstructure:"base_delay"`
+	// Блокировка после max_failures, каждая следующая неудача её удваивает
+	LockoutDuration time.Duration `mapstructure:"lockout_duration"`
+	// Верхняя граница блокировки
+	MaxLockout time.Duration `mapstructure:"max_lockout"`
+}
+
+type OIDCConfig struct {
+	// Внешний адрес сервиса, он же iss в токенах
+	Issuer string `mapstructure:"issuer"`
+}
+
+type JwtConfig struct {
+	// Ключ, которым подписываются новые токены
+	CurrentKeyId string         `mapstructure:"current_key_id"`
+	Keys         []JwtKeyConfig `mapstructure:"keys"`
+}
+
+type JwtKeyConfig struct {
+	// kid в заголовке токена
+	Id string `mapstructure:"id"`
+	// HS256 - подпись секретом Secret (по умолчанию secret из корня конфига),
+	// RS256/ES256/EdDSA и т.п. - ключом из PrivateKeyPath
+	Algorithm      string `mapstructure:"algorithm"`
+	PrivateKeyPath string `mapstructure:"private_key_path"`
+	Secret         string `mapstructure:"secret"`
+	// RFC 3339. С этого момента ключ только проверяет ранее выпущенные токены
+	RetiredAt string `mapstructure:"retired_at"`
 }
 
 type HTTPConfig struct {
 	Port    int           `mapstructure:"port"`
 	Timeout time.Duration `mapstructure:"timeout"`
+	// Брать адрес клиента из X-Forwarded-For. Только за прокси, иначе адрес подделывается
+	TrustProxyHeaders bool `mapstructure:"trust_proxy_headers"`
 }
 
 func LoadConfig() (*Config, error) {
@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"slices"
+	"strings"
+	"time"
+)
+
+// CodeChallengeMethodS256 — единственный поддерживаемый метод PKCE
+const CodeChallengeMethodS256 = "S256"
+
+// AuthorizationCode — одноразовый код OAuth authorization code flow.
+// Сам код не храним, только его хеш
+type AuthorizationCode struct {
+	CodeHash            string     `db:"code_hash"`
+	ClientId            string     `db:"client_id"`
+	UserId              int64      `db:"user_id"`
+	RedirectUri         string     `db:"redirect_uri"`
+	Scope               string     `db:"scope"`
+	Nonce               string     `db:"nonce"`
+	CodeChallenge       string     `db:"code_challenge"`
+	CodeChallengeMethod string     `db:"code_challenge_method"`
+	AuthTime            time.Time  `db:"auth_datetime"`
+	ExpiresAt           time.Time  `db:"expires_at"`
+	UsedAt              *time.Time `db:"used_at"`
+}
+
+// NewAuthorizationCode создаёт код и возвращает его значение для редиректа клиенту
+func NewAuthorizationCode(clientId string, userId int64, redirectUri, scope, nonce, codeChallenge string, ttl time.Duration) (*AuthorizationCode, string) {
+	code := NewTokenId() + NewTokenId()
+	now := time.Now()
+	return &AuthorizationCode{
+		CodeHash:            HashToken(code),
+		ClientId:            clientId,
+		UserId:              userId,
+		RedirectUri:         redirectUri,
+		Scope:               scope,
+		Nonce:               nonce,
+		CodeChallenge:       codeChallenge,
+		CodeChallengeMethod: CodeChallengeMethodS256,
+		AuthTime:            now,
+		ExpiresAt:           now.Add(ttl),
+	}, code
+}
+
+// CheckVerifier проверяет PKCE: BASE64URL(SHA256(code_verifier)) == code_challenge
+func (c *AuthorizationCode) CheckVerifier(verifier string) bool {
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(challenge), []byte(c.CodeChallenge)) == 1
+}
+
+func (c *AuthorizationCode) IsExpired() bool {
+	return time.Now().After(c.ExpiresAt)
+}
+
+// HasScope проверяет, что в запрошенных правах есть scope
+func (c *AuthorizationCode) HasScope(scope string) bool {
+	return slices.Contains(strings.Fields(c.Scope), scope)
+}
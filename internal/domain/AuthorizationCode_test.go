@@ -0,0 +1,34 @@
+package domain
+
+import "testing"
+
+func TestCheckVerifier(t *testing.T) {
+	// пример из RFC 7636, приложение B
+	const verifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	const challenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	tests := []struct {
+		name      string
+		challenge string
+		verifier  string
+		want      bool
+	}{
+		{"rfc 7636 example", challenge, verifier, true},
+		{"other verifier", challenge, verifier[:len(verifier)-1] + "l", false},
+		{"empty verifier", challenge, "", false},
+		{"challenge as verifier", challenge, challenge, false},
+		// метод plain не поддерживается
+		{"plain method", verifier, verifier, false},
+		{"padded challenge", challenge + "=", verifier, false},
+		{"standard base64 challenge", "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw+cM", verifier, false},
+		{"empty challenge", "", verifier, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := &AuthorizationCode{CodeChallenge: tt.challenge}
+			if got := code.CheckVerifier(tt.verifier); got != tt.want {
+				t.Errorf("CheckVerifier(%q) = %v, want %v", tt.verifier, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package domain
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Гранты OAuth, которые может разрешить клиент
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantRefreshToken      = "refresh_token"
+	GrantClientCredentials = "client_credentials"
+)
+
+// Client — приложение, которому разрешено получать токены через OAuth.
+// Клиент без секрета и ключа - публичный (SPA, мобильное приложение), для него обязателен PKCE
+type Client struct {
+	Id         string `db:"id"`
+	Name       string `db:"name"`
+	SecretHash []byte `db:"secret_hash"`
+	// PEM публичного ключа для аутентификации private_key_jwt
+	PublicKey    string         `db:"public_key"`
+	RedirectUris pq.StringArray `db:"redirect_uris"`
+	GrantTypes   pq.StringArray `db:"grant_types"`
+	Scopes       pq.StringArray `db:"scopes"`
+	// Время жизни токенов в секундах, 0 - значение по умолчанию
+	AccessTokenTTL  int64 `db:"access_token_ttl"`
+	RefreshTokenTTL int64 `db:"refresh_token_ttl"`
+	// aud в access токенах, пустой - без aud
+	Audience     string     `db:"audience"`
+	IsDisabled   bool       `db:"is_disabled"`
+	CreationTime time.Time  `db:"creation_datetime"`
+	UpdateTime   *time.Time `db:"update_datetime"`
+}
+
+// NewClient создаёт клиента. Для конфиденциального клиента возвращает его секрет -
+// показать его можно только один раз
+func NewClient(id, name string, confidential bool) (*Client, string) {
+	if id == "" {
+		id = NewTokenId()
+	}
+	client := &Client{
+		Id:           id,
+		Name:         name,
+		CreationTime: time.Now(),
+	}
+	var secret string
+	if confidential {
+		secret = client.RotateSecret()
+	}
+	return client, secret
+}
+
+// RotateSecret выпускает новый секрет, старый сразу перестаёт работать
+func (c *Client) RotateSecret() string {
+	secret := NewTokenId() + NewTokenId()
+	c.SecretHash, _ = bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	c.updateDateTime()
+	return secret
+}
+
+// IsConfidential — клиент умеет аутентифицироваться секретом или ключом
+func (c *Client) IsConfidential() bool {
+	return len(c.SecretHash) > 0 || c.PublicKey != ""
+}
+
+func (c *Client) CheckSecret(secret string) bool {
+	if len(c.SecretHash) == 0 {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword(c.SecretHash, []byte(secret)) == nil
+}
+
+// SetPublicKey задаёт ключ для private_key_jwt, пустая строка - убрать ключ
+func (c *Client) SetPublicKey(publicKey string) {
+	c.PublicKey = publicKey
+	c.updateDateTime()
+}
+
+func (c *Client) ChangeDisabledStatus(status bool) {
+	c.IsDisabled = status
+	c.updateDateTime()
+}
+
+// UpdateSettings меняет всё, кроме идентификатора и секрета
+func (c *Client) UpdateSettings(name string, redirectUris, grantTypes, scopes []string, accessTTL, refreshTTL int64, audience string) {
+	c.Name = name
+	c.RedirectUris = redirectUris
+	c.GrantTypes = grantTypes
+	c.Scopes = scopes
+	c.AccessTokenTTL = accessTTL
+	c.RefreshTokenTTL = refreshTTL
+	c.Audience = audience
+	c.updateDateTime()
+}
+
+// HasRedirectUri — redirect_uri сравнивается только точным совпадением
+func (c *Client) HasRedirectUri(uri string) bool {
+	return slices.Contains(c.RedirectUris, uri)
+}
+
+func (c *Client) AllowsGrant(grantType string) bool {
+	return slices.Contains(c.GrantTypes, grantType)
+}
+
+// ResolveScope проверяет запрошенные права. Пустой запрос - все права клиента
+func (c *Client) ResolveScope(requested string) (string, bool) {
+	if requested == "" {
+		return strings.Join(c.Scopes, " "), true
+	}
+	for _, scope := range strings.Fields(requested) {
+		if !slices.Contains(c.Scopes, scope) {
+			return "", false
+		}
+	}
+	return requested, true
+}
+
+// AccessTTL — время жизни access токена клиента или defaultTTL, если не задано
+func (c *Client) AccessTTL(defaultTTL time.Duration) time.Duration {
+	if c.AccessTokenTTL > 0 {
+		return time.Duration(c.AccessTokenTTL) * time.Second
+	}
+	return defaultTTL
+}
+
+// RefreshTTL — время жизни refresh токена клиента или defaultTTL, если не задано
+func (c *Client) RefreshTTL(defaultTTL time.Duration) time.Duration {
+	if c.RefreshTokenTTL > 0 {
+		return time.Duration(c.RefreshTokenTTL) * time.Second
+	}
+	return defaultTTL
+}
+
+func (c *Client) updateDateTime() {
+	t := time.Now()
+	c.UpdateTime = &t
+}
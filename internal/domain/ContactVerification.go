@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// Каналы, по которым подтверждаются контакты пользователя
+const (
+	ChannelEmail = "email"
+	ChannelPhone = "phone"
+)
+
+// MaxVerificationAttempts — сколько раз можно ошибиться в коде, прежде чем он перестанет действовать.
+// Короткий код из SMS иначе перебирается
+const MaxVerificationAttempts = 5
+
+// ContactVerification — действующий код подтверждения контакта, не больше одного на канал.
+// Сам код не храним, только его хеш
+type ContactVerification struct {
+	UserId       int64     `db:"user_id"`
+	Channel      string    `db:"channel"`
+	CodeHash     string    `db:"code_hash"`
+	Attempts     int       `db:"attempts"`
+	ExpiresAt    time.Time `db:"expires_at"`
+	CreationTime time.Time `db:"creation_datetime"`
+}
+
+// NewContactVerification создаёт код и возвращает его значение для отправки пользователю.
+// Для почты код длинный - он уходит ссылкой, для телефона - 6 цифр, их вводят руками
+func NewContactVerification(userId int64, channel string, ttl time.Duration) (*ContactVerification, string) {
+	var code string
+	if channel == ChannelPhone {
+		n, _ := rand.Int(rand.Reader, big.NewInt(1_000_000))
+		code = fmt.Sprintf("%06d", n.Int64())
+	} else {
+		code = NewTokenId()
+	}
+
+	now := time.Now()
+	return &ContactVerification{
+		UserId:       userId,
+		Channel:      channel,
+		CodeHash:     HashToken(code),
+		ExpiresAt:    now.Add(ttl),
+		CreationTime: now,
+	}, code
+}
+
+// CheckCode сверяет предъявленный код с сохранённым хешем
+func (v *ContactVerification) CheckCode(code string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashToken(code)), []byte(v.CodeHash)) == 1
+}
+
+// IsUsable — код не истёк и попытки ввода не исчерпаны
+func (v *ContactVerification) IsUsable() bool {
+	return time.Now().Before(v.ExpiresAt) && v.Attempts < MaxVerificationAttempts
+}
@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+// LoginAttempts — неудачные попытки входа по ключу: логину или IP адресу клиента.
+// Попытка считается неудачной с самого начала и отменяется, если вход удался
+type LoginAttempts struct {
+	Key      string `db:"key"`
+	Failures int    `db:"failures"`
+	// По ней считается блокировка и обнуление счётчика
+	LastFailureTime time.Time `db:"last_failure_datetime"`
+}
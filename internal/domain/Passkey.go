@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Церемонии WebAuthn, для которых выдаётся challenge
+const (
+	CeremonyRegistration = "registration"
+	CeremonyLogin        = "login"
+	// Passkey как второй фактор после пароля
+	CeremonyMfa = "mfa"
+)
+
+// Passkey — ключ WebAuthn пользователя
+type Passkey struct {
+	// Идентификатор учётных данных от аутентификатора, base64url
+	Id     string `db:"id"`
+	UserId int64  `db:"user_id"`
+	// Название, которое видит пользователь в списке ключей
+	Name string `db:"name"`
+	// Публичный ключ в формате COSE_Key
+	PublicKey []byte `db:"public_key"`
+	// Счётчик подписей: если аутентификатор его ведёт, он обязан расти
+	SignCount int64 `db:"sign_count"`
+	// Способы связи с аутентификатором (usb, nfc, internal...) - подсказка браузеру
+	Transports   pq.StringArray `db:"transports"`
+	CreationTime time.Time      `db:"creation_datetime"`
+	LastUsedTime *time.Time     `db:"last_used_datetime"`
+}
+
+func NewPasskey(id string, userId int64, name string, publicKey []byte, signCount uint32, transports []string) *Passkey {
+	return &Passkey{
+		Id:           id,
+		UserId:       userId,
+		Name:         name,
+		PublicKey:    publicKey,
+		SignCount:    int64(signCount),
+		Transports:   transports,
+		CreationTime: time.Now(),
+	}
+}
+
+// Use запоминает новый счётчик подписей после успешного входа
+func (p *Passkey) Use(signCount uint32) {
+	now := time.Now()
+	p.SignCount = int64(signCount)
+	p.LastUsedTime = &now
+}
+
+// PasskeyChallenge — выданный challenge WebAuthn, одноразовый. Храним только хеш
+type PasskeyChallenge struct {
+	ChallengeHash string `db:"challenge_hash"`
+	// Пользователь, если он известен заранее: регистрация и второй фактор
+	UserId       *int64    `db:"user_id"`
+	Ceremony     string    `db:"ceremony"`
+	ExpiresAt    time.Time `db:"expires_at"`
+	CreationTime time.Time `db:"creation_datetime"`
+}
+
+func NewPasskeyChallenge(challenge string, userId *int64, ceremony string, ttl time.Duration) *PasskeyChallenge {
+	now := time.Now()
+	return &PasskeyChallenge{
+		ChallengeHash: HashToken(challenge),
+		UserId:        userId,
+		Ceremony:      ceremony,
+		ExpiresAt:     now.Add(ttl),
+		CreationTime:  now,
+	}
+}
+
+func (c *PasskeyChallenge) IsExpired() bool {
+	return time.Now().After(c.ExpiresAt)
+}
@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+// PasswordResetToken — одноразовый токен сброса пароля из письма.
+// Сам токен не храним, только его хеш
+type PasswordResetToken struct {
+	TokenHash    string     `db:"token_hash"`
+	UserId       int64      `db:"user_id"`
+	ExpiresAt    time.Time  `db:"expires_at"`
+	CreationTime time.Time  `db:"creation_datetime"`
+	UsedAt       *time.Time `db:"used_at"`
+}
+
+// NewPasswordResetToken создаёт токен и возвращает его значение для ссылки в письме
+func NewPasswordResetToken(userId int64, ttl time.Duration) (*PasswordResetToken, string) {
+	token := NewTokenId() + NewTokenId()
+	now := time.Now()
+	return &PasswordResetToken{
+		TokenHash:    HashToken(token),
+		UserId:       userId,
+		ExpiresAt:    now.Add(ttl),
+		CreationTime: now,
+	}, token
+}
+
+func (t *PasswordResetToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+)
+
+// RecoveryCodesCount — сколько резервных кодов выдаём при подключении 2FA
+const RecoveryCodesCount = 10
+
+// MaxMfaAttempts — сколько раз подряд можно ошибиться в коде TOTP. Дальше принимаются только
+// резервные коды: шесть цифр иначе перебираются
+const MaxMfaAttempts = 10
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewRecoveryCodes создаёт одноразовые резервные коды на случай потери телефона.
+// Возвращает коды для пользователя и их хеши для хранения
+func NewRecoveryCodes() (codes []string, hashes []string) {
+	codes = make([]string, RecoveryCodesCount)
+	hashes = make([]string, RecoveryCodesCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		_, _ = rand.Read(b)
+		code := strings.ToLower(recoveryEncoding.EncodeToString(b))
+		codes[i] = code[:4] + "-" + code[4:]
+		hashes[i] = HashRecoveryCode(codes[i])
+	}
+	return codes, hashes
+}
+
+// HashRecoveryCode — хеш резервного кода. Регистр и дефис не важны, их легко перепутать при вводе
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	return HashToken(code)
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// RefreshToken — выданный refresh токен (сессия пользователя).
+// Сам токен не храним, только его хеш. ClientId - клиент OAuth, которому выдан токен,
+// пустой - вход через /auth/logIn
+type RefreshToken struct {
+	Id           string     `db:"id"`
+	FamilyId     string     `db:"family_id"`
+	UserId       int64      `db:"user_id"`
+	ClientId     string     `db:"client_id"`
+	Scope        string     `db:"scope"`
+	TokenHash    string     `db:"token_hash"`
+	ExpiresAt    time.Time  `db:"expires_at"`
+	CreationTime time.Time  `db:"creation_datetime"`
+	UsedAt       *time.Time `db:"used_at"`
+	RevokedAt    *time.Time `db:"revoked_at"`
+}
+
+// NewRefreshToken создаёт запись о токене. Пустой familyId - начало новой сессии
+func NewRefreshToken(userId int64, familyId, clientId, scope string, ttl time.Duration) *RefreshToken {
+	if familyId == "" {
+		familyId = NewTokenId()
+	}
+	now := time.Now()
+	return &RefreshToken{
+		Id:           NewTokenId(),
+		FamilyId:     familyId,
+		UserId:       userId,
+		ClientId:     clientId,
+		Scope:        scope,
+		ExpiresAt:    now.Add(ttl),
+		CreationTime: now,
+	}
+}
+
+// SetToken запоминает хеш подписанного токена
+func (t *RefreshToken) SetToken(token string) {
+	t.TokenHash = HashToken(token)
+}
+
+func (t *RefreshToken) CheckToken(token string) bool {
+	return t.TokenHash == HashToken(token)
+}
+
+// IsActive — токен ещё можно обменять: не использован, не отозван и не истёк
+func (t *RefreshToken) IsActive() bool {
+	return t.UsedAt == nil && t.RevokedAt == nil && time.Now().Before(t.ExpiresAt)
+}
+
+// NewTokenId генерирует случайный идентификатор для jti и семейства токенов
+func NewTokenId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -2,13 +2,24 @@ package domain
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Роли пользователей (таблица roles)
+const (
+	RoleBuyer int64 = 1
+	RoleAdmin int64 = 2
+)
+
+// MaxPasswordBytes — bcrypt учитывает только первые 72 байта пароля
+const MaxPasswordBytes = 72
+
 var (
 	ErrInvalidOldPassword error = errors.New("cтарый пароль не совпадает с текущим")
+	ErrPasswordTooLong    error = errors.New("пароль длиннее 72 байт")
 )
 
 type User struct {
@@ -19,18 +30,39 @@ type User struct {
 	CreationTime time.Time  `db:"creation_datetime"`
 	UpdateTime   *time.Time `db:"update_datetime"`
 	IsArchived   bool       `db:"is_archived"`
+	// Токены, выпущенные раньше этого момента, недействительны
+	TokensNotBefore *time.Time `db:"tokens_not_before"`
+	// Контакты пользователя и подтверждено ли, что они ему принадлежат
+	Email         string `db:"email"`
+	EmailVerified bool   `db:"email_verified"`
+	Phone         string `db:"phone"`
+	PhoneVerified bool   `db:"phone_verified"`
+	// Секрет TOTP, зашифрованный ключом из конфига. Есть и до подтверждения подключения
+	TotpSecret *string `db:"totp_secret"`
+	// Двухфакторная аутентификация подключена: без кода токены не выдаются
+	TotpEnabled bool `db:"totp_enabled"`
+	// Последний принятый шаг TOTP, код из него повторно не принимается
+	TotpLastStep int64 `db:"totp_last_step"`
+	// Проверки второго фактора подряд без успеха. Меняется только атомарно в репозитории
+	MfaAttempts int `db:"mfa_attempts"`
+	// Есть ли у пользователя ключи WebAuthn. Вычисляется при чтении, в users не хранится
+	HasPasskeys bool `db:"has_passkeys"`
 }
 
-func NewUser(login, password string, roleId *int64, isArchived *bool) *User {
+func NewUser(login, password string, roleId *int64, isArchived *bool) (*User, error) {
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
 	user := &User{
-		Login: login,
+		Login:        login,
+		PasswordHash: passwordHash,
 	}
-	user.PasswordHash, _ = bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if roleId != nil {
 		user.RoleId = *roleId
 	} else {
-		// 1 = покупатель - база
-		user.RoleId = 1
+		// покупатель - база
+		user.RoleId = RoleBuyer
 	}
 	user.CreationTime = time.Now()
 	if isArchived != nil {
@@ -39,7 +71,7 @@ func NewUser(login, password string, roleId *int64, isArchived *bool) *User {
 		user.IsArchived = false
 	}
 
-	return user
+	return user, nil
 }
 
 func (u *User) CheckPassword(password string) bool {
@@ -55,17 +87,136 @@ func (u *User) UpdateLogin(login string) {
 func (u *User) UpdatePassword(oldPass, newPass string) error {
 	oldCorrect := u.CheckPassword(oldPass)
 	if oldCorrect {
-		u.PasswordHash, _ = bcrypt.GenerateFromPassword([]byte(newPass), bcrypt.DefaultCost)
-		u.updateDateTime()
-		return nil
+		return u.SetPassword(newPass)
 	} else {
 		return ErrInvalidOldPassword
 	}
 }
 
+// SetPassword меняет пароль без проверки старого (сброс администратором)
+// и делает недействительными все токены пользователя
+func (u *User) SetPassword(password string) error {
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = passwordHash
+	u.updateDateTime()
+	u.RevokeTokens()
+	return nil
+}
+
+// ValidatePassword проверяет, что пароль можно захешировать: до того как тратить
+// одноразовые токены и коды на его установку
+func ValidatePassword(password string) error {
+	if len(password) > MaxPasswordBytes {
+		return ErrPasswordTooLong
+	}
+	return nil
+}
+
+func hashPassword(password string) ([]byte, error) {
+	if err := ValidatePassword(password); err != nil {
+		return nil, err
+	}
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка хеширования пароля: %w", err)
+	}
+	return passwordHash, nil
+}
+
 func (u *User) ChangeArchiveStatus(status bool) {
 	u.IsArchived = status
 	u.updateDateTime()
+	if status {
+		u.RevokeTokens()
+	}
+}
+
+// SetEmail задаёт почту пользователя. Новый адрес нужно подтвердить заново
+func (u *User) SetEmail(email string) {
+	if u.Email == email {
+		return
+	}
+	u.Email = email
+	u.EmailVerified = false
+	u.updateDateTime()
+}
+
+// SetPhone задаёт телефон пользователя. Новый номер нужно подтвердить заново
+func (u *User) SetPhone(phone string) {
+	if u.Phone == phone {
+		return
+	}
+	u.Phone = phone
+	u.PhoneVerified = false
+	u.updateDateTime()
+}
+
+// Contact — адрес пользователя в канале channel, пусто - не указан
+func (u *User) Contact(channel string) string {
+	switch channel {
+	case ChannelEmail:
+		return u.Email
+	case ChannelPhone:
+		return u.Phone
+	default:
+		return ""
+	}
+}
+
+// IsContactVerified — подтверждён ли контакт в канале channel
+func (u *User) IsContactVerified(channel string) bool {
+	switch channel {
+	case ChannelEmail:
+		return u.EmailVerified
+	case ChannelPhone:
+		return u.PhoneVerified
+	default:
+		return false
+	}
+}
+
+// VerifyContact отмечает контакт в канале channel подтверждённым
+func (u *User) VerifyContact(channel string) {
+	switch channel {
+	case ChannelEmail:
+		u.EmailVerified = true
+	case ChannelPhone:
+		u.PhoneVerified = true
+	default:
+		return
+	}
+	u.updateDateTime()
+}
+
+// IsVerified — пользователь подтвердил хотя бы один контакт. Пользователям без контактов
+// подтверждать нечего, они считаются подтверждёнными
+func (u *User) IsVerified() bool {
+	if u.Email == "" && u.Phone == "" {
+		return true
+	}
+	return u.EmailVerified || u.PhoneVerified
+}
+
+// RequiresSecondFactor — после пароля нужен второй фактор: код TOTP или ключ WebAuthn
+func (u *User) RequiresSecondFactor() bool {
+	return u.TotpEnabled || u.HasPasskeys
+}
+
+// DisableTotp отключает двухфакторную аутентификацию и забывает секрет
+func (u *User) DisableTotp() {
+	u.TotpSecret = nil
+	u.TotpEnabled = false
+	u.TotpLastStep = 0
+}
+
+// RevokeTokens делает недействительными все ранее выпущенные токены пользователя.
+// iat в токене и время в базе - с точностью до микросекунды, с ней и храним границу
+func (u *User) RevokeTokens() {
+	t := time.Now().Truncate(time.Microsecond)
+	u.TokensNotBefore = &t
 }
 
 func (u *User) updateDateTime() {
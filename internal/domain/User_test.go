@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSetPassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		wantErr  error
+	}{
+		{"ordinary password", "correct horse battery staple", nil},
+		{"exactly 72 bytes", strings.Repeat("a", MaxPasswordBytes), nil},
+		// bcrypt молча обрезал бы хвост - такой пароль не принимаем
+		{"73 bytes", strings.Repeat("a", MaxPasswordBytes+1), ErrPasswordTooLong},
+		{"72 characters of cyrillic", strings.Repeat("я", MaxPasswordBytes), ErrPasswordTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := NewUser("user@example.com", "initial", nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			oldHash := user.PasswordHash
+
+			err = user.SetPassword(tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SetPassword: got %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if string(user.PasswordHash) != string(oldHash) || user.TokensNotBefore != nil {
+					t.Error("failed SetPassword changed the user")
+				}
+				if _, err := NewUser("user@example.com", tt.password, nil, nil); !errors.Is(err, tt.wantErr) {
+					t.Errorf("NewUser: got %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if !user.CheckPassword(tt.password) || user.CheckPassword("initial") {
+				t.Error("password was not changed")
+			}
+		})
+	}
+}
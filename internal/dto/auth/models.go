@@ -5,15 +5,122 @@ package auth
 type AuthRequest struct {
 	// Логин пользователя
 	Login string `json:"login" example:"user@example.com"`
-	// Пароль пользователя
+	// Пароль пользователя, не длиннее 72 байт
 	Password string `json:"password" example:"P@ssw0rd!"`
+	// Телефон в формате E.164, только при регистрации. На него придёт код подтверждения
+	Phone string `json:"phone,omitempty" example:"+79991234567"`
+	// Клиент OpenID Connect. Если указан - в ответе будет ID токен для него; неизвестный или отключённый клиент - 400
+	ClientId string `json:"client_id,omitempty" example:"grafana"`
+	// nonce клиента OpenID Connect, попадёт в ID токен
+	Nonce string `json:"nonce,omitempty"`
+}
+
+// ChangePasswordRequest — смена пароля текущего пользователя
+// swagger:model ChangePasswordRequest
+type ChangePasswordRequest struct {
+	// Текущий пароль
+	OldPassword string `json:"old_password" example:"P@ssw0rd!"`
+	// Новый пароль, не длиннее 72 байт
+	NewPassword string `json:"new_password" example:"N3wP@ssw0rd!"`
+}
+
+// ForgotPasswordRequest — запрос ссылки для сброса пароля
+// swagger:model ForgotPasswordRequest
+type ForgotPasswordRequest struct {
+	// Логин пользователя, на него же придёт письмо
+	Login string `json:"login" example:"user@example.com"`
+}
+
+// ResetPasswordRequest — новый пароль по токену из письма
+// swagger:model ResetPasswordRequest
+type ResetPasswordRequest struct {
+	// Токен из ссылки в письме
+	Token string `json:"token"`
+	// Новый пароль, не длиннее 72 байт
+	Password string `json:"password" example:"N3wP@ssw0rd!"`
+}
+
+// VerifyContactRequest — подтверждение почты или телефона кодом
+// swagger:model VerifyContactRequest
+type VerifyContactRequest struct {
+	// Логин пользователя
+	Login string `json:"login" example:"user@example.com"`
+	// email или phone
+	Channel string `json:"channel" example:"email"`
+	// Код из письма или SMS
+	Code string `json:"code" example:"123456"`
+}
+
+// ResendCodeRequest — повторная отправка кода подтверждения
+// swagger:model ResendCodeRequest
+type ResendCodeRequest struct {
+	// Логин пользователя
+	Login string `json:"login" example:"user@example.com"`
+	// email или phone
+	Channel string `json:"channel" example:"email"`
+}
+
+// MfaCodeRequest — код из приложения-аутентификатора или резервный код
+// swagger:model MfaCodeRequest
+type MfaCodeRequest struct {
+	// Код TOTP или резервный код
+	Code string `json:"code" example:"123456"`
+}
+
+// MfaVerifyRequest — второй шаг входа с подключённой двухфакторной аутентификацией
+// swagger:model MfaVerifyRequest
+type MfaVerifyRequest struct {
+	// mfa_token из ответа logIn
+	MfaToken string `json:"mfa_token"`
+	// Код TOTP или резервный код
+	Code string `json:"code" example:"123456"`
+}
+
+// MfaEnrollResponse — секрет TOTP для приложения-аутентификатора
+// swagger:model MfaEnrollResponse
+type MfaEnrollResponse struct {
+	// Секрет в base32 для ручного ввода
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+	// otpauth:// адрес для QR кода
+	Uri string `json:"uri" example:"otpauth://totp/SSO:user@example.com?secret=JBSWY3DPEHPK3PXP&issuer=SSO"`
+}
+
+// RecoveryCodesResponse — одноразовые резервные коды, показываются пользователю один раз
+// swagger:model RecoveryCodesResponse
+type RecoveryCodesResponse struct {
+	Codes []string `json:"codes" example:"abcd-efgh"`
 }
 
 // AuthResponse возвращает JWT токены после успешной аутентификации
 // swagger:model AuthResponse
 type AuthResponse struct {
 	// Refresh Token для обновления пары токенов
-	RefreshToken string `json:"refresh_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
 	// Access Token для доступа к защищенным ресурсам
-	AccessToken string `json:"access_token"`
+	AccessToken string `json:"access_token,omitempty"`
+	// ID Token OpenID Connect, если был указан client_id
+	IdToken string `json:"id_token,omitempty"`
+	// Нужен второй фактор (TOTP или ключ): токенов нет, вход завершается запросом
+	// /auth/mfa/verify с этим токеном и кодом или /auth/mfa/passkey/finish с ключом
+	MfaToken string `json:"mfa_token,omitempty"`
+	// Регистрация прошла, но токены выдаются только после подтверждения почты или телефона:
+	// коды уже отправлены, подтверждение - /auth/contact/verify, затем вход
+	VerificationRequired bool `json:"verification_required,omitempty"`
+}
+
+// Identity — владелец access токена для forward auth. У токена сервиса заполнен только ClientId
+type Identity struct {
+	UserId   int64
+	RoleId   int64
+	Login    string
+	ClientId string
+}
+
+// UnlockRequest — снятие блокировки входа после неудачных попыток
+// swagger:model UnlockRequest
+type UnlockRequest struct {
+	// Логин, вход по которому заблокирован
+	Login string `json:"login,omitempty" example:"user@example.com"`
+	// IP адрес, с которого вход заблокирован
+	Ip string `json:"ip,omitempty" example:"203.0.113.10"`
 }
@@ -0,0 +1,47 @@
+package client
+
+import "time"
+
+// ClientRequest — настройки клиента OAuth для создания и изменения
+// swagger:model ClientRequest
+type ClientRequest struct {
+	// Идентификатор клиента (client_id). Если не указан при создании - будет сгенерирован
+	Id string `json:"id,omitempty" example:"grafana"`
+	// Название приложения
+	Name string `json:"name" example:"Grafana"`
+	// Разрешённые redirect_uri, сравниваются точным совпадением
+	RedirectUris []string `json:"redirect_uris" example:"https://grafana.example.com/login/generic_oauth"`
+	// Разрешённые гранты: authorization_code, refresh_token, client_credentials
+	GrantTypes []string `json:"grant_types" example:"authorization_code,refresh_token"`
+	// Разрешённые права (scope)
+	Scopes []string `json:"scopes" example:"openid,profile"`
+	// Время жизни access токена в секундах, не указано - по умолчанию
+	AccessTokenTTL *int64 `json:"access_token_ttl,omitempty" example:"3600"`
+	// Время жизни refresh токена в секундах, не указано - по умолчанию
+	RefreshTokenTTL *int64 `json:"refresh_token_ttl,omitempty" example:"2592000"`
+	// aud в access токенах клиента
+	Audience string `json:"audience,omitempty" example:"https://api.example.com"`
+	// Конфиденциальный клиент получает секрет, учитывается только при создании
+	IsConfidential bool `json:"is_confidential"`
+	// PEM публичного ключа для аутентификации private_key_jwt, пустой - без ключа
+	PublicKey string `json:"public_key,omitempty"`
+}
+
+// ClientResponse — клиент OAuth
+// swagger:model ClientResponse
+type ClientResponse struct {
+	Id              string    `json:"id"`
+	Name            string    `json:"name"`
+	RedirectUris    []string  `json:"redirect_uris"`
+	GrantTypes      []string  `json:"grant_types"`
+	Scopes          []string  `json:"scopes"`
+	AccessTokenTTL  int64     `json:"access_token_ttl"`
+	RefreshTokenTTL int64     `json:"refresh_token_ttl"`
+	Audience        string    `json:"audience,omitempty"`
+	PublicKey       string    `json:"public_key,omitempty"`
+	IsConfidential  bool      `json:"is_confidential"`
+	IsDisabled      bool      `json:"is_disabled"`
+	CreationTime    time.Time `json:"creation_datetime"`
+	// Секрет клиента. Возвращается только при создании и перевыпуске
+	Secret string `json:"secret,omitempty"`
+}
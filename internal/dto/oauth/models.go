@@ -0,0 +1,97 @@
+package oauth
+
+// AuthorizeRequest — параметры запроса /oauth/authorize (RFC 6749, RFC 7636)
+type AuthorizeRequest struct {
+	ResponseType        string `query:"response_type" form:"response_type"`
+	ClientId            string `query:"client_id" form:"client_id"`
+	RedirectUri         string `query:"redirect_uri" form:"redirect_uri"`
+	Scope               string `query:"scope" form:"scope"`
+	State               string `query:"state" form:"state"`
+	Nonce               string `query:"nonce" form:"nonce"`
+	CodeChallenge       string `query:"code_challenge" form:"code_challenge"`
+	CodeChallengeMethod string `query:"code_challenge_method" form:"code_challenge_method"`
+}
+
+// LoginForm — форма входа на странице /oauth/authorize
+type LoginForm struct {
+	AuthorizeRequest
+	Login    string `form:"login"`
+	Password string `form:"password"`
+	// Код двухфакторной аутентификации, если она подключена
+	Otp string `form:"otp"`
+	// Ответ ключа WebAuthn в JSON вместо кода - заполняет скрипт страницы
+	Passkey string `form:"passkey"`
+	// Защита от CSRF: должен совпасть с cookie, выданной вместе со страницей
+	CsrfToken string `form:"csrf_token"`
+}
+
+// ClientAuth — аутентификация клиента на token, introspection и revocation endpoint.
+// client_secret_basic из заголовка Authorization перекладывается сюда же
+type ClientAuth struct {
+	ClientId     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	// private_key_jwt (RFC 7523)
+	ClientAssertionType string `form:"client_assertion_type"`
+	ClientAssertion     string `form:"client_assertion"`
+}
+
+// TokenRequest — параметры запроса /oauth/token
+type TokenRequest struct {
+	ClientAuth
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectUri  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+}
+
+// IntrospectRequest — параметры запроса /oauth/introspect (RFC 7662)
+type IntrospectRequest struct {
+	ClientAuth
+	Token string `form:"token"`
+	// access_token или refresh_token, подсказка с какого типа начать поиск
+	TokenTypeHint string `form:"token_type_hint"`
+}
+
+// RevokeRequest — параметры запроса /oauth/revoke (RFC 7009)
+type RevokeRequest struct {
+	ClientAuth
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"`
+}
+
+// IntrospectionResponse — ответ /oauth/introspect. Для недействительного токена - только active: false
+// swagger:model IntrospectionResponse
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientId  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty" example:"access_token"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Aud       string `json:"aud,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+	RoleId    int64  `json:"role_id,omitempty"`
+}
+
+// TokenResponse — ответ /oauth/token
+// swagger:model TokenResponse
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type" example:"Bearer"`
+	ExpiresIn    int64  `json:"expires_in" example:"3600"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IdToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// ErrorResponse — ошибка OAuth 2.0
+// swagger:model OAuthErrorResponse
+type ErrorResponse struct {
+	Error            string `json:"error" example:"invalid_grant"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
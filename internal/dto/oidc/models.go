@@ -0,0 +1,39 @@
+package oidc
+
+// UserInfo — стандартные claims пользователя для /userinfo
+// swagger:model UserInfo
+type UserInfo struct {
+	// Идентификатор пользователя
+	Sub string `json:"sub" example:"42"`
+	// Логин пользователя
+	PreferredUsername string `json:"preferred_username" example:"user@example.com"`
+	// Роль пользователя
+	RoleId int64 `json:"role_id" example:"1"`
+	// Почта и подтверждена ли она
+	Email         string `json:"email,omitempty" example:"user@example.com"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+	// Телефон и подтверждён ли он
+	PhoneNumber         string `json:"phone_number,omitempty" example:"+79991234567"`
+	PhoneNumberVerified *bool  `json:"phone_number_verified,omitempty"`
+}
+
+// ProviderMetadata — документ /.well-known/openid-configuration
+// swagger:model ProviderMetadata
+type ProviderMetadata struct {
+	Issuer                                     string   `json:"issuer"`
+	AuthorizationEndpoint                      string   `json:"authorization_endpoint"`
+	TokenEndpoint                              string   `json:"token_endpoint"`
+	IntrospectionEndpoint                      string   `json:"introspection_endpoint"`
+	RevocationEndpoint                         string   `json:"revocation_endpoint"`
+	JwksUri                                    string   `json:"jwks_uri"`
+	UserinfoEndpoint                           string   `json:"userinfo_endpoint"`
+	ScopesSupported                            []string `json:"scopes_supported"`
+	ResponseTypesSupported                     []string `json:"response_types_supported"`
+	GrantTypesSupported                        []string `json:"grant_types_supported"`
+	SubjectTypesSupported                      []string `json:"subject_types_supported"`
+	IdTokenSigningAlgValuesSupported           []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported          []string `json:"token_endpoint_auth_methods_supported"`
+	TokenEndpointAuthSigningAlgValuesSupported []string `json:"token_endpoint_auth_signing_alg_values_supported"`
+	CodeChallengeMethodsSupported              []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                            []string `json:"claims_supported"`
+}
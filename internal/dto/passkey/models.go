@@ -0,0 +1,137 @@
+package passkey
+
+import "time"
+
+// Модели повторяют PublicKeyCredentialCreationOptions, PublicKeyCredentialRequestOptions и
+// PublicKeyCredential из спецификации WebAuthn. Бинарные поля - строки base64url без выравнивания
+
+// RelyingParty — сервис, к которому привязывается ключ
+type RelyingParty struct {
+	// Домен, к которому привязаны ключи
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// User — пользователь, которому создаётся ключ
+type User struct {
+	// user handle, base64url
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// CredentialParameter — алгоритм ключа, который мы принимаем
+type CredentialParameter struct {
+	Type string `json:"type" example:"public-key"`
+	// Идентификатор алгоритма COSE: -7 ES256, -8 EdDSA, -257 RS256
+	Alg int64 `json:"alg" example:"-7"`
+}
+
+// CredentialDescriptor — уже известный ключ пользователя
+type CredentialDescriptor struct {
+	Type string `json:"type" example:"public-key"`
+	// Идентификатор ключа, base64url
+	Id         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+// AuthenticatorSelection — требования к аутентификатору
+type AuthenticatorSelection struct {
+	ResidentKey string `json:"residentKey" example:"required"`
+	// То же для браузеров, которые не знают residentKey (WebAuthn Level 1)
+	RequireResidentKey bool   `json:"requireResidentKey" example:"true"`
+	UserVerification   string `json:"userVerification" example:"required"`
+}
+
+// CreationOptions — параметры для navigator.credentials.create({publicKey: ...})
+// swagger:model PasskeyCreationOptions
+type CreationOptions struct {
+	// challenge, base64url
+	Challenge        string                `json:"challenge"`
+	Rp               RelyingParty          `json:"rp"`
+	User             User                  `json:"user"`
+	PubKeyCredParams []CredentialParameter `json:"pubKeyCredParams"`
+	// Время на церемонию в миллисекундах
+	Timeout int64 `json:"timeout" example:"300000"`
+	// Ключи, которые уже зарегистрированы - второй раз тот же аутентификатор не добавить
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation" example:"none"`
+}
+
+// RequestOptions — параметры для navigator.credentials.get({publicKey: ...})
+// swagger:model PasskeyRequestOptions
+type RequestOptions struct {
+	// challenge, base64url
+	Challenge string `json:"challenge"`
+	// Время на церемонию в миллисекундах
+	Timeout int64  `json:"timeout" example:"300000"`
+	RpId    string `json:"rpId" example:"sso.example.com"`
+	// Ключи, которыми можно войти. Пустой список - браузер предложит любой ключ для этого сервиса
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification" example:"required"`
+}
+
+// CredentialResponse — response из PublicKeyCredential. При регистрации заполнены
+// clientDataJSON, attestationObject и transports, при входе - clientDataJSON,
+// authenticatorData, signature и userHandle
+type CredentialResponse struct {
+	ClientDataJSON    string   `json:"clientDataJSON"`
+	AttestationObject string   `json:"attestationObject,omitempty"`
+	AuthenticatorData string   `json:"authenticatorData,omitempty"`
+	Signature         string   `json:"signature,omitempty"`
+	UserHandle        string   `json:"userHandle,omitempty"`
+	Transports        []string `json:"transports,omitempty"`
+}
+
+// Credential — результат navigator.credentials.create или navigator.credentials.get
+// swagger:model PasskeyCredential
+type Credential struct {
+	// Идентификатор ключа, base64url
+	Id       string             `json:"id"`
+	RawId    string             `json:"rawId"`
+	Type     string             `json:"type" example:"public-key"`
+	Response CredentialResponse `json:"response"`
+}
+
+// RegistrationRequest — завершение регистрации ключа
+// swagger:model PasskeyRegistrationRequest
+type RegistrationRequest struct {
+	// Название ключа в списке, по умолчанию "Passkey"
+	Name       string     `json:"name,omitempty" example:"MacBook"`
+	Credential Credential `json:"credential"`
+}
+
+// LoginRequest — завершение входа по ключу
+// swagger:model PasskeyLoginRequest
+type LoginRequest struct {
+	Credential Credential `json:"credential"`
+	// Клиент OpenID Connect. Если указан - в ответе будет ID токен для него; неизвестный или отключённый клиент - 400
+	ClientId string `json:"client_id,omitempty" example:"grafana"`
+	// nonce клиента OpenID Connect, попадёт в ID токен
+	Nonce string `json:"nonce,omitempty"`
+}
+
+// MfaBeginRequest — начало проверки ключа вторым фактором
+// swagger:model PasskeyMfaBeginRequest
+type MfaBeginRequest struct {
+	// mfa_token из ответа logIn
+	MfaToken string `json:"mfa_token"`
+}
+
+// MfaRequest — второй шаг входа ключом вместо кода
+// swagger:model PasskeyMfaRequest
+type MfaRequest struct {
+	// mfa_token из ответа logIn
+	MfaToken   string     `json:"mfa_token"`
+	Credential Credential `json:"credential"`
+}
+
+// PasskeyResponse — ключ пользователя в списке
+// swagger:model PasskeyResponse
+type PasskeyResponse struct {
+	Id           string     `json:"id"`
+	Name         string     `json:"name"`
+	CreationTime time.Time  `json:"creation_datetime"`
+	LastUsedTime *time.Time `json:"last_used_datetime,omitempty"`
+}
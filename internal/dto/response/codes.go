@@ -0,0 +1,50 @@
+package response
+
+// Коды ошибок ApiResponse
+const (
+	CodeInvalidRequest   = "INVALID_REQUEST"
+	CodeMissingArgument  = "MISSING_ARGUMENT"
+	CodeInternal         = "INTERNAL_ERROR"
+	CodeUnauthorized     = "UNAUTHORIZED"
+	CodeForbidden        = "FORBIDDEN"
+	CodeNotFound         = "NOT_FOUND"
+	CodeMethodNotAllowed = "METHOD_NOT_ALLOWED"
+
+	CodeInvalidCredentials  = "INVALID_CREDENTIALS"
+	CodeInvalidOldPassword  = "INVALID_OLD_PASSWORD"
+	CodePasswordTooLong     = "PASSWORD_TOO_LONG"
+	CodeUserExists          = "USER_EXISTS"
+	CodeUserNotFound        = "USER_NOT_FOUND"
+	CodeMissingToken        = "MISSING_TOKEN"
+	CodeInvalidToken        = "INVALID_TOKEN"
+	CodeInvalidTokenType    = "INVALID_TOKEN_TYPE"
+	CodeTokenExpired        = "TOKEN_EXPIRED"
+	CodeTokenRevoked        = "TOKEN_REVOKED"
+	CodeInvalidRefreshToken = "INVALID_REFRESH_TOKEN"
+	CodeRefreshTokenReused  = "REFRESH_TOKEN_REUSED"
+	CodeInvalidResetToken   = "INVALID_RESET_TOKEN"
+	CodeUnknownClient       = "UNKNOWN_CLIENT"
+	CodeOpenIdUnavailable   = "OPENID_UNAVAILABLE"
+
+	CodeInvalidPhone            = "INVALID_PHONE"
+	CodeContactNotVerified      = "CONTACT_NOT_VERIFIED"
+	CodeInvalidVerificationCode = "INVALID_VERIFICATION_CODE"
+
+	CodeMfaRequired       = "MFA_REQUIRED"
+	CodeMfaAlreadyEnabled = "MFA_ALREADY_ENABLED"
+	CodeMfaNotEnabled     = "MFA_NOT_ENABLED"
+	CodeMfaUnavailable    = "MFA_UNAVAILABLE"
+	CodeInvalidMfaCode    = "INVALID_MFA_CODE"
+	CodeInvalidMfaToken   = "INVALID_MFA_TOKEN"
+
+	CodeInvalidPasskey  = "INVALID_PASSKEY"
+	CodePasskeyNotFound = "PASSKEY_NOT_FOUND"
+	CodePasskeyCloned   = "PASSKEY_CLONED"
+
+	CodeTooManyAttempts = "TOO_MANY_ATTEMPTS"
+	CodeTooManyMfaCodes = "TOO_MANY_MFA_CODES"
+
+	CodeClientNotFound        = "CLIENT_NOT_FOUND"
+	CodeClientExists          = "CLIENT_EXISTS"
+	CodeInvalidClientSettings = "INVALID_CLIENT_SETTINGS"
+)
@@ -6,6 +6,9 @@ type ApiResponse[T any] struct {
 	// Статус ответа
 	Success bool `json:"success"`
 
+	// Код ошибки для клиентов, не меняется вместе с текстом сообщения
+	Code string `json:"code,omitempty" example:"INVALID_CREDENTIALS"`
+
 	// Сообщение (комментарий) об ошибке
 	Message string `json:"message,omitempty"`
 
@@ -14,11 +17,15 @@ type ApiResponse[T any] struct {
 
 	// Детали ошибки
 	Details string `json:"details,omitempty"`
+
+	// Идентификатор запроса для внутренней ошибки - по нему ищется запись в логах
+	CorrelationId string `json:"correlation_id,omitempty"`
 }
 
-func NewBadResponse[T any](message, details string) ApiResponse[T] {
+func NewBadResponse[T any](code, message, details string) ApiResponse[T] {
 	return ApiResponse[T]{
 		Success: false,
+		Code: code,
 		Message: message,
 		Details: details,
 	}
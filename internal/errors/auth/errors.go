@@ -1,9 +1,50 @@
 package auth
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	ErrInvalidUserCredentials = errors.New("неверен логин или пароль")
 	ErrUserAlreadyExists      = errors.New("пользователь уже существует")
 	ErrUserNotFound           = errors.New("пользователь не существует")
+	ErrInvalidRefreshToken    = errors.New("refresh токен недействителен")
+	ErrRefreshTokenReused     = errors.New("refresh токен уже был использован, сессия завершена")
+	ErrInvalidAccessToken     = errors.New("access токен недействителен")
+	ErrInvalidResetToken      = errors.New("ссылка для сброса пароля недействительна или устарела")
+	ErrUnknownClient          = errors.New("клиент OpenID Connect не найден или отключён")
+	ErrOpenIdUnavailable      = errors.New("OpenID Connect не настроен на сервере")
+
+	ErrInvalidPhone            = errors.New("телефон должен быть в формате +79991234567")
+	ErrContactNotVerified      = errors.New("подтвердите почту или телефон: код отправлен")
+	ErrInvalidVerificationCode = errors.New("код подтверждения неверен или устарел")
+
+	ErrMfaRequired       = errors.New("введите код из приложения-аутентификатора или резервный код")
+	ErrMfaAlreadyEnabled = errors.New("двухфакторная аутентификация уже подключена")
+	ErrMfaNotEnabled     = errors.New("двухфакторная аутентификация не подключена")
+	ErrMfaUnavailable    = errors.New("двухфакторная аутентификация не настроена на сервере")
+	ErrInvalidMfaCode    = errors.New("код двухфакторной аутентификации неверен")
+	ErrInvalidMfaToken   = errors.New("время на ввод кода истекло, войдите заново")
+
+	ErrInvalidPasskey  = errors.New("ключ не прошёл проверку, попробуйте ещё раз")
+	ErrPasskeyNotFound = errors.New("ключ не найден")
+	ErrPasskeyCloned   = errors.New("счётчик ключа не вырос: возможно, ключ скопирован")
+
+	ErrTooManyAttempts = errors.New("слишком много неудачных попыток входа, попробуйте позже")
+	ErrTooManyMfaCodes = errors.New("слишком много неверных кодов: войдите с резервным кодом или обратитесь к администратору")
 )
+
+// LockoutError — вход временно заблокирован после неудачных попыток. errors.Is(err, ErrTooManyAttempts)
+type LockoutError struct {
+	// Через сколько можно попробовать снова
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return ErrTooManyAttempts.Error()
+}
+
+func (e *LockoutError) Unwrap() error {
+	return ErrTooManyAttempts
+}
@@ -0,0 +1,12 @@
+package client
+
+import "errors"
+
+var (
+	ErrClientNotFound      = errors.New("клиент не найден")
+	ErrClientAlreadyExists = errors.New("клиент с таким идентификатором уже существует")
+	ErrUnknownGrantType    = errors.New("неизвестный тип гранта")
+	ErrRedirectUriRequired = errors.New("для authorization_code нужен хотя бы один redirect_uri")
+	ErrInvalidPublicKey    = errors.New("некорректный публичный ключ клиента")
+	ErrInvalidTokenTTL     = errors.New("некорректное время жизни токена")
+)
@@ -4,5 +4,9 @@ import "errors"
 
 
 var (
-	ErrInvalidToken = errors.New("invalid token")
+	ErrInvalidToken     = errors.New("invalid token")
+	ErrInvalidTokenType = errors.New("invalid token type")
+	ErrTokenExpired     = errors.New("token expired")
+	// ID токен проверяет сторонний клиент, поэтому общим секретом HMAC он не подписывается
+	ErrIdTokenUnsupported = errors.New("id token requires an asymmetric signing key")
 )
\ No newline at end of file
@@ -0,0 +1,26 @@
+package oauth
+
+// Error — ошибка OAuth 2.0, Code - код из RFC 6749, который отдаётся клиенту
+type Error struct {
+	Code        string
+	Description string
+}
+
+func (e *Error) Error() string {
+	return e.Description
+}
+
+var (
+	ErrInvalidRequest             = &Error{Code: "invalid_request", Description: "некорректный запрос"}
+	ErrInvalidClient              = &Error{Code: "invalid_client", Description: "неизвестный клиент"}
+	ErrInvalidRedirectUri         = &Error{Code: "invalid_request", Description: "redirect_uri не зарегистрирован для клиента"}
+	ErrInvalidGrant               = &Error{Code: "invalid_grant", Description: "код авторизации или refresh токен недействителен"}
+	ErrUnauthorizedClient         = &Error{Code: "unauthorized_client", Description: "клиенту не разрешён этот тип гранта"}
+	ErrInvalidScope               = &Error{Code: "invalid_scope", Description: "запрошены права, не разрешённые клиенту"}
+	ErrOpenIdUnavailable          = &Error{Code: "invalid_scope", Description: "OpenID Connect не настроен на сервере"}
+	ErrConfidentialClientRequired = &Error{Code: "invalid_client", Description: "требуется аутентификация конфиденциального клиента"}
+	ErrTokenNotOwned              = &Error{Code: "unauthorized_client", Description: "токен выдан другому клиенту"}
+	ErrPKCERequired               = &Error{Code: "invalid_request", Description: "требуется code_challenge с методом S256"}
+	ErrUnsupportedGrantType       = &Error{Code: "unsupported_grant_type", Description: "тип гранта не поддерживается"}
+	ErrUnsupportedResponseType    = &Error{Code: "unsupported_response_type", Description: "поддерживается только response_type=code"}
+)
@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+type item[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// TTLCache — потокобезопасный in-memory кеш, записи в котором живут ttl.
+// Протухшие записи вычищаются при записи, не чаще раза в ttl
+type TTLCache[K comparable, V any] struct {
+	mu        sync.RWMutex
+	ttl       time.Duration
+	items     map[K]item[V]
+	lastSweep time.Time
+}
+
+func New[K comparable, V any](ttl time.Duration) *TTLCache[K, V] {
+	return &TTLCache[K, V]{
+		ttl:       ttl,
+		items:     make(map[K]item[V]),
+		lastSweep: time.Now(),
+	}
+}
+
+func (c *TTLCache[K, V]) Get(key K) (V, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	it, ok := c.items[key]
+	if !ok || time.Now().After(it.expiresAt) {
+		var zero V
+		return zero, false
+	}
+	return it.value, true
+}
+
+func (c *TTLCache[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.lastSweep) > c.ttl {
+		for k, it := range c.items {
+			if now.After(it.expiresAt) {
+				delete(c.items, k)
+			}
+		}
+		c.lastSweep = now
+	}
+	c.items[key] = item[V]{value: value, expiresAt: now.Add(c.ttl)}
+}
+
+func (c *TTLCache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.items, key)
+}
@@ -0,0 +1,44 @@
+package i18n
+
+import (
+	"context"
+
+	"github.com/phenirain/sso/pkg/contextkeys"
+)
+
+// Языки, на которые переведены сообщения API
+const (
+	Ru = "ru"
+	En = "en"
+	Kk = "kk"
+)
+
+// Supported — поддерживаемые языки в порядке предпочтения при равном q в Accept-Language
+var Supported = []string{Ru, En, Kk}
+
+// Fallback — язык, на котором есть все сообщения
+const Fallback = Ru
+
+// Text возвращает сообщение key на языке lang. Если перевода нет - на Fallback, а если нет
+// и его - сам key: ключи сообщений совпадают с кодами ошибок, так что ответ остаётся осмысленным
+func Text(lang, key string) string {
+	translations, ok := catalog[key]
+	if !ok {
+		return key
+	}
+	if text, ok := translations[lang]; ok {
+		return text
+	}
+	if text, ok := translations[Fallback]; ok {
+		return text
+	}
+	return key
+}
+
+// FromContext — язык запроса, выбранный middleware Language
+func FromContext(ctx context.Context) string {
+	if lang, ok := ctx.Value(contextkeys.LanguageCtxKey).(string); ok {
+		return lang
+	}
+	return Fallback
+}
@@ -0,0 +1,219 @@
+package i18n
+
+import "github.com/phenirain/sso/internal/dto/response"
+
+// Ключи сообщений обработчиков: что не удалось сделать и причины ошибок валидации.
+// Детали ошибок сервисов берутся из каталога по коду ошибки (response.Code*)
+const (
+	MsgBadRequest       = "bad_request"
+	MsgUnauthorized     = "unauthorized"
+	MsgForbidden        = "forbidden"
+	MsgNotFound         = "not_found"
+	MsgMethodNotAllowed = "method_not_allowed"
+	MsgConflict         = "conflict"
+	MsgInternal         = "internal"
+
+	MsgInvalidJson         = "invalid_json"
+	MsgMissingArgument     = "missing_argument"
+	MsgInvalidArgument     = "invalid_argument"
+	MsgLoginRequired       = "login_required"
+	MsgPasswordRequired    = "password_required"
+	MsgOldPasswordRequired = "old_password_required"
+	MsgNewPasswordRequired = "new_password_required"
+	MsgMissingToken        = "missing_token"
+	MsgHeaderRequired      = "authorization_header_required"
+	MsgInvalidFormat       = "invalid_token_format"
+	MsgBearerFormat        = "bearer_format"
+
+	MsgAuthFailed           = "auth_failed"
+	MsgRefreshFailed        = "refresh_failed"
+	MsgLogoutFailed         = "logout_failed"
+	MsgLogoutAllFailed      = "logout_all_failed"
+	MsgChangePasswordFailed = "change_password_failed"
+	MsgForgotPasswordFailed = "forgot_password_failed"
+	MsgResetPasswordFailed  = "reset_password_failed"
+	MsgResetTokenRequired   = "reset_token_required"
+
+	MsgResetEmailSubject = "reset_email_subject"
+	MsgResetEmailBody    = "reset_email_body"
+
+	MsgChannelRequired     = "channel_required"
+	MsgCodeRequired        = "code_required"
+	MsgVerifyContactFailed = "verify_contact_failed"
+	MsgResendCodeFailed    = "resend_code_failed"
+	MsgVerifyEmailSubject  = "verify_email_subject"
+	MsgVerifyEmailBody     = "verify_email_body"
+	MsgVerifySmsBody       = "verify_sms_body"
+
+	MsgMfaTokenRequired    = "mfa_token_required"
+	MsgMfaEnrollFailed     = "mfa_enroll_failed"
+	MsgMfaConfirmFailed    = "mfa_confirm_failed"
+	MsgMfaDisableFailed    = "mfa_disable_failed"
+	MsgMfaVerifyFailed     = "mfa_verify_failed"
+	MsgRecoveryCodesFailed = "recovery_codes_failed"
+
+	MsgCredentialRequired    = "credential_required"
+	MsgPasskeyOptionsFailed  = "passkey_options_failed"
+	MsgPasskeyRegisterFailed = "passkey_register_failed"
+	MsgPasskeyLoginFailed    = "passkey_login_failed"
+	MsgGetPasskeysFailed     = "get_passkeys_failed"
+	MsgDeletePasskeyFailed   = "delete_passkey_failed"
+
+	MsgLoginFormExpired = "login_form_expired"
+
+	// Страницы OAuth: форма входа и ошибка авторизации
+	MsgLoginPageTitle      = "login_page_title"
+	MsgLoginPageHeading    = "login_page_heading"
+	MsgLoginLabel          = "login_label"
+	MsgPasswordLabel       = "password_label"
+	MsgOtpLabel            = "otp_label"
+	MsgLoginButton         = "login_button"
+	MsgPasskeyButton       = "passkey_button"
+	MsgAuthorizeErrorTitle = "authorize_error_title"
+
+	MsgLoginOrIpRequired = "login_or_ip_required"
+	MsgUnlockFailed      = "unlock_failed"
+
+	MsgClientNameRequired = "client_name_required"
+	MsgGetClientsFailed   = "get_clients_failed"
+	MsgCreateClientFailed = "create_client_failed"
+	MsgUpdateClientFailed = "update_client_failed"
+	MsgRotateSecretFailed = "rotate_secret_failed"
+	MsgClientStatusFailed = "client_status_failed"
+)
+
+var catalog = map[string]map[string]string{
+	MsgBadRequest:       {Ru: "Некорректный запрос", En: "Bad request", Kk: "Қате сұрау"},
+	MsgUnauthorized:     {Ru: "Требуется авторизация", En: "Authorization required", Kk: "Авторизация қажет"},
+	MsgForbidden:        {Ru: "Недостаточно прав", En: "Insufficient permissions", Kk: "Құқықтар жеткіліксіз"},
+	MsgNotFound:         {Ru: "Не найдено", En: "Not found", Kk: "Табылмады"},
+	MsgMethodNotAllowed: {Ru: "Метод не поддерживается", En: "Method not allowed", Kk: "Әдіс қолдау көрсетілмейді"},
+	MsgConflict:         {Ru: "Конфликт", En: "Conflict", Kk: "Қайшылық"},
+	MsgInternal:         {Ru: "Внутренняя ошибка сервера", En: "Internal server error", Kk: "Сервердің ішкі қатесі"},
+
+	MsgInvalidJson:         {Ru: "Ошибка чтения json", En: "Failed to read json", Kk: "json оқу қатесі"},
+	MsgMissingArgument:     {Ru: "Отсутствует аргумент", En: "Missing argument", Kk: "Аргумент жоқ"},
+	MsgInvalidArgument:     {Ru: "Некорректный аргумент", En: "Invalid argument", Kk: "Аргумент қате"},
+	MsgLoginRequired:       {Ru: "Логин обязателен", En: "Login is required", Kk: "Логин міндетті"},
+	MsgPasswordRequired:    {Ru: "Пароль обязателен", En: "Password is required", Kk: "Құпиясөз міндетті"},
+	MsgOldPasswordRequired: {Ru: "Текущий пароль обязателен", En: "Current password is required", Kk: "Ағымдағы құпиясөз міндетті"},
+	MsgNewPasswordRequired: {Ru: "Новый пароль обязателен", En: "New password is required", Kk: "Жаңа құпиясөз міндетті"},
+	MsgMissingToken:        {Ru: "Отсутствует токен", En: "Missing token", Kk: "Токен жоқ"},
+	MsgHeaderRequired:      {Ru: "Заголовок Authorization обязателен", En: "Authorization header is required", Kk: "Authorization тақырыбы міндетті"},
+	MsgInvalidFormat:       {Ru: "Неверный формат токена", En: "Invalid token format", Kk: "Токен пішімі қате"},
+	MsgBearerFormat:        {Ru: "Используйте формат: Bearer <token>", En: "Use format: Bearer <token>", Kk: "Пішімді қолданыңыз: Bearer <token>"},
+
+	MsgAuthFailed:           {Ru: "Ошибка авторизации", En: "Authorization failed", Kk: "Авторизация қатесі"},
+	MsgRefreshFailed:        {Ru: "Ошибка обновления токена", En: "Failed to refresh token", Kk: "Токенді жаңарту қатесі"},
+	MsgLogoutFailed:         {Ru: "Ошибка завершения сессии", En: "Failed to end session", Kk: "Сессияны аяқтау қатесі"},
+	MsgForgotPasswordFailed: {Ru: "Ошибка отправки ссылки для сброса пароля", En: "Failed to send password reset link", Kk: "Құпиясөзді қалпына келтіру сілтемесін жіберу қатесі"},
+	MsgResetPasswordFailed:  {Ru: "Ошибка сброса пароля", En: "Failed to reset password", Kk: "Құпиясөзді қалпына келтіру қатесі"},
+	MsgResetTokenRequired:   {Ru: "Токен сброса пароля обязателен", En: "Reset token is required", Kk: "Қалпына келтіру токені міндетті"},
+	MsgResetEmailSubject:    {Ru: "Сброс пароля", En: "Password reset", Kk: "Құпиясөзді қалпына келтіру"},
+	// %[1]s - ссылка, %[2]d - сколько минут она действует
+	MsgResetEmailBody: {
+		Ru: "Чтобы задать новый пароль, перейдите по ссылке:\n%[1]s\n\nСсылка действует %[2]d мин. Если вы не запрашивали сброс пароля, просто проигнорируйте это письмо.",
+		En: "To set a new password, follow the link:\n%[1]s\n\nThe link is valid for %[2]d min. If you did not request a password reset, just ignore this email.",
+		Kk: "Жаңа құпиясөз орнату үшін сілтемеге өтіңіз:\n%[1]s\n\nСілтеме %[2]d мин жарамды. Егер сіз құпиясөзді қалпына келтіруді сұрамасаңыз, бұл хатты елемеңіз.",
+	},
+	MsgChannelRequired:     {Ru: "Канал должен быть email или phone", En: "Channel must be email or phone", Kk: "Арна email немесе phone болуы керек"},
+	MsgCodeRequired:        {Ru: "Код подтверждения обязателен", En: "Verification code is required", Kk: "Растау коды міндетті"},
+	MsgVerifyContactFailed: {Ru: "Ошибка подтверждения контакта", En: "Failed to verify contact", Kk: "Байланысты растау қатесі"},
+	MsgResendCodeFailed:    {Ru: "Ошибка отправки кода подтверждения", En: "Failed to send verification code", Kk: "Растау кодын жіберу қатесі"},
+	MsgVerifyEmailSubject:  {Ru: "Подтверждение почты", En: "Email verification", Kk: "Поштаны растау"},
+	// %[1]s - ссылка, %[2]d - сколько часов она действует
+	MsgVerifyEmailBody: {
+		Ru: "Чтобы подтвердить почту, перейдите по ссылке:\n%[1]s\n\nСсылка действует %[2]d ч. Если вы не регистрировались, просто проигнорируйте это письмо.",
+		En: "To verify your email, follow the link:\n%[1]s\n\nThe link is valid for %[2]d h. If you did not sign up, just ignore this email.",
+		Kk: "Поштаны растау үшін сілтемеге өтіңіз:\n%[1]s\n\nСілтеме %[2]d сағ жарамды. Егер сіз тіркелмесеңіз, бұл хатты елемеңіз.",
+	},
+	// %[1]s - код, %[2]d - сколько минут он действует
+	MsgVerifySmsBody: {
+		Ru: "Код подтверждения: %[1]s. Действует %[2]d мин.",
+		En: "Verification code: %[1]s. Valid for %[2]d min.",
+		Kk: "Растау коды: %[1]s. %[2]d мин жарамды.",
+	},
+
+	MsgMfaTokenRequired:    {Ru: "Токен mfa_token обязателен", En: "mfa_token is required", Kk: "mfa_token токені міндетті"},
+	MsgMfaEnrollFailed:     {Ru: "Ошибка подключения двухфакторной аутентификации", En: "Failed to enroll two-factor authentication", Kk: "Екі факторлы аутентификацияны қосу қатесі"},
+	MsgMfaConfirmFailed:    {Ru: "Ошибка подтверждения двухфакторной аутентификации", En: "Failed to confirm two-factor authentication", Kk: "Екі факторлы аутентификацияны растау қатесі"},
+	MsgMfaDisableFailed:    {Ru: "Ошибка отключения двухфакторной аутентификации", En: "Failed to disable two-factor authentication", Kk: "Екі факторлы аутентификацияны өшіру қатесі"},
+	MsgMfaVerifyFailed:     {Ru: "Ошибка проверки кода двухфакторной аутентификации", En: "Failed to verify two-factor code", Kk: "Екі факторлы аутентификация кодын тексеру қатесі"},
+	MsgRecoveryCodesFailed: {Ru: "Ошибка выпуска резервных кодов", En: "Failed to generate recovery codes", Kk: "Резервтік кодтарды шығару қатесі"},
+
+	MsgCredentialRequired:    {Ru: "Ответ ключа credential обязателен", En: "credential is required", Kk: "credential кілт жауабы міндетті"},
+	MsgPasskeyOptionsFailed:  {Ru: "Ошибка подготовки входа по ключу", En: "Failed to prepare passkey ceremony", Kk: "Кілтпен кіруді дайындау қатесі"},
+	MsgPasskeyRegisterFailed: {Ru: "Ошибка добавления ключа", En: "Failed to register passkey", Kk: "Кілтті қосу қатесі"},
+	MsgPasskeyLoginFailed:    {Ru: "Ошибка входа по ключу", En: "Failed to log in with passkey", Kk: "Кілтпен кіру қатесі"},
+	MsgGetPasskeysFailed:     {Ru: "Ошибка получения ключей", En: "Failed to get passkeys", Kk: "Кілттерді алу қатесі"},
+	MsgDeletePasskeyFailed:   {Ru: "Ошибка удаления ключа", En: "Failed to delete passkey", Kk: "Кілтті жою қатесі"},
+
+	MsgLoginFormExpired: {Ru: "Форма входа устарела, попробуйте ещё раз", En: "The login form has expired, please try again", Kk: "Кіру формасының мерзімі өтті, қайталап көріңіз"},
+
+	MsgLoginPageTitle: {Ru: "Вход", En: "Sign in", Kk: "Кіру"},
+	// %[1]s - название клиента
+	MsgLoginPageHeading:    {Ru: "Вход в %[1]s", En: "Sign in to %[1]s", Kk: "%[1]s жүйесіне кіру"},
+	MsgLoginLabel:          {Ru: "Логин", En: "Login", Kk: "Логин"},
+	MsgPasswordLabel:       {Ru: "Пароль", En: "Password", Kk: "Құпиясөз"},
+	MsgOtpLabel:            {Ru: "Код из приложения-аутентификатора или резервный код", En: "Code from the authenticator app or a recovery code", Kk: "Аутентификатор қолданбасындағы код немесе резервтік код"},
+	MsgLoginButton:         {Ru: "Войти", En: "Sign in", Kk: "Кіру"},
+	MsgPasskeyButton:       {Ru: "Подтвердить ключом", En: "Confirm with passkey", Kk: "Кілтпен растау"},
+	MsgAuthorizeErrorTitle: {Ru: "Ошибка авторизации", En: "Authorization error", Kk: "Авторизация қатесі"},
+
+	MsgLoginOrIpRequired: {Ru: "Укажите логин или IP адрес", En: "Login or IP address is required", Kk: "Логинді немесе IP мекенжайын көрсетіңіз"},
+	MsgUnlockFailed:      {Ru: "Ошибка снятия блокировки входа", En: "Failed to unlock login", Kk: "Кіру бұғатын алу қатесі"},
+
+	MsgChangePasswordFailed: {Ru: "Ошибка смены пароля", En: "Failed to change password", Kk: "Құпиясөзді өзгерту қатесі"},
+	MsgLogoutAllFailed:      {Ru: "Ошибка завершения сессий", En: "Failed to end sessions", Kk: "Сессияларды аяқтау қатесі"},
+
+	MsgClientNameRequired: {Ru: "Название клиента обязательно", En: "Client name is required", Kk: "Клиент атауы міндетті"},
+	MsgGetClientsFailed:   {Ru: "Ошибка получения клиентов", En: "Failed to get clients", Kk: "Клиенттерді алу қатесі"},
+	MsgCreateClientFailed: {Ru: "Ошибка создания клиента", En: "Failed to create client", Kk: "Клиентті құру қатесі"},
+	MsgUpdateClientFailed: {Ru: "Ошибка изменения клиента", En: "Failed to update client", Kk: "Клиентті өзгерту қатесі"},
+	MsgRotateSecretFailed: {Ru: "Ошибка перевыпуска секрета", En: "Failed to rotate secret", Kk: "Құпияны қайта шығару қатесі"},
+	MsgClientStatusFailed: {Ru: "Ошибка изменения статуса клиента", En: "Failed to change client status", Kk: "Клиент күйін өзгерту қатесі"},
+
+	// детали ошибок echo и middleware, у которых есть только HTTP статус
+	response.CodeInvalidRequest:   {Ru: "Запрос не удалось разобрать", En: "The request could not be parsed", Kk: "Сұрауды талдау мүмкін болмады"},
+	response.CodeUnauthorized:     {Ru: "Нужен действующий токен доступа", En: "A valid access token is required", Kk: "Жарамды қол жеткізу токені қажет"},
+	response.CodeForbidden:        {Ru: "Доступ к ресурсу запрещён", En: "Access to the resource is denied", Kk: "Ресурсқа қол жеткізуге тыйым салынған"},
+	response.CodeNotFound:         {Ru: "Ресурс не найден", En: "Resource not found", Kk: "Ресурс табылмады"},
+	response.CodeMethodNotAllowed: {Ru: "Метод не поддерживается для этого адреса", En: "Method is not supported for this path", Kk: "Бұл мекенжай үшін әдіс қолдау көрсетілмейді"},
+
+	response.CodeInvalidCredentials:  {Ru: "Неверен логин или пароль", En: "Invalid login or password", Kk: "Логин немесе құпиясөз қате"},
+	response.CodeInvalidOldPassword:  {Ru: "Старый пароль не совпадает с текущим", En: "Old password does not match the current one", Kk: "Ескі құпиясөз ағымдағымен сәйкес келмейді"},
+	response.CodePasswordTooLong:     {Ru: "Пароль не может быть длиннее 72 байт", En: "Password cannot be longer than 72 bytes", Kk: "Құпиясөз 72 байттан ұзын болмауы керек"},
+	response.CodeUserExists:          {Ru: "Пользователь уже существует", En: "User already exists", Kk: "Пайдаланушы бар"},
+	response.CodeUserNotFound:        {Ru: "Пользователь не существует", En: "User does not exist", Kk: "Пайдаланушы жоқ"},
+	response.CodeInvalidToken:        {Ru: "Токен недействителен", En: "Invalid token", Kk: "Токен жарамсыз"},
+	response.CodeInvalidTokenType:    {Ru: "Неверный тип токена", En: "Invalid token type", Kk: "Токен түрі қате"},
+	response.CodeTokenExpired:        {Ru: "Срок действия токена истёк", En: "Token expired", Kk: "Токеннің мерзімі өтті"},
+	response.CodeTokenRevoked:        {Ru: "Токен отозван", En: "Token revoked", Kk: "Токен кері қайтарылды"},
+	response.CodeInvalidRefreshToken: {Ru: "Refresh токен недействителен", En: "Invalid refresh token", Kk: "Refresh токен жарамсыз"},
+	response.CodeInvalidResetToken:   {Ru: "Ссылка для сброса пароля недействительна или устарела", En: "Password reset link is invalid or expired", Kk: "Құпиясөзді қалпына келтіру сілтемесі жарамсыз немесе ескірген"},
+	response.CodeUnknownClient:       {Ru: "Клиент OpenID Connect не найден или отключён", En: "OpenID Connect client not found or disabled", Kk: "OpenID Connect клиенті табылмады немесе өшірілген"},
+	response.CodeOpenIdUnavailable:   {Ru: "OpenID Connect не настроен на сервере", En: "OpenID Connect is not configured on the server", Kk: "OpenID Connect серверде бапталмаған"},
+	response.CodeRefreshTokenReused:  {Ru: "Refresh токен уже был использован, сессия завершена", En: "Refresh token was already used, session terminated", Kk: "Refresh токен бұрын қолданылған, сессия аяқталды"},
+
+	response.CodeInvalidPhone:            {Ru: "Телефон должен быть в формате +79991234567", En: "Phone must be in format +79991234567", Kk: "Телефон +79991234567 пішімінде болуы керек"},
+	response.CodeContactNotVerified:      {Ru: "Подтвердите почту или телефон: код отправлен", En: "Verify your email or phone: the code has been sent", Kk: "Поштаны немесе телефонды растаңыз: код жіберілді"},
+	response.CodeInvalidVerificationCode: {Ru: "Код подтверждения неверен или устарел", En: "Verification code is invalid or expired", Kk: "Растау коды қате немесе ескірген"},
+
+	response.CodeMfaRequired:       {Ru: "Введите код из приложения-аутентификатора или резервный код", En: "Enter the code from your authenticator app or a recovery code", Kk: "Аутентификатор қолданбасындағы кодты немесе резервтік кодты енгізіңіз"},
+	response.CodeMfaAlreadyEnabled: {Ru: "Двухфакторная аутентификация уже подключена", En: "Two-factor authentication is already enabled", Kk: "Екі факторлы аутентификация қосылған"},
+	response.CodeMfaNotEnabled:     {Ru: "Двухфакторная аутентификация не подключена", En: "Two-factor authentication is not enabled", Kk: "Екі факторлы аутентификация қосылмаған"},
+	response.CodeMfaUnavailable:    {Ru: "Двухфакторная аутентификация не настроена на сервере", En: "Two-factor authentication is not configured on the server", Kk: "Екі факторлы аутентификация серверде бапталмаған"},
+	response.CodeInvalidMfaCode:    {Ru: "Код двухфакторной аутентификации неверен", En: "Two-factor code is invalid", Kk: "Екі факторлы аутентификация коды қате"},
+	response.CodeInvalidMfaToken:   {Ru: "Время на ввод кода истекло, войдите заново", En: "Time to enter the code has expired, please log in again", Kk: "Кодты енгізу уақыты өтті, қайта кіріңіз"},
+
+	response.CodeInvalidPasskey:  {Ru: "Ключ не прошёл проверку, попробуйте ещё раз", En: "Passkey verification failed, please try again", Kk: "Кілт тексеруден өтпеді, қайталап көріңіз"},
+	response.CodePasskeyNotFound: {Ru: "Ключ не найден", En: "Passkey not found", Kk: "Кілт табылмады"},
+	response.CodePasskeyCloned:   {Ru: "Счётчик ключа не вырос: возможно, ключ скопирован", En: "Passkey counter did not increase: the key may have been cloned", Kk: "Кілт есептегіші өспеді: кілт көшірілген болуы мүмкін"},
+
+	response.CodeTooManyAttempts: {Ru: "Слишком много неудачных попыток входа, попробуйте позже", En: "Too many failed login attempts, please try again later", Kk: "Сәтсіз кіру әрекеттері тым көп, кейінірек қайталаңыз"},
+	response.CodeTooManyMfaCodes: {Ru: "Слишком много неверных кодов: войдите с резервным кодом или обратитесь к администратору", En: "Too many invalid codes: log in with a recovery code or contact the administrator", Kk: "Қате кодтар тым көп: резервтік кодпен кіріңіз немесе әкімшіге хабарласыңыз"},
+
+	response.CodeClientNotFound:        {Ru: "Клиент не найден", En: "Client not found", Kk: "Клиент табылмады"},
+	response.CodeClientExists:          {Ru: "Клиент с таким идентификатором уже существует", En: "Client with this id already exists", Kk: "Мұндай идентификаторы бар клиент бар"},
+	response.CodeInvalidClientSettings: {Ru: "Некорректные настройки клиента", En: "Invalid client settings", Kk: "Клиент баптаулары қате"},
+}
@@ -0,0 +1,149 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/phenirain/sso/internal/domain"
+	tokenClaims "github.com/phenirain/sso/pkg/claims"
+)
+
+// ClientAssertionType — client_assertion_type для аутентификации private_key_jwt (RFC 7523)
+const ClientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// maxClientAssertionLifetime — assertion дольше этого не принимаем: столько помним его jti
+const maxClientAssertionLifetime = time.Minute * 5
+
+// ClientAssertionAlgorithms — алгоритмы подписи client_assertion
+var ClientAssertionAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// ClientAssertion — проверенный client_assertion
+type ClientAssertion struct {
+	ClientId  string
+	TokenId   string
+	ExpiresAt time.Time
+}
+
+// NewClientToken выпускает access токен сервиса (client_credentials):
+// sub - сам клиент, пользователя и роли в токене нет, refresh токен не выдаётся
+func (j *JwtLib) NewClientToken(params AccessTokenParams) (string, error) {
+	ttl := params.TTL
+	if ttl == 0 {
+		ttl = j.duration
+	}
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":       j.issuer,
+		"sub":       params.ClientId,
+		"client_id": params.ClientId,
+		"iat":       now.Unix(),
+		"token_use": tokenClaims.TokenTypeAccess,
+		"jti":       domain.NewTokenId(),
+		"exp":       now.Add(ttl).Unix(),
+	}
+	if params.Scope != "" {
+		claims["scope"] = params.Scope
+	}
+	if params.Audience != "" {
+		claims["aud"] = params.Audience
+	}
+	return j.sign(claims)
+}
+
+// ParseClientAssertion проверяет client_assertion: iss и sub - client_id, aud - мы,
+// подпись - ключом клиента. publicKey по client_id возвращает PEM публичного ключа клиента
+func (j *JwtLib) ParseClientAssertion(assertion string, publicKey func(clientId string) (string, error)) (*ClientAssertion, error) {
+	token, err := jwt.Parse(assertion, func(token *jwt.Token) (interface{}, error) {
+		mapClaims := token.Claims.(jwt.MapClaims)
+		iss, _ := mapClaims["iss"].(string)
+		sub, _ := mapClaims["sub"].(string)
+		if sub == "" || iss != sub {
+			return nil, errors.New("iss and sub must be client_id")
+		}
+
+		pemData, err := publicKey(sub)
+		if err != nil {
+			return nil, err
+		}
+		key, err := ParsePublicKey([]byte(pemData))
+		if err != nil {
+			return nil, err
+		}
+		if !keyAllowsMethod(key, token.Method) {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key, nil
+	}, jwt.WithExpirationRequired(), jwt.WithValidMethods(ClientAssertionAlgorithms))
+	if err != nil {
+		return nil, fmt.Errorf("client assertion parse error: %s", err.Error())
+	}
+
+	mapClaims := token.Claims.(jwt.MapClaims)
+	audience, _ := mapClaims.GetAudience()
+	issuer := strings.TrimSuffix(j.issuer, "/")
+	if !slices.Contains(audience, issuer) && !slices.Contains(audience, issuer+"/oauth/token") {
+		return nil, errors.New("client assertion audience mismatch")
+	}
+	exp, _ := mapClaims.GetExpirationTime()
+	if time.Until(exp.Time) > maxClientAssertionLifetime {
+		return nil, errors.New("client assertion lifetime is too long")
+	}
+	jti, _ := mapClaims["jti"].(string)
+	if jti == "" {
+		return nil, errors.New("client assertion jti is required")
+	}
+	sub, _ := mapClaims["sub"].(string)
+
+	return &ClientAssertion{
+		ClientId:  sub,
+		TokenId:   jti,
+		ExpiresAt: exp.Time,
+	}, nil
+}
+
+// ParsePublicKey читает публичный ключ клиента из PEM (PKIX): RSA, ECDSA или Ed25519
+func ParsePublicKey(pemData []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("public key is not PEM encoded")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+// keyAllowsMethod не даёт подменить алгоритм: ключ RSA - только RS/PS, ECDSA - ES своей кривой
+func keyAllowsMethod(key crypto.PublicKey, method jwt.SigningMethod) bool {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		switch method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+			return true
+		}
+	case *ecdsa.PublicKey:
+		if m, ok := method.(*jwt.SigningMethodECDSA); ok {
+			return k.Curve.Params().BitSize == m.CurveBits
+		}
+	case ed25519.PublicKey:
+		_, ok := method.(*jwt.SigningMethodEd25519)
+		return ok
+	}
+	return false
+}
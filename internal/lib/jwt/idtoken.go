@@ -0,0 +1,85 @@
+package jwt
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/phenirain/sso/internal/domain"
+	jwtErrors "github.com/phenirain/sso/internal/errors/jwt"
+)
+
+// IdTokenParams — данные запроса аутентификации, которые попадают в ID токен
+type IdTokenParams struct {
+	// Клиент, для которого выпускается токен (aud)
+	Audience string
+	// nonce из запроса клиента, если был
+	Nonce string
+	// Момент, когда пользователь ввёл учётные данные
+	AuthTime time.Time
+}
+
+// NewIdToken выпускает ID токен OpenID Connect. В отличие от access токена
+// sub здесь строка, как требует спецификация
+func (j *JwtLib) NewIdToken(user *domain.User, params IdTokenParams) (string, error) {
+	if !j.SupportsIdTokens() {
+		return "", jwtErrors.ErrIdTokenUnsupported
+	}
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":                j.issuer,
+		"sub":                strconv.FormatInt(user.Id, 10),
+		"aud":                params.Audience,
+		"iat":                now.Unix(),
+		"exp":                now.Add(j.duration).Unix(),
+		"auth_time":          params.AuthTime.Unix(),
+		"preferred_username": user.Login,
+		"role_id":            user.RoleId,
+	}
+	if params.Nonce != "" {
+		claims["nonce"] = params.Nonce
+	}
+	contactClaims(claims, user)
+	return j.sign(claims)
+}
+
+// contactClaims добавляет стандартные claims OpenID Connect о почте и телефоне, если они указаны
+func contactClaims(claims jwt.MapClaims, user *domain.User) {
+	if user.Email != "" {
+		claims["email"] = user.Email
+		claims["email_verified"] = user.EmailVerified
+	}
+	if user.Phone != "" {
+		claims["phone_number"] = user.Phone
+		claims["phone_number_verified"] = user.PhoneVerified
+	}
+}
+
+// Issuer — идентификатор провайдера (iss)
+func (j *JwtLib) Issuer() string {
+	return j.issuer
+}
+
+// SupportsIdTokens — текущий ключ асимметричный: клиенты проверят ID токен по JWKS.
+// С ключом HMAC OpenID Connect выключен
+func (j *JwtLib) SupportsIdTokens() bool {
+	return j.keys.Current().isAsymmetric()
+}
+
+// SigningAlgorithms — алгоритмы, которыми подписаны живые ID токены: только асимметричные,
+// ключи HMAC в JWKS не публикуются
+func (j *JwtLib) SigningAlgorithms() []string {
+	seen := make(map[string]struct{})
+	algorithms := []string{}
+	for _, key := range j.keys.Verifying() {
+		if !key.isAsymmetric() {
+			continue
+		}
+		if _, ok := seen[key.Algorithm()]; ok {
+			continue
+		}
+		seen[key.Algorithm()] = struct{}{}
+		algorithms = append(algorithms, key.Algorithm())
+	}
+	return algorithms
+}
@@ -4,61 +4,162 @@ import (
 	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
+	"math"
+	"github.com/phenirain/sso/internal/domain"
 	jwtErrors "github.com/phenirain/sso/internal/errors/jwt"
+	tokenClaims "github.com/phenirain/sso/pkg/claims"
 	"time"
 )
 
 type JwtLib struct {
 	duration time.Duration
-	secret []byte
+	keys *KeySet
+	issuer string
 }
 
-func NewJwtLib(duration time.Duration, secret []byte) *JwtLib {
+func NewJwtLib(duration time.Duration, keys *KeySet, issuer string) *JwtLib {
 	return &JwtLib{
 		duration: duration,
-		secret: secret,
+		keys: keys,
+		issuer: issuer,
 	}
 }
 
-//TODO: класть роль
-func (j *JwtLib) NewToken(userId int64) (accessToken string, refreshToken string, error error) {
+// AccessTokenParams — настройки access токена клиента OAuth
+type AccessTokenParams struct {
+	// Время жизни, 0 - по умолчанию
+	TTL      time.Duration
+	ClientId string
+	Audience string
+	Scope    string
+}
+
+// NewToken выпускает пару токенов. jti и срок жизни refresh токена берутся из session
+func (j *JwtLib) NewToken(user *domain.User, session *domain.RefreshToken, params AccessTokenParams) (accessToken string, refreshToken string, error error) {
 	claims := jwt.MapClaims{
-		"sub":  userId,
+		"iss":     j.issuer,
+		"sub":     user.Id,
+		"role_id": user.RoleId,
+		"sid": session.FamilyId,
+	}
+	if params.ClientId != "" {
+		claims["client_id"] = params.ClientId
+	}
+	if params.Scope != "" {
+		claims["scope"] = params.Scope
+	}
+	contactClaims(claims, user)
+	ttl := params.TTL
+	if ttl == 0 {
+		ttl = j.duration
+	}
+	now := time.Now()
+	// iat с микросекундами: отзыв по tokens_not_before отделяет токены, выпущенные в ту же секунду
+	claims["iat"] = issuedAt(now)
+	claims["token_use"] = tokenClaims.TokenTypeAccess
+	claims["jti"] = domain.NewTokenId()
+	claims["exp"] = now.Add(ttl).Unix()
+	// aud только у access токена: refresh токен предъявляется только нам
+	if params.Audience != "" {
+		claims["aud"] = params.Audience
 	}
-	claims["exp"] = time.Now().Add(j.duration).Unix()
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	accessToken, err := token.SignedString(j.secret)
+	accessToken, err := j.sign(claims)
 	if err != nil {
 		return "", "", err
 	}
 
-	claims["exp"] = time.Now().Add(time.Hour*24*30).Unix()
-	token = jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	refreshToken, err = token.SignedString(j.secret)
+	delete(claims, "aud")
+	claims["token_use"] = tokenClaims.TokenTypeRefresh
+	claims["jti"] = session.Id
+	claims["exp"] = session.ExpiresAt.Unix()
+	refreshToken, err = j.sign(claims)
 	if err != nil {
 		return "", "", err
 	}
 	return
 }
 
-//TODO доставать роль
-func (j *JwtLib) ParseToken(tokenString string) (int64, error) {
+// issuedAt — iat с точностью до микросекунды. NumericDate может быть дробным (RFC 7519)
+func issuedAt(t time.Time) float64 {
+	return float64(t.UnixMicro()) / 1e6
+}
+
+// sign подписывает claims текущим ключом
+func (j *JwtLib) sign(claims jwt.MapClaims) (string, error) {
+	key := j.keys.Current()
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	return token.SignedString(key.sign)
+}
+
+// ParseToken проверяет подпись и срок токена, а также что он выпущен как tokenType
+func (j *JwtLib) ParseToken(tokenString string, tokenType tokenClaims.TokenType) (*tokenClaims.Claims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		// токены, выпущенные до появления kid, подписаны текущим ключом
+		key := j.keys.Current()
+		if kid, ok := token.Header["kid"].(string); ok {
+			if key, ok = j.keys.Get(kid); !ok {
+				return nil, fmt.Errorf("unknown signing key: %s", kid)
+			}
+		}
+		if token.Method.Alg() != key.Algorithm() {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return j.secret, nil
+		return key.verify, nil
 	})
 	if err != nil {
-		return -1, fmt.Errorf("token parse error: %s", err.Error())
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, jwtErrors.ErrTokenExpired
+		}
+		return nil, fmt.Errorf("%w: %s", jwtErrors.ErrInvalidToken, err.Error())
 	}
 	if !token.Valid {
-		return -1, jwtErrors.ErrInvalidToken
+		return nil, jwtErrors.ErrInvalidToken
 	}
-	uid, ok := token.Claims.(jwt.MapClaims)["sub"]
-	if !ok {
-		return -1, errors.New("can't get sub from claims")
+	mapClaims := token.Claims.(jwt.MapClaims)
+	// iss есть не во всех старых токенах, но если есть - должен быть наш
+	if iss, ok := mapClaims["iss"]; ok && iss != j.issuer {
+		return nil, jwtErrors.ErrInvalidToken
+	}
+	if use, _ := mapClaims["token_use"].(string); tokenClaims.TokenType(use) != tokenType {
+		return nil, jwtErrors.ErrInvalidTokenType
+	}
+	clientId, _ := mapClaims["client_id"].(string)
+	var uid, roleId float64
+	switch sub := mapClaims["sub"].(type) {
+	case float64:
+		uid = sub
+		var ok bool
+		if roleId, ok = mapClaims["role_id"].(float64); !ok {
+			return nil, fmt.Errorf("%w: can't get role_id from claims", jwtErrors.ErrInvalidToken)
+		}
+	case string:
+		// токен сервиса (client_credentials): sub - сам клиент
+		if sub == "" || sub != clientId {
+			return nil, fmt.Errorf("%w: can't get sub from claims", jwtErrors.ErrInvalidToken)
+		}
+	default:
+		return nil, fmt.Errorf("%w: can't get sub from claims", jwtErrors.ErrInvalidToken)
 	}
-	return int64(uid.(float64)), nil
+	jti, _ := mapClaims["jti"].(string)
+	sid, _ := mapClaims["sid"].(string)
+	audience, _ := mapClaims["aud"].(string)
+	scope, _ := mapClaims["scope"].(string)
+	nonce, _ := mapClaims["nonce"].(string)
+	iat, _ := mapClaims["iat"].(float64)
+	exp, _ := mapClaims["exp"].(float64)
+	return &tokenClaims.Claims{
+		UserId:    int64(uid),
+		RoleId:    int64(roleId),
+		TokenType: tokenType,
+		TokenId:   jti,
+		SessionId: sid,
+		IssuedAt:  time.UnixMicro(int64(math.Round(iat * 1e6))),
+		ExpiresAt: time.Unix(int64(exp), 0),
+		ClientId:  clientId,
+		Audience:  audience,
+		Scope:     scope,
+		Nonce:     nonce,
+	}, nil
 }
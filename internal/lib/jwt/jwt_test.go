@@ -0,0 +1,196 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/phenirain/sso/internal/domain"
+	jwtErrors "github.com/phenirain/sso/internal/errors/jwt"
+	tokenClaims "github.com/phenirain/sso/pkg/claims"
+)
+
+const testIssuer = "http://sso"
+
+func newTestLib(t *testing.T, keys ...*Key) *JwtLib {
+	t.Helper()
+	current, _, err := GenerateKey("current", "ES256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	set, err := NewKeySet(current.id, append([]*Key{current}, keys...), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewJwtLib(time.Minute, set, testIssuer)
+}
+
+// userClaims — claims access токена пользователя, tweak меняет их под случай
+func userClaims(tweak func(jwt.MapClaims)) jwt.MapClaims {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":       testIssuer,
+		"sub":       1,
+		"role_id":   1,
+		"jti":       "jti",
+		"token_use": tokenClaims.TokenTypeAccess,
+		"iat":       now.Unix(),
+		"exp":       now.Add(time.Minute).Unix(),
+	}
+	if tweak != nil {
+		tweak(claims)
+	}
+	return claims
+}
+
+func TestParseTokenTypeAndLifetime(t *testing.T) {
+	j := newTestLib(t)
+	tests := []struct {
+		name      string
+		claims    jwt.MapClaims
+		tokenType tokenClaims.TokenType
+		wantErr   error
+	}{
+		{"access as access", userClaims(nil), tokenClaims.TokenTypeAccess, nil},
+		{"access as refresh", userClaims(nil), tokenClaims.TokenTypeRefresh, jwtErrors.ErrInvalidTokenType},
+		{"refresh as access", userClaims(func(c jwt.MapClaims) { c["token_use"] = tokenClaims.TokenTypeRefresh }), tokenClaims.TokenTypeAccess, jwtErrors.ErrInvalidTokenType},
+		{"without token_use", userClaims(func(c jwt.MapClaims) { delete(c, "token_use") }), tokenClaims.TokenTypeAccess, jwtErrors.ErrInvalidTokenType},
+		{"expired", userClaims(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }), tokenClaims.TokenTypeAccess, jwtErrors.ErrTokenExpired},
+		{"foreign issuer", userClaims(func(c jwt.MapClaims) { c["iss"] = "http://other" }), tokenClaims.TokenTypeAccess, jwtErrors.ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := j.sign(tt.claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+			claims, err := j.ParseToken(token, tt.tokenType)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("ParseToken: %v", err)
+				}
+				if claims.UserId != 1 || claims.TokenType != tt.tokenType {
+					t.Errorf("claims = %+v", claims)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ParseToken: got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// signWith подписывает claims методом method и ключом signKey, в заголовок кладёт kid, если он не пустой
+func signWith(t *testing.T, method jwt.SigningMethod, signKey any, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(signKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestParseTokenSigningKey(t *testing.T) {
+	retired := func(id string, ago time.Duration) *Key {
+		key, _, err := GenerateKey(id, "ES256")
+		if err != nil {
+			t.Fatal(err)
+		}
+		key.Retire(time.Now().Add(-ago))
+		return key
+	}
+	recent, expired := retired("recent", time.Minute*10), retired("expired", time.Hour*2)
+	secret := NewHMACKey("hmac", []byte("0123456789abcdef0123456789abcdef"))
+	stranger, _, err := GenerateKey("stranger", "ES256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	j := newTestLib(t, recent, expired, secret)
+	current := j.keys.Current()
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"current key", signWith(t, current.method, current.sign, current.id, userClaims(nil)), true},
+		{"without kid signed by current key", signWith(t, current.method, current.sign, "", userClaims(nil)), true},
+		{"recently retired key", signWith(t, recent.method, recent.sign, recent.id, userClaims(nil)), true},
+		{"retired longer than token lifetime", signWith(t, expired.method, expired.sign, expired.id, userClaims(nil)), false},
+		{"unknown kid", signWith(t, stranger.method, stranger.sign, stranger.id, userClaims(nil)), false},
+		{"unknown key under known kid", signWith(t, stranger.method, stranger.sign, current.id, userClaims(nil)), false},
+		// подмена алгоритма: HS256 под kid ключа ES256
+		{"hs256 under es256 kid", signWith(t, jwt.SigningMethodHS256, []byte("known to attacker"), current.id, userClaims(nil)), false},
+		{"es256 under hmac kid", signWith(t, current.method, current.sign, secret.id, userClaims(nil)), false},
+		{"alg none", signWith(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, current.id, userClaims(nil)), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := j.ParseToken(tt.token, tokenClaims.TokenTypeAccess)
+			if tt.valid && err != nil {
+				t.Fatalf("ParseToken: %v", err)
+			}
+			if !tt.valid && !errors.Is(err, jwtErrors.ErrInvalidToken) {
+				t.Errorf("ParseToken: got %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestNewIdTokenSigningKey(t *testing.T) {
+	hmac := NewHMACKey("hmac", []byte("0123456789abcdef0123456789abcdef"))
+	hmacSet, err := NewKeySet(hmac.id, []*Key{hmac}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		lib        *JwtLib
+		wantErr    error
+		algorithms int
+	}{
+		{"asymmetric current key", newTestLib(t), nil, 1},
+		// ID токен проверяет клиент, общий секрет ему не выдать
+		{"hmac current key", NewJwtLib(time.Minute, hmacSet, testIssuer), jwtErrors.ErrIdTokenUnsupported, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.lib.NewIdToken(&domain.User{Id: 1, Login: "user"}, IdTokenParams{Audience: "grafana"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewIdToken: got %v, want %v", err, tt.wantErr)
+			}
+			if got := len(tt.lib.SigningAlgorithms()); got != tt.algorithms {
+				t.Errorf("SigningAlgorithms: got %d, want %d", got, tt.algorithms)
+			}
+		})
+	}
+}
+
+func TestNewTokenIssuedAtPrecision(t *testing.T) {
+	j := newTestLib(t)
+	before := time.Now().Truncate(time.Microsecond)
+	accessToken, _, err := j.NewToken(&domain.User{Id: 1, RoleId: 1}, domain.NewRefreshToken(1, "", "", "", time.Hour), AccessTokenParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := time.Now()
+
+	// отзыв по tokens_not_before сравнивает iat с точностью до микросекунды
+	parsed, err := j.ParseToken(accessToken, tokenClaims.TokenTypeAccess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.IssuedAt.Before(before) || parsed.IssuedAt.After(after) {
+		t.Errorf("iat %v is outside of [%v, %v]", parsed.IssuedAt, before, after)
+	}
+	if parsed.SessionId == "" {
+		t.Error("access token has no sid")
+	}
+}
@@ -0,0 +1,166 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key — ключ подписи токенов. Для HMAC sign и verify - один и тот же секрет,
+// для асимметричных алгоритмов verify - публичная часть, её и публикуем в JWKS
+type Key struct {
+	// kid в заголовке токена
+	id     string
+	method jwt.SigningMethod
+	sign   crypto.PrivateKey
+	verify crypto.PublicKey
+	// с этого момента ключ не подписывает новые токены
+	retiredAt *time.Time
+}
+
+func NewHMACKey(id string, secret []byte) *Key {
+	return &Key{
+		id:     id,
+		method: jwt.SigningMethodHS256,
+		sign:   secret,
+		verify: secret,
+	}
+}
+
+// LoadKey читает приватный ключ из PEM файла. algorithm - RS256/RS384/RS512,
+// PS256/PS384/PS512, ES256/ES384/ES512 или EdDSA
+func LoadKey(id, algorithm, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key file: %w", err)
+	}
+	return ParseKey(id, algorithm, data)
+}
+
+func ParseKey(id, algorithm string, pemData []byte) (*Key, error) {
+	method := jwt.GetSigningMethod(algorithm)
+	if method == nil {
+		return nil, fmt.Errorf("unknown signing algorithm %q", algorithm)
+	}
+
+	switch m := method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		private, err := jwt.ParseRSAPrivateKeyFromPEM(pemData)
+		if err != nil {
+			return nil, fmt.Errorf("parse rsa key: %w", err)
+		}
+		return &Key{id: id, method: method, sign: private, verify: &private.PublicKey}, nil
+	case *jwt.SigningMethodECDSA:
+		private, err := jwt.ParseECPrivateKeyFromPEM(pemData)
+		if err != nil {
+			return nil, fmt.Errorf("parse ecdsa key: %w", err)
+		}
+		if private.Curve.Params().BitSize != m.CurveBits {
+			return nil, fmt.Errorf("%s requires %d-bit curve, got %s", algorithm, m.CurveBits, private.Curve.Params().Name)
+		}
+		return &Key{id: id, method: method, sign: private, verify: &private.PublicKey}, nil
+	case *jwt.SigningMethodEd25519:
+		private, err := jwt.ParseEdPrivateKeyFromPEM(pemData)
+		if err != nil {
+			return nil, fmt.Errorf("parse ed25519 key: %w", err)
+		}
+		edPrivate := private.(ed25519.PrivateKey)
+		return &Key{id: id, method: method, sign: edPrivate, verify: edPrivate.Public()}, nil
+	default:
+		return nil, fmt.Errorf("signing algorithm %q is not supported for key files", algorithm)
+	}
+}
+
+// GenerateKey создаёт новый приватный ключ для algorithm и возвращает его вместе с PEM (PKCS#8),
+// который потом читает LoadKey
+func GenerateKey(id, algorithm string) (*Key, []byte, error) {
+	method := jwt.GetSigningMethod(algorithm)
+	if method == nil {
+		return nil, nil, fmt.Errorf("unknown signing algorithm %q", algorithm)
+	}
+
+	var private crypto.PrivateKey
+	var err error
+	switch m := method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		private, err = rsa.GenerateKey(rand.Reader, 3072)
+	case *jwt.SigningMethodECDSA:
+		var curve elliptic.Curve
+		switch m.CurveBits {
+		case 256:
+			curve = elliptic.P256()
+		case 384:
+			curve = elliptic.P384()
+		default:
+			curve = elliptic.P521()
+		}
+		private, err = ecdsa.GenerateKey(curve, rand.Reader)
+	case *jwt.SigningMethodEd25519:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, nil, fmt.Errorf("signing algorithm %q is not supported for key files", algorithm)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate key: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, nil, fmt.Errorf("marshal key: %w", err)
+	}
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	key, err := ParseKey(id, algorithm, pemData)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, pemData, nil
+}
+
+func (k *Key) Id() string {
+	return k.id
+}
+
+// Retire выводит ключ из ротации: с момента at он только проверяет ранее выпущенные токены
+func (k *Key) Retire(at time.Time) {
+	k.retiredAt = &at
+}
+
+// Algorithm — значение alg в заголовке токенов, подписанных этим ключом
+func (k *Key) Algorithm() string {
+	return k.method.Alg()
+}
+
+// curveName — имя кривой в терминах JWK (crv)
+func curveName(curve elliptic.Curve) string {
+	switch curve {
+	case elliptic.P256():
+		return "P-256"
+	case elliptic.P384():
+		return "P-384"
+	case elliptic.P521():
+		return "P-521"
+	default:
+		return curve.Params().Name
+	}
+}
+
+// isAsymmetric - можно ли публиковать verify ключ
+func (k *Key) isAsymmetric() bool {
+	switch k.verify.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return true
+	default:
+		return false
+	}
+}
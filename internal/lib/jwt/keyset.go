@@ -0,0 +1,77 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// KeySet — ключи подписи. Новые токены подписываются текущим ключом, а проверяются
+// любым ключом, который ещё может встретиться в живых токенах: выведенный из ротации
+// ключ остаётся в наборе (и в JWKS) ещё maxTokenLifetime, пока не истекут подписанные им токены.
+//
+// Ротация без простоя: добавить новый ключ в конфиг и раскатить, чтобы все реплики
+// и потребители JWKS его увидели; затем сделать его текущим, а старому проставить retired_at
+type KeySet struct {
+	current          *Key
+	keys             map[string]*Key
+	maxTokenLifetime time.Duration
+}
+
+func NewKeySet(currentId string, keys []*Key, maxTokenLifetime time.Duration) (*KeySet, error) {
+	set := &KeySet{
+		keys:             make(map[string]*Key, len(keys)),
+		maxTokenLifetime: maxTokenLifetime,
+	}
+	for _, key := range keys {
+		if key.id == "" {
+			return nil, errors.New("key id is required")
+		}
+		if _, ok := set.keys[key.id]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", key.id)
+		}
+		set.keys[key.id] = key
+	}
+
+	current, ok := set.keys[currentId]
+	if !ok {
+		return nil, fmt.Errorf("current key %q not found", currentId)
+	}
+	if current.retiredAt != nil {
+		return nil, fmt.Errorf("current key %q is retired", currentId)
+	}
+	set.current = current
+	return set, nil
+}
+
+// Current — ключ, которым подписываются новые токены
+func (s *KeySet) Current() *Key {
+	return s.current
+}
+
+// Get возвращает ключ для проверки подписи, если им ещё могут быть подписаны живые токены
+func (s *KeySet) Get(id string) (*Key, bool) {
+	key, ok := s.keys[id]
+	if !ok || !s.isVerifying(key, time.Now()) {
+		return nil, false
+	}
+	return key, true
+}
+
+// Verifying — все ключи, пригодные для проверки, в стабильном порядке
+func (s *KeySet) Verifying() []*Key {
+	now := time.Now()
+	keys := make([]*Key, 0, len(s.keys))
+	for _, key := range s.keys {
+		if s.isVerifying(key, now) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].id < keys[j].id })
+	return keys
+}
+
+func (s *KeySet) isVerifying(key *Key, now time.Time) bool {
+	return key.retiredAt == nil || now.Before(key.retiredAt.Add(s.maxTokenLifetime))
+}
@@ -0,0 +1,40 @@
+package jwt
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/phenirain/sso/internal/domain"
+	tokenClaims "github.com/phenirain/sso/pkg/claims"
+)
+
+// MfaTokenParams — что нужно помнить между проверкой пароля и вводом второго фактора
+type MfaTokenParams struct {
+	TTL time.Duration
+	// Клиент OpenID Connect, для которого потом выпускается ID токен
+	ClientId string
+	// nonce из запроса клиента, если был
+	Nonce string
+}
+
+// NewMfaToken выпускает короткоживущий токен второго шага входа. Ни к чему, кроме
+// /auth/mfa/verify, он доступа не даёт: token_use у него mfa
+func (j *JwtLib) NewMfaToken(user *domain.User, params MfaTokenParams) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":       j.issuer,
+		"sub":       user.Id,
+		"role_id":   user.RoleId,
+		"iat":       now.Unix(),
+		"exp":       now.Add(params.TTL).Unix(),
+		"jti":       domain.NewTokenId(),
+		"token_use": tokenClaims.TokenTypeMfa,
+	}
+	if params.ClientId != "" {
+		claims["client_id"] = params.ClientId
+	}
+	if params.Nonce != "" {
+		claims["nonce"] = params.Nonce
+	}
+	return j.sign(claims)
+}
@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// File — доставка для локальной разработки и тестов: письма дописываются в файл,
+// а если путь не задан - просто пишутся в лог
+type File struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+func (f *File) Send(ctx context.Context, message Message) error {
+	if f.path == "" {
+		slog.Info("notification", "channel", message.Channel, "to", message.To, "subject", message.Subject, "body", message.Body)
+		return nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("open notifications file: %w", err)
+	}
+	defer file.Close()
+
+	channel := message.Channel
+	if channel == "" {
+		channel = Email
+	}
+	_, err = fmt.Fprintf(file, "Date: %s\nChannel: %s\nTo: %s\nSubject: %s\n\n%s\n\n---\n\n",
+		time.Now().Format(time.RFC3339), channel, message.To, message.Subject, message.Body)
+	if err != nil {
+		return fmt.Errorf("write notification: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package notify
+
+// Каналы доставки
+const (
+	Email = "email"
+	SMS   = "sms"
+)
+
+// Message — сообщение пользователю. Subject используется только в письмах
+type Message struct {
+	// Канал доставки, пусто - письмо
+	Channel string
+	To      string
+	Subject string
+	Body    string
+}
@@ -0,0 +1,68 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTP отправляет письма через SMTP сервер. Если указан username - с аутентификацией PLAIN,
+// net/smtp сам включает STARTTLS, если сервер его поддерживает
+type SMTP struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTP(host string, port int, username, password, from string) *SMTP {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTP{
+		addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		auth: auth,
+		from: from,
+	}
+}
+
+func (s *SMTP) Send(ctx context.Context, message Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if message.Channel != "" && message.Channel != Email {
+		return fmt.Errorf("smtp can't deliver %s messages", message.Channel)
+	}
+	// адрес попадает в заголовок письма - переводы строк в нём недопустимы
+	if _, err := mail.ParseAddress(message.To); err != nil {
+		return fmt.Errorf("invalid recipient %q: %w", message.To, err)
+	}
+	// from может быть с именем ("SSO <no-reply@example.com>"): имя - только для заголовка,
+	// в MAIL FROM сервер ждёт голый адрес
+	from, err := mail.ParseAddress(s.from)
+	if err != nil {
+		return fmt.Errorf("invalid sender %q: %w", s.from, err)
+	}
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "From: %s\r\n", from.String())
+	fmt.Fprintf(&body, "To: %s\r\n", message.To)
+	fmt.Fprintf(&body, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", message.Subject))
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	body.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	body.WriteString("\r\n")
+	body.WriteString(message.Body)
+
+	if err := smtp.SendMail(s.addr, s.auth, from.Address, []string{message.To}, body.Bytes()); err != nil {
+		return fmt.Errorf("send mail: %w", err)
+	}
+	return nil
+}
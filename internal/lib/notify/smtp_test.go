@@ -0,0 +1,130 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeSMTP принимает одно письмо и запоминает команды клиента и текст письма
+type fakeSMTP struct {
+	listener net.Listener
+	commands chan []string
+	data     chan string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &fakeSMTP{listener: listener, commands: make(chan []string, 1), data: make(chan string, 1)}
+	go s.serve()
+	return s
+}
+
+func (s *fakeSMTP) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 localhost ESMTP")
+
+	var commands []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		commands = append(commands, line)
+		switch {
+		case strings.HasPrefix(line, "EHLO"):
+			reply("250 localhost")
+		case line == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			s.data <- data.String()
+			reply("250 queued")
+		case line == "QUIT":
+			reply("221 bye")
+			s.commands <- commands
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func (s *fakeSMTP) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestSMTPEnvelopeSender(t *testing.T) {
+	server := newFakeSMTP(t)
+	sender := NewSMTP("127.0.0.1", server.port(), "", "", "SSO <no-reply@example.com>")
+
+	err := sender.Send(context.Background(), Message{To: "user@example.com", Subject: "Тема", Body: "текст"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commands := <-server.commands
+	if !contains(commands, "MAIL FROM:<no-reply@example.com>") {
+		t.Errorf("MAIL FROM with bare address expected, got %q", commands)
+	}
+	if !contains(commands, "RCPT TO:<user@example.com>") {
+		t.Errorf("RCPT TO expected, got %q", commands)
+	}
+	if data := <-server.data; !strings.Contains(data, "From: \"SSO\" <no-reply@example.com>\r\n") {
+		t.Errorf("From header with display name expected, got %q", data)
+	}
+}
+
+func TestSMTPRejectsInvalidAddresses(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+	}{
+		{"header injection in recipient", "no-reply@example.com", "user@example.com\r\nBcc: other@example.com"},
+		{"invalid sender", "SSO no-reply", "user@example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// порт 0 - до соединения дело дойти не должно
+			sender := NewSMTP("127.0.0.1", 0, "", "", tt.from)
+			if err := sender.Send(context.Background(), Message{To: tt.to}); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func contains(lines []string, want string) bool {
+	for _, line := range lines {
+		if strings.HasPrefix(line, want) {
+			return true
+		}
+	}
+	return false
+}
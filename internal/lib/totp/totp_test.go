@@ -0,0 +1,77 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret — ключ "12345678901234567890" из RFC 6238 в base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeRfc6238(t *testing.T) {
+	// RFC 6238, приложение B, SHA1: последние 6 цифр 8-значных кодов
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		t.Run(time.Unix(tt.unix, 0).UTC().Format(time.RFC3339), func(t *testing.T) {
+			code, err := Code(rfcSecret, time.Unix(tt.unix, 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if code != tt.want {
+				t.Errorf("Code = %s, want %s", code, tt.want)
+			}
+			if step, ok := Validate(rfcSecret, tt.want, time.Unix(tt.unix, 0), 0); !ok || step != tt.unix/Period {
+				t.Errorf("Validate = %d, %v, want %d, true", step, ok, tt.unix/Period)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / Period
+	code := func(offset int64) string {
+		c, err := Code(rfcSecret, time.Unix((step+offset)*Period, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		lastStep int64
+		wantStep int64
+		wantOk   bool
+	}{
+		{"current step", rfcSecret, code(0), 0, step, true},
+		{"previous step", rfcSecret, code(-1), 0, step - 1, true},
+		{"next step", rfcSecret, code(1), 0, step + 1, true},
+		{"two steps back", rfcSecret, code(-2), 0, 0, false},
+		{"lowercase secret", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", code(0), 0, step, true},
+		{"step already used", rfcSecret, code(0), step, 0, false},
+		{"step before used one", rfcSecret, code(-1), step, 0, false},
+		{"step after used one", rfcSecret, code(1), step, step + 1, true},
+		{"short code", rfcSecret, code(0)[1:], 0, 0, false},
+		{"invalid secret", "not base32!", code(0), 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, gotOk := Validate(tt.secret, tt.code, now, tt.lastStep)
+			if gotOk != tt.wantOk || gotStep != tt.wantStep {
+				t.Errorf("Validate = %d, %v, want %d, %v", gotStep, gotOk, tt.wantStep, tt.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package webauthn
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeCbor(t *testing.T) {
+	// примеры из RFC 8949, приложение A
+	tests := []struct {
+		hex  string
+		want any
+	}{
+		{"00", int64(0)},
+		{"17", int64(23)},
+		{"1818", int64(24)},
+		{"1903e8", int64(1000)},
+		{"1a000f4240", int64(1000000)},
+		{"1b000000e8d4a51000", int64(1000000000000)},
+		{"1b7fffffffffffffff", int64(1<<63 - 1)},
+		{"20", int64(-1)},
+		{"3863", int64(-100)},
+		{"3903e7", int64(-1000)},
+		{"3b7fffffffffffffff", int64(-1 << 63)},
+		{"40", []byte(nil)},
+		{"4401020304", []byte{1, 2, 3, 4}},
+		{"60", ""},
+		{"6161", "a"},
+		{"6449455446", "IETF"},
+		{"80", []any{}},
+		{"83010203", []any{int64(1), int64(2), int64(3)}},
+		{"8301820203820405", []any{int64(1), []any{int64(2), int64(3)}, []any{int64(4), int64(5)}}},
+		{"a0", map[any]any{}},
+		{"a201020304", map[any]any{int64(1): int64(2), int64(3): int64(4)}},
+		{"a26161016162820203", map[any]any{"a": int64(1), "b": []any{int64(2), int64(3)}}},
+		{"f4", false},
+		{"f5", true},
+		{"f6", nil},
+		{"f7", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hex, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.hex)
+			got, rest, err := decodeCbor(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rest) != 0 {
+				t.Errorf("rest = %x, want empty", rest)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCborReturnsRest(t *testing.T) {
+	got, rest, err := decodeCbor([]byte{0x01, 0x02, 0x03})
+	if err != nil || got != int64(1) || !bytes.Equal(rest, []byte{0x02, 0x03}) {
+		t.Errorf("got %v, rest %x, err %v", got, rest, err)
+	}
+}
+
+func TestDecodeCborRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated argument", []byte{0x19, 0x01}},
+		{"truncated 64-bit argument", []byte{0x1b, 0x00, 0x00}},
+		{"string longer than data", []byte{0x44, 0x01, 0x02}},
+		{"text longer than data", []byte{0x65, 'a'}},
+		{"truncated array", []byte{0x83, 0x01, 0x02}},
+		{"truncated map", []byte{0xa2, 0x01, 0x02, 0x03}},
+		{"map without value", []byte{0xa1, 0x01}},
+		{"huge array length", []byte{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"huge string length", []byte{0x5b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"unsigned overflow", []byte{0x1b, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{"negative overflow", []byte{0x3b, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{"indefinite length array", []byte{0x9f, 0x01, 0xff}},
+		{"reserved additional info", []byte{0x1c}},
+		{"tag", []byte{0xc1, 0x01}},
+		{"float", []byte{0xf9, 0x3c, 0x00}},
+		{"byte string map key", []byte{0xa1, 0x41, 0x00, 0x01}},
+		{"array map key", []byte{0xa1, 0x80, 0x01}},
+		{"nested too deep", append(bytes.Repeat([]byte{0x81}, maxCborDepth+1), 0x00)},
+		{"deeply nested maps", append(bytes.Repeat([]byte{0xa1, 0x01}, 100), 0x00)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _, err := decodeCbor(tt.data); !errors.Is(err, errCbor) {
+				t.Errorf("got %#v, %v, want errCbor", got, err)
+			}
+		})
+	}
+}
+
+func TestDecodeCborMaxDepth(t *testing.T) {
+	data := append(bytes.Repeat([]byte{0x81}, maxCborDepth), 0x00)
+	if _, _, err := decodeCbor(data); err != nil {
+		t.Errorf("nesting of %d: %v", maxCborDepth, err)
+	}
+}
@@ -0,0 +1,133 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// encodeCose кодирует COSE_Key: пары ключ (int) - значение (int или []byte)
+func encodeCose(pairs ...any) []byte {
+	result := cborHeader(5, uint64(len(pairs)/2))
+	for _, item := range pairs {
+		switch v := item.(type) {
+		case int:
+			if v < 0 {
+				result = append(result, cborHeader(1, uint64(-1-v))...)
+			} else {
+				result = append(result, cborHeader(0, uint64(v))...)
+			}
+		case []byte:
+			result = append(append(result, cborHeader(2, uint64(len(v)))...), v...)
+		}
+	}
+	return result
+}
+
+func cborHeader(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n < 1<<8:
+		return []byte{major<<5 | 24, byte(n)}
+	default:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	x, y := ecKey.X.FillBytes(make([]byte, 32)), ecKey.Y.FillBytes(make([]byte, 32))
+	edPublic, _, _ := ed25519.GenerateKey(rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	n, e := rsaKey.N.Bytes(), big.NewInt(int64(rsaKey.E)).Bytes()
+	smallRsaKey, _ := rsa.GenerateKey(rand.Reader, 1024)
+	offCurve := make([]byte, 32)
+	offCurve[31] = 1
+
+	tests := []struct {
+		name    string
+		key     []byte
+		wantAlg int64
+		wantErr bool
+	}{
+		{name: "ES256", key: encodeCose(1, 2, 3, -7, -1, 1, -2, x, -3, y), wantAlg: AlgES256},
+		{name: "EdDSA", key: encodeCose(1, 1, 3, -8, -1, 6, -2, []byte(edPublic)), wantAlg: AlgEdDSA},
+		{name: "RS256", key: encodeCose(1, 3, 3, -257, -1, n, -2, e), wantAlg: AlgRS256},
+
+		{name: "ES256 wrong curve", key: encodeCose(1, 2, 3, -7, -1, 2, -2, x, -3, y), wantErr: true},
+		{name: "ES256 short x", key: encodeCose(1, 2, 3, -7, -1, 1, -2, x[:31], -3, y), wantErr: true},
+		{name: "ES256 missing y", key: encodeCose(1, 2, 3, -7, -1, 1, -2, x), wantErr: true},
+		{name: "ES256 point not on curve", key: encodeCose(1, 2, 3, -7, -1, 1, -2, offCurve, -3, offCurve), wantErr: true},
+		{name: "EdDSA wrong curve", key: encodeCose(1, 1, 3, -8, -1, 1, -2, []byte(edPublic)), wantErr: true},
+		{name: "EdDSA short key", key: encodeCose(1, 1, 3, -8, -1, 6, -2, []byte(edPublic)[:31]), wantErr: true},
+		{name: "RS256 1024 bit", key: encodeCose(1, 3, 3, -257, -1, smallRsaKey.N.Bytes(), -2, e), wantErr: true},
+		{name: "RS256 empty exponent", key: encodeCose(1, 3, 3, -257, -1, n, -2, []byte{}), wantErr: true},
+		{name: "RS256 long exponent", key: encodeCose(1, 3, 3, -257, -1, n, -2, []byte{1, 0, 0, 0, 1}), wantErr: true},
+		{name: "key type and algorithm mismatch", key: encodeCose(1, 2, 3, -8, -1, 1, -2, x, -3, y), wantErr: true},
+		{name: "unsupported algorithm", key: encodeCose(1, 2, 3, -35, -1, 1, -2, x, -3, y), wantErr: true},
+		{name: "not a map", key: []byte{0x80}, wantErr: true},
+		{name: "trailing data", key: append(encodeCose(1, 1, 3, -8, -1, 6, -2, []byte(edPublic)), 0x00), wantErr: true},
+		{name: "truncated", key: encodeCose(1, 2, 3, -7, -1, 1, -2, x, -3, y)[:50], wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := parsePublicKey(tt.key)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				if !errors.Is(err, ErrInvalidResponse) && !errors.Is(err, errCbor) {
+					t.Errorf("unexpected error type: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key.alg != tt.wantAlg {
+				t.Errorf("alg = %d, want %d", key.alg, tt.wantAlg)
+			}
+		})
+	}
+}
+
+func TestPublicKeyVerify(t *testing.T) {
+	data := []byte("authenticatorData || clientDataHash")
+	digest := sha256.Sum256(data)
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rsaSignature, _ := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ecSignature, _ := ecdsa.SignASN1(rand.Reader, ecKey, digest[:])
+	edPublic, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	edSignature := ed25519.Sign(edPrivate, data)
+
+	tests := []struct {
+		name      string
+		key       *publicKey
+		signature []byte
+	}{
+		{"ES256", &publicKey{alg: AlgES256, key: &ecKey.PublicKey}, ecSignature},
+		{"EdDSA", &publicKey{alg: AlgEdDSA, key: edPublic}, edSignature},
+		{"RS256", &publicKey{alg: AlgRS256, key: &rsaKey.PublicKey}, rsaSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.key.verify(data, tt.signature) {
+				t.Error("valid signature rejected")
+			}
+			if tt.key.verify([]byte("other data"), tt.signature) {
+				t.Error("signature of other data accepted")
+			}
+		})
+	}
+}
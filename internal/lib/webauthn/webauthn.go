@@ -0,0 +1,215 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Типы церемоний в clientDataJSON
+const (
+	ceremonyCreate = "webauthn.create"
+	ceremonyGet    = "webauthn.get"
+)
+
+// Флаги authenticatorData
+const (
+	flagUserPresent       = 0x01
+	flagUserVerified      = 0x04
+	flagAttestedData      = 0x40
+	authenticatorDataSize = 37
+)
+
+// ChallengeSize — длина challenge в байтах, спецификация требует не меньше 16
+const ChallengeSize = 32
+
+var (
+	ErrInvalidResponse = errors.New("invalid webauthn response")
+	// ErrSignCount — счётчик подписей не вырос: похоже, ключ скопирован с аутентификатора
+	ErrSignCount = errors.New("webauthn sign count did not increase")
+)
+
+// Encoding — base64url без выравнивания, так в WebAuthn передаются все бинарные поля
+var Encoding = base64.RawURLEncoding
+
+// RelyingParty — наш сервис с точки зрения WebAuthn. id - домен, к которому привязаны
+// ключи, origins - адреса страниц, с которых разрешены церемонии
+type RelyingParty struct {
+	Id      string
+	Name    string
+	origins []string
+	idHash  [32]byte
+}
+
+func New(id, name string, origins []string) *RelyingParty {
+	return &RelyingParty{
+		Id:      id,
+		Name:    name,
+		origins: origins,
+		idHash:  sha256.Sum256([]byte(id)),
+	}
+}
+
+// Credential — ключ, зарегистрированный аутентификатором
+type Credential struct {
+	Id []byte
+	// COSE_Key как прислал аутентификатор, его и храним
+	PublicKey []byte
+	SignCount uint32
+}
+
+// NewChallenge — случайный challenge для options
+func NewChallenge() (string, error) {
+	challenge := make([]byte, ChallengeSize)
+	if _, err := rand.Read(challenge); err != nil {
+		return "", fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	return Encoding.EncodeToString(challenge), nil
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// Challenge достаёт challenge из clientDataJSON, чтобы найти сохранённую церемонию.
+// Остальное проверяется в VerifyRegistration и VerifyAssertion
+func Challenge(clientDataJSON []byte) (string, error) {
+	var data clientData
+	if err := json.Unmarshal(clientDataJSON, &data); err != nil || data.Challenge == "" {
+		return "", fmt.Errorf("%w: invalid clientDataJSON", ErrInvalidResponse)
+	}
+	return data.Challenge, nil
+}
+
+// VerifyRegistration проверяет ответ navigator.credentials.create. Аттестацию не проверяем:
+// options просят attestation=none, доверяем ключу, а не модели аутентификатора
+func (rp *RelyingParty) VerifyRegistration(challenge string, clientDataJSON, attestationObject []byte, requireUserVerification bool) (*Credential, error) {
+	if err := rp.verifyClientData(clientDataJSON, ceremonyCreate, challenge); err != nil {
+		return nil, err
+	}
+
+	decoded, _, err := decodeCbor(attestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidResponse, err.Error())
+	}
+	attestation, ok := decoded.(map[any]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: attestationObject is not a map", ErrInvalidResponse)
+	}
+	authData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("%w: missing authData", ErrInvalidResponse)
+	}
+
+	flags, signCount, attested, err := rp.parseAuthenticatorData(authData, requireUserVerification)
+	if err != nil {
+		return nil, err
+	}
+	if flags&flagAttestedData == 0 {
+		return nil, fmt.Errorf("%w: missing attested credential data", ErrInvalidResponse)
+	}
+
+	// aaguid (16) | длина id (2) | id | COSE_Key | расширения
+	if len(attested) < 18 {
+		return nil, fmt.Errorf("%w: attested credential data too short", ErrInvalidResponse)
+	}
+	idLength := int(binary.BigEndian.Uint16(attested[16:18]))
+	attested = attested[18:]
+	if idLength == 0 || idLength > 1023 || len(attested) < idLength {
+		return nil, fmt.Errorf("%w: invalid credential id", ErrInvalidResponse)
+	}
+	credentialId := attested[:idLength]
+	attested = attested[idLength:]
+
+	coseKey, rest, err := decodeCbor(attested)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidResponse, err.Error())
+	}
+	if _, err := publicKeyFromMap(coseKey); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		Id:        append([]byte(nil), credentialId...),
+		PublicKey: append([]byte(nil), attested[:len(attested)-len(rest)]...),
+		SignCount: signCount,
+	}, nil
+}
+
+// VerifyAssertion проверяет ответ navigator.credentials.get ключом publicKey и возвращает
+// новое значение счётчика подписей
+func (rp *RelyingParty) VerifyAssertion(challenge string, clientDataJSON, authenticatorData, signature, publicKey []byte, storedSignCount uint32, requireUserVerification bool) (uint32, error) {
+	if err := rp.verifyClientData(clientDataJSON, ceremonyGet, challenge); err != nil {
+		return 0, err
+	}
+	_, signCount, _, err := rp.parseAuthenticatorData(authenticatorData, requireUserVerification)
+	if err != nil {
+		return 0, err
+	}
+
+	key, err := parsePublicKey(publicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte(nil), authenticatorData...), clientDataHash[:]...)
+	if !key.verify(signed, signature) {
+		return 0, fmt.Errorf("%w: invalid signature", ErrInvalidResponse)
+	}
+
+	// аутентификаторы без счётчика всегда присылают 0
+	if (signCount != 0 || storedSignCount != 0) && signCount <= storedSignCount {
+		return 0, ErrSignCount
+	}
+	return signCount, nil
+}
+
+func (rp *RelyingParty) verifyClientData(clientDataJSON []byte, ceremony, challenge string) error {
+	var data clientData
+	if err := json.Unmarshal(clientDataJSON, &data); err != nil {
+		return fmt.Errorf("%w: invalid clientDataJSON", ErrInvalidResponse)
+	}
+	if data.Type != ceremony {
+		return fmt.Errorf("%w: unexpected type %q", ErrInvalidResponse, data.Type)
+	}
+	if subtle.ConstantTimeCompare([]byte(data.Challenge), []byte(challenge)) != 1 {
+		return fmt.Errorf("%w: challenge mismatch", ErrInvalidResponse)
+	}
+	if !slices.Contains(rp.origins, data.Origin) {
+		return fmt.Errorf("%w: origin %q is not allowed", ErrInvalidResponse, data.Origin)
+	}
+	if data.CrossOrigin {
+		return fmt.Errorf("%w: cross-origin ceremony", ErrInvalidResponse)
+	}
+	return nil
+}
+
+// parseAuthenticatorData проверяет rpIdHash и флаги, возвращает флаги, счётчик и остаток
+// с attested credential data
+func (rp *RelyingParty) parseAuthenticatorData(authData []byte, requireUserVerification bool) (byte, uint32, []byte, error) {
+	if len(authData) < authenticatorDataSize {
+		return 0, 0, nil, fmt.Errorf("%w: authenticatorData too short", ErrInvalidResponse)
+	}
+	if !bytes.Equal(authData[:32], rp.idHash[:]) {
+		return 0, 0, nil, fmt.Errorf("%w: rpIdHash mismatch", ErrInvalidResponse)
+	}
+	flags := authData[32]
+	if flags&flagUserPresent == 0 {
+		return 0, 0, nil, fmt.Errorf("%w: user not present", ErrInvalidResponse)
+	}
+	if requireUserVerification && flags&flagUserVerified == 0 {
+		return 0, 0, nil, fmt.Errorf("%w: user not verified", ErrInvalidResponse)
+	}
+	signCount := binary.BigEndian.Uint32(authData[33:37])
+	return flags, signCount, authData[authenticatorDataSize:], nil
+}
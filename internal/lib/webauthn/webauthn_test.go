@@ -0,0 +1,240 @@
+package webauthn
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/phenirain/sso/internal/lib/webauthn/webauthntest"
+)
+
+const (
+	testRpId   = "sso.example.com"
+	testOrigin = "https://sso.example.com"
+)
+
+func newTestRp() *RelyingParty {
+	return New(testRpId, "SSO", []string{testOrigin})
+}
+
+func newChallenge(t *testing.T) string {
+	t.Helper()
+	challenge, err := NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return challenge
+}
+
+var authenticators = []struct {
+	name string
+	new  func(rpId, origin string) *webauthntest.Authenticator
+}{
+	{"ES256", webauthntest.NewES256},
+	{"Ed25519", webauthntest.NewEd25519},
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	for _, tt := range authenticators {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := newTestRp()
+			authenticator := tt.new(testRpId, testOrigin)
+
+			challenge := newChallenge(t)
+			clientDataJSON, attestationObject := authenticator.Create(challenge)
+			credential, err := rp.VerifyRegistration(challenge, clientDataJSON, attestationObject, true)
+			if err != nil {
+				t.Fatalf("registration: %v", err)
+			}
+			if string(credential.Id) != string(authenticator.Id) || credential.SignCount != 1 {
+				t.Fatalf("credential = %x, sign count %d", credential.Id, credential.SignCount)
+			}
+
+			signCount := credential.SignCount
+			for i := 0; i < 2; i++ {
+				challenge = newChallenge(t)
+				clientDataJSON, authenticatorData, signature := authenticator.Get(challenge)
+				signCount, err = rp.VerifyAssertion(challenge, clientDataJSON, authenticatorData, signature, credential.PublicKey, signCount, true)
+				if err != nil {
+					t.Fatalf("assertion %d: %v", i+1, err)
+				}
+			}
+			if signCount != 3 {
+				t.Errorf("sign count = %d, want 3", signCount)
+			}
+		})
+	}
+}
+
+func TestVerifyAssertionRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare портит аутентификатор или сохранённый счётчик перед ответом
+		prepare         func(a *webauthntest.Authenticator)
+		storedSignCount uint32
+		// tamper портит уже подписанный ответ
+		tamper  func(clientDataJSON, authenticatorData, signature []byte)
+		wantErr error
+	}{
+		{
+			name:    "wrong origin",
+			prepare: func(a *webauthntest.Authenticator) { a.Origin = "https://evil.example.com" },
+			wantErr: ErrInvalidResponse,
+		},
+		{
+			name:    "cross origin",
+			prepare: func(a *webauthntest.Authenticator) { a.CrossOrigin = true },
+			wantErr: ErrInvalidResponse,
+		},
+		{
+			name:    "wrong rpId",
+			prepare: func(a *webauthntest.Authenticator) { a.RpId = "evil.example.com" },
+			wantErr: ErrInvalidResponse,
+		},
+		{
+			name:    "user not verified",
+			prepare: func(a *webauthntest.Authenticator) { a.Flags = webauthntest.FlagUserPresent },
+			wantErr: ErrInvalidResponse,
+		},
+		{
+			name:    "user not present",
+			prepare: func(a *webauthntest.Authenticator) { a.Flags = webauthntest.FlagUserVerified },
+			wantErr: ErrInvalidResponse,
+		},
+		{
+			name:            "counter regression",
+			prepare:         func(a *webauthntest.Authenticator) { a.SignCount = 5 },
+			storedSignCount: 7,
+			wantErr:         ErrSignCount,
+		},
+		{
+			name:            "counter not increased",
+			prepare:         func(a *webauthntest.Authenticator) { a.SignCount = 7 },
+			storedSignCount: 7,
+			wantErr:         ErrSignCount,
+		},
+		{
+			name:            "counter dropped to zero",
+			prepare:         func(a *webauthntest.Authenticator) { a.SignCount = 0 },
+			storedSignCount: 7,
+			wantErr:         ErrSignCount,
+		},
+		{
+			name:    "tampered authenticator data",
+			tamper:  func(_, authenticatorData, _ []byte) { authenticatorData[36]++ },
+			wantErr: ErrInvalidResponse,
+		},
+		{
+			name:    "tampered signature",
+			tamper:  func(_, _, signature []byte) { signature[len(signature)-1] ^= 0xff },
+			wantErr: ErrInvalidResponse,
+		},
+	}
+	for _, tt := range tests {
+		for _, kind := range authenticators {
+			t.Run(tt.name+"/"+kind.name, func(t *testing.T) {
+				rp := newTestRp()
+				authenticator := kind.new(testRpId, testOrigin)
+				if tt.prepare != nil {
+					tt.prepare(authenticator)
+				}
+
+				challenge := newChallenge(t)
+				clientDataJSON, authenticatorData, signature := authenticator.Get(challenge)
+				if tt.tamper != nil {
+					tt.tamper(clientDataJSON, authenticatorData, signature)
+				}
+				_, err := rp.VerifyAssertion(challenge, clientDataJSON, authenticatorData, signature, authenticator.PublicKey(), tt.storedSignCount, true)
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("got %v, want %v", err, tt.wantErr)
+				}
+			})
+		}
+	}
+}
+
+func TestVerifyAssertionWithoutCounter(t *testing.T) {
+	rp := newTestRp()
+	authenticator := webauthntest.NewES256(testRpId, testOrigin)
+	authenticator.SignCount = 0
+
+	for i := 0; i < 2; i++ {
+		challenge := newChallenge(t)
+		clientDataJSON, authenticatorData, signature := authenticator.Get(challenge)
+		signCount, err := rp.VerifyAssertion(challenge, clientDataJSON, authenticatorData, signature, authenticator.PublicKey(), 0, true)
+		if err != nil || signCount != 0 {
+			t.Fatalf("assertion %d: sign count %d, err %v", i+1, signCount, err)
+		}
+	}
+}
+
+func TestVerifyAssertionChecksCeremony(t *testing.T) {
+	rp := newTestRp()
+	authenticator := webauthntest.NewES256(testRpId, testOrigin)
+	challenge := newChallenge(t)
+	clientDataJSON, authenticatorData, signature := authenticator.Get(challenge)
+
+	if _, err := rp.VerifyAssertion(newChallenge(t), clientDataJSON, authenticatorData, signature, authenticator.PublicKey(), 0, true); !errors.Is(err, ErrInvalidResponse) {
+		t.Errorf("other challenge: got %v", err)
+	}
+	// ответ регистрации не годится для входа
+	createClientData, _ := authenticator.Create(challenge)
+	if _, err := rp.VerifyAssertion(challenge, createClientData, authenticatorData, signature, authenticator.PublicKey(), 0, true); !errors.Is(err, ErrInvalidResponse) {
+		t.Errorf("create ceremony: got %v", err)
+	}
+	// ключ другого аутентификатора
+	other := webauthntest.NewES256(testRpId, testOrigin)
+	if _, err := rp.VerifyAssertion(challenge, clientDataJSON, authenticatorData, signature, other.PublicKey(), 0, true); !errors.Is(err, ErrInvalidResponse) {
+		t.Errorf("other key: got %v", err)
+	}
+}
+
+func TestVerifyRegistrationRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(a *webauthntest.Authenticator)
+		tamper  func(attestationObject []byte) []byte
+	}{
+		{name: "wrong origin", prepare: func(a *webauthntest.Authenticator) { a.Origin = "https://evil.example.com" }},
+		{name: "wrong rpId", prepare: func(a *webauthntest.Authenticator) { a.RpId = "evil.example.com" }},
+		{name: "user not verified", prepare: func(a *webauthntest.Authenticator) { a.Flags = webauthntest.FlagUserPresent }},
+		{name: "empty credential id", prepare: func(a *webauthntest.Authenticator) { a.Id = nil }},
+		{name: "truncated attestation object", tamper: func(b []byte) []byte { return b[:len(b)-10] }},
+		{name: "attestation object is not a map", tamper: func([]byte) []byte { return []byte{0x80} }},
+		{name: "deeply nested attestation object", tamper: func([]byte) []byte {
+			// [[[[...0...]]]] глубже maxCborDepth
+			return append(bytes.Repeat([]byte{0x81}, 1000), 0x00)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := newTestRp()
+			authenticator := webauthntest.NewES256(testRpId, testOrigin)
+			if tt.prepare != nil {
+				tt.prepare(authenticator)
+			}
+
+			challenge := newChallenge(t)
+			clientDataJSON, attestationObject := authenticator.Create(challenge)
+			if tt.tamper != nil {
+				attestationObject = tt.tamper(attestationObject)
+			}
+			if _, err := rp.VerifyRegistration(challenge, clientDataJSON, attestationObject, true); !errors.Is(err, ErrInvalidResponse) {
+				t.Errorf("got %v, want ErrInvalidResponse", err)
+			}
+		})
+	}
+}
+
+func TestChallenge(t *testing.T) {
+	authenticator := webauthntest.NewES256(testRpId, testOrigin)
+	clientDataJSON, _, _ := authenticator.Get("abc")
+	if challenge, err := Challenge(clientDataJSON); err != nil || challenge != "abc" {
+		t.Errorf("Challenge = %q, %v", challenge, err)
+	}
+	for _, data := range []string{``, `{}`, `{"challenge":""}`, `not json`} {
+		if _, err := Challenge([]byte(data)); !errors.Is(err, ErrInvalidResponse) {
+			t.Errorf("Challenge(%q): got %v", data, err)
+		}
+	}
+}
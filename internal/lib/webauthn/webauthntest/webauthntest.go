@@ -0,0 +1,166 @@
+// Package webauthntest — программный аутентификатор WebAuthn для тестов: создаёт ключи
+// и подписывает ответы так же, как браузер с настоящим аутентификатором
+package webauthntest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+)
+
+// Флаги authenticatorData
+const (
+	FlagUserPresent  byte = 0x01
+	FlagUserVerified byte = 0x04
+	FlagAttestedData byte = 0x40
+)
+
+// Authenticator хранит один ключ. Поля можно менять между церемониями, чтобы получить
+// неверный ответ: чужой origin, другой rpId, откат счётчика
+type Authenticator struct {
+	Id          []byte
+	RpId        string
+	Origin      string
+	CrossOrigin bool
+	// Флаги следующего ответа, по умолчанию пользователь присутствует и проверен
+	Flags byte
+	// Счётчик в следующем ответе. 0 - аутентификатор без счётчика, тогда он не растёт
+	SignCount uint32
+
+	coseKey []byte
+	sign    func(data []byte) []byte
+}
+
+// NewES256 — аутентификатор с ключом ECDSA P-256
+func NewES256(rpId, origin string) *Authenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	a := newAuthenticator(rpId, origin)
+	a.coseKey = cborMap(
+		cborInt(1), cborInt(2), // kty: EC2
+		cborInt(3), cborInt(-7), // alg: ES256
+		cborInt(-1), cborInt(1), // crv: P-256
+		cborInt(-2), cborBytes(key.X.FillBytes(make([]byte, 32))),
+		cborInt(-3), cborBytes(key.Y.FillBytes(make([]byte, 32))),
+	)
+	a.sign = func(data []byte) []byte {
+		digest := sha256.Sum256(data)
+		signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		if err != nil {
+			panic(err)
+		}
+		return signature
+	}
+	return a
+}
+
+// NewEd25519 — аутентификатор с ключом Ed25519
+func NewEd25519(rpId, origin string) *Authenticator {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	a := newAuthenticator(rpId, origin)
+	a.coseKey = cborMap(
+		cborInt(1), cborInt(1), // kty: OKP
+		cborInt(3), cborInt(-8), // alg: EdDSA
+		cborInt(-1), cborInt(6), // crv: Ed25519
+		cborInt(-2), cborBytes(public),
+	)
+	a.sign = func(data []byte) []byte {
+		signature, err := private.Sign(rand.Reader, data, crypto.Hash(0))
+		if err != nil {
+			panic(err)
+		}
+		return signature
+	}
+	return a
+}
+
+func newAuthenticator(rpId, origin string) *Authenticator {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+	return &Authenticator{
+		Id:        id,
+		RpId:      rpId,
+		Origin:    origin,
+		Flags:     FlagUserPresent | FlagUserVerified,
+		SignCount: 1,
+	}
+}
+
+// PublicKey — ключ в формате COSE_Key, как его сохраняет сервер
+func (a *Authenticator) PublicKey() []byte {
+	return a.coseKey
+}
+
+// Create — ответ navigator.credentials.create с attestation "none". Счётчик после ответа растёт
+func (a *Authenticator) Create(challenge string) (clientDataJSON, attestationObject []byte) {
+	clientDataJSON = a.clientData("webauthn.create", challenge)
+
+	// aaguid (16 нулей) | длина id | id | COSE_Key
+	attested := make([]byte, 18, 18+len(a.Id)+len(a.coseKey))
+	binary.BigEndian.PutUint16(attested[16:], uint16(len(a.Id)))
+	attested = append(append(attested, a.Id...), a.coseKey...)
+	authData := append(a.authenticatorData(a.Flags|FlagAttestedData), attested...)
+
+	attestationObject = cborMap(
+		cborText("fmt"), cborText("none"),
+		cborText("attStmt"), cborMap(),
+		cborText("authData"), cborBytes(authData),
+	)
+	a.nextSignCount()
+	return clientDataJSON, attestationObject
+}
+
+// Get — ответ navigator.credentials.get. Счётчик после ответа растёт
+func (a *Authenticator) Get(challenge string) (clientDataJSON, authenticatorData, signature []byte) {
+	clientDataJSON = a.clientData("webauthn.get", challenge)
+	authenticatorData = a.authenticatorData(a.Flags)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signature = a.sign(append(append([]byte(nil), authenticatorData...), clientDataHash[:]...))
+	a.nextSignCount()
+	return clientDataJSON, authenticatorData, signature
+}
+
+func (a *Authenticator) nextSignCount() {
+	if a.SignCount != 0 {
+		a.SignCount++
+	}
+}
+
+func (a *Authenticator) clientData(ceremony, challenge string) []byte {
+	data, err := json.Marshal(map[string]any{
+		"type":        ceremony,
+		"challenge":   challenge,
+		"origin":      a.Origin,
+		"crossOrigin": a.CrossOrigin,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// authenticatorData — rpIdHash | флаги | счётчик
+func (a *Authenticator) authenticatorData(flags byte) []byte {
+	rpIdHash := sha256.Sum256([]byte(a.RpId))
+	data := append(rpIdHash[:], flags, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[33:], a.SignCount)
+	return data
+}
+
+// Encode — base64url без выравнивания, как бинарные поля в ответах браузера
+func Encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/phenirain/sso/internal/migrations"
+)
+
+// migrate — подкоманда "sso migrate": up применяет все миграции, down откатывает steps
+// последних (по умолчанию одну), status показывает версию схемы и неприменённые миграции
+func migrate(ctx context.Context, db *sqlx.DB, args []string) error {
+	if len(args) == 0 {
+		return ErrUsage
+	}
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		slog.Info("migrations applied", "count", applied)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid steps %q\n%w", args[1], ErrUsage)
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		slog.Info("migrations reverted", "count", reverted)
+	case "status":
+		current, pending, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		slog.Info("schema version", "version", current, "pending", len(pending))
+		for _, migration := range pending {
+			slog.Info("pending migration", "version", migration.Version, "name", migration.Name)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%w", args[0], ErrUsage)
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockId — ключ advisory lock, чтобы два экземпляра сервиса не применяли миграции одновременно
+const lockId = 7_130_208_001
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration — одна версия схемы: sql/<версия>_<название>.up.sql и парный .down.sql
+type Migration struct {
+	Version int64
+	Name    string
+	up      string
+	down    string
+}
+
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+}
+
+func New(db *sqlx.DB) (*Migrator, error) {
+	migrations, err := load(files)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration, len(entries)/2)
+	for _, entry := range entries {
+		parts := fileName.FindStringSubmatch(entry.Name())
+		if parts == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		version, _ := strconv.ParseInt(parts[1], 10, 64)
+		content, err := fs.ReadFile(fsys, "sql/"+entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %q: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: parts[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != parts[2] {
+			return nil, fmt.Errorf("migration %d has different names: %q and %q", version, migration.Name, parts[2])
+		}
+		if parts[3] == "up" {
+			migration.up = string(content)
+		} else {
+			migration.down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.up == "" || migration.down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up применяет все ещё не применённые миграции, каждую в своей транзакции.
+// Возвращает количество применённых
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	const op = "Migrator.Up"
+	log := slog.With(slog.String("op", op))
+
+	applied := 0
+	err := m.locked(ctx, func(conn *sqlx.Conn) error {
+		current, err := version(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if migration.Version <= current {
+				continue
+			}
+			log.Info("applying migration", slog.Int64("version", migration.Version), slog.String("name", migration.Name))
+			err := inTx(ctx, conn, func(tx *sqlx.Tx) error {
+				if _, err := tx.ExecContext(ctx, migration.up); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", migration.Version, migration.Name)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			applied++
+		}
+		return nil
+	})
+	if err != nil {
+		return applied, fmt.Errorf("%s: %w", op, err)
+	}
+	return applied, nil
+}
+
+// Down откатывает steps последних применённых миграций. Возвращает количество откаченных
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	const op = "Migrator.Down"
+	log := slog.With(slog.String("op", op))
+
+	reverted := 0
+	err := m.locked(ctx, func(conn *sqlx.Conn) error {
+		for ; reverted < steps; reverted++ {
+			current, err := version(ctx, conn)
+			if err != nil {
+				return err
+			}
+			if current == 0 {
+				return nil
+			}
+			migration, ok := m.find(current)
+			if !ok {
+				return fmt.Errorf("applied migration %d is unknown to this build", current)
+			}
+			log.Info("reverting migration", slog.Int64("version", migration.Version), slog.String("name", migration.Name))
+			err = inTx(ctx, conn, func(tx *sqlx.Tx) error {
+				if _, err := tx.ExecContext(ctx, migration.down); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return reverted, fmt.Errorf("%s: %w", op, err)
+	}
+	return reverted, nil
+}
+
+// Status — текущая версия схемы и миграции, которые ещё не применены
+func (m *Migrator) Status(ctx context.Context) (int64, []Migration, error) {
+	const op = "Migrator.Status"
+
+	var current int64
+	err := m.locked(ctx, func(conn *sqlx.Conn) error {
+		var err error
+		current, err = version(ctx, conn)
+		return err
+	})
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if migration.Version > current {
+			pending = append(pending, migration)
+		}
+	}
+	return current, pending, nil
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// locked выполняет f на отдельном соединении под advisory lock и создаёт таблицу версий, если её нет
+func (m *Migrator) locked(ctx context.Context, f func(conn *sqlx.Conn) error) error {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockId); err != nil {
+		return fmt.Errorf("failed to acquire migrations lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockId); err != nil {
+			slog.Error("failed to release migrations lock", "err", err)
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    BIGINT      PRIMARY KEY,
+			name       TEXT        NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	return f(conn)
+}
+
+func version(ctx context.Context, conn *sqlx.Conn) (int64, error) {
+	var current sql.NullInt64
+	if err := conn.GetContext(ctx, &current, "SELECT max(version) FROM schema_migrations"); err != nil {
+		return 0, fmt.Errorf("failed to get schema version: %w", err)
+	}
+	return current.Int64, nil
+}
+
+func inTx(ctx context.Context, conn *sqlx.Conn, f func(tx *sqlx.Tx) error) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := f(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
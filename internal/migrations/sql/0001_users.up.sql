@@ -0,0 +1,19 @@
+-- Схема, под которую написан сервис изначально. IF NOT EXISTS - чтобы миграции
+-- можно было применить к базе, созданной до их появления
+CREATE TABLE IF NOT EXISTS roles (
+    id   BIGINT PRIMARY KEY,
+    name TEXT   NOT NULL UNIQUE
+);
+
+INSERT INTO roles (id, name) VALUES (1, 'buyer'), (2, 'admin')
+ON CONFLICT (id) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS users (
+    id                BIGSERIAL   PRIMARY KEY,
+    role_id           BIGINT      NOT NULL REFERENCES roles (id),
+    login             TEXT        NOT NULL UNIQUE,
+    password          BYTEA       NOT NULL,
+    creation_datetime TIMESTAMPTZ NOT NULL DEFAULT now(),
+    update_datetime   TIMESTAMPTZ,
+    is_archived       BOOLEAN     NOT NULL DEFAULT FALSE
+);
@@ -0,0 +1,26 @@
+-- Ротация refresh токенов и отзыв сессий
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tokens_not_before TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id                TEXT        PRIMARY KEY,
+    family_id         TEXT        NOT NULL,
+    user_id           BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    -- пустая строка - вход напрямую через /auth, без OAuth клиента
+    client_id         TEXT        NOT NULL DEFAULT '',
+    scope             TEXT        NOT NULL DEFAULT '',
+    token_hash        TEXT        NOT NULL,
+    expires_at        TIMESTAMPTZ NOT NULL,
+    creation_datetime TIMESTAMPTZ NOT NULL DEFAULT now(),
+    used_at           TIMESTAMPTZ,
+    revoked_at        TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS refresh_tokens_user_id_idx ON refresh_tokens (user_id);
+
+CREATE TABLE IF NOT EXISTS revoked_access_tokens (
+    jti        TEXT        PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS revoked_access_tokens_expires_at_idx ON revoked_access_tokens (expires_at);
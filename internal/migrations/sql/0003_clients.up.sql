@@ -0,0 +1,16 @@
+-- OAuth клиенты. TTL в секундах, 0 - значение по умолчанию сервиса
+CREATE TABLE IF NOT EXISTS clients (
+    id                TEXT        PRIMARY KEY,
+    name              TEXT        NOT NULL,
+    secret_hash       BYTEA,
+    public_key        TEXT        NOT NULL DEFAULT '',
+    redirect_uris     TEXT[]      NOT NULL DEFAULT '{}',
+    grant_types       TEXT[]      NOT NULL DEFAULT '{}',
+    scopes            TEXT[]      NOT NULL DEFAULT '{}',
+    access_token_ttl  BIGINT      NOT NULL DEFAULT 0,
+    refresh_token_ttl BIGINT      NOT NULL DEFAULT 0,
+    audience          TEXT        NOT NULL DEFAULT '',
+    is_disabled       BOOLEAN     NOT NULL DEFAULT FALSE,
+    creation_datetime TIMESTAMPTZ NOT NULL DEFAULT now(),
+    update_datetime   TIMESTAMPTZ
+);
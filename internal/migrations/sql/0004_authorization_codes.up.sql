@@ -0,0 +1,14 @@
+-- Одноразовые коды authorization code flow, хранится только хеш кода
+CREATE TABLE IF NOT EXISTS authorization_codes (
+    code_hash             TEXT        PRIMARY KEY,
+    client_id             TEXT        NOT NULL REFERENCES clients (id) ON DELETE CASCADE,
+    user_id               BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    redirect_uri          TEXT        NOT NULL,
+    scope                 TEXT        NOT NULL DEFAULT '',
+    nonce                 TEXT        NOT NULL DEFAULT '',
+    code_challenge        TEXT        NOT NULL,
+    code_challenge_method TEXT        NOT NULL,
+    auth_datetime         TIMESTAMPTZ NOT NULL,
+    expires_at            TIMESTAMPTZ NOT NULL,
+    used_at               TIMESTAMPTZ
+);
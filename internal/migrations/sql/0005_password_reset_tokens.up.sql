@@ -0,0 +1,10 @@
+-- Одноразовые токены сброса пароля, хранится только хеш токена
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    token_hash        TEXT        PRIMARY KEY,
+    user_id           BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    expires_at        TIMESTAMPTZ NOT NULL,
+    creation_datetime TIMESTAMPTZ NOT NULL DEFAULT now(),
+    used_at           TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS password_reset_tokens_user_id_idx ON password_reset_tokens (user_id);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS contact_verifications;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS phone_verified,
+    DROP COLUMN IF EXISTS phone,
+    DROP COLUMN IF EXISTS email_verified,
+    DROP COLUMN IF EXISTS email;
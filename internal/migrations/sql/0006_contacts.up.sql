@@ -0,0 +1,20 @@
+-- Контакты пользователя и их подтверждение
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS email          TEXT    NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS phone          TEXT    NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS phone_verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- логин с почтой - это и есть почта пользователя, но она не подтверждена
+UPDATE users SET email = login WHERE email = '' AND login LIKE '%_@_%';
+
+-- действующий код подтверждения, не больше одного на канал
+CREATE TABLE IF NOT EXISTS contact_verifications (
+    user_id           BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    channel           TEXT        NOT NULL,
+    code_hash         TEXT        NOT NULL,
+    attempts          INT         NOT NULL DEFAULT 0,
+    expires_at        TIMESTAMPTZ NOT NULL,
+    creation_datetime TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, channel)
+);
@@ -0,0 +1,14 @@
+-- двухфакторная аутентификация по TOTP
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS totp_secret    TEXT,
+    ADD COLUMN IF NOT EXISTS totp_enabled   BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS totp_last_step BIGINT  NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS mfa_attempts   INT     NOT NULL DEFAULT 0;
+
+-- одноразовые резервные коды, храним только хеши
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    user_id   BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash TEXT        NOT NULL,
+    used_at   TIMESTAMPTZ,
+    PRIMARY KEY (user_id, code_hash)
+);
@@ -0,0 +1,22 @@
+-- ключи WebAuthn (passkeys)
+CREATE TABLE IF NOT EXISTS passkeys (
+    id                 TEXT        PRIMARY KEY,
+    user_id            BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name               TEXT        NOT NULL,
+    public_key         BYTEA       NOT NULL,
+    sign_count         BIGINT      NOT NULL DEFAULT 0,
+    transports         TEXT[]      NOT NULL DEFAULT '{}',
+    creation_datetime  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_datetime TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS passkeys_user_id_idx ON passkeys (user_id);
+
+-- выданные challenge, одноразовые
+CREATE TABLE IF NOT EXISTS passkey_challenges (
+    challenge_hash    TEXT        PRIMARY KEY,
+    user_id           BIGINT      REFERENCES users (id) ON DELETE CASCADE,
+    ceremony          TEXT        NOT NULL,
+    expires_at        TIMESTAMPTZ NOT NULL,
+    creation_datetime TIMESTAMPTZ NOT NULL DEFAULT now()
+);
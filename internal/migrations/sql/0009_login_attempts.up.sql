@@ -0,0 +1,8 @@
+-- счётчики неудачных попыток входа по логину и IP, общие для всех реплик
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key                   TEXT        PRIMARY KEY,
+    failures              INT         NOT NULL,
+    last_failure_datetime TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS login_attempts_last_failure_idx ON login_attempts (last_failure_datetime);
@@ -0,0 +1,7 @@
+-- отозванные сессии (семейства refresh токенов): их access токены с этим sid недействительны
+CREATE TABLE IF NOT EXISTS revoked_sessions (
+    session_id TEXT        PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS revoked_sessions_expires_at_idx ON revoked_sessions (expires_at);
@@ -0,0 +1,62 @@
+package authcode
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/phenirain/sso/internal/domain"
+)
+
+type AuthorizationCodeRepository struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *AuthorizationCodeRepository {
+	return &AuthorizationCodeRepository{db: db}
+}
+
+func (r *AuthorizationCodeRepository) CreateAuthorizationCode(ctx context.Context, code *domain.AuthorizationCode) error {
+	const op = "AuthorizationCode.CreateAuthorizationCode"
+	const query = `
+		INSERT INTO authorization_codes (code_hash, client_id, user_id, redirect_uri, scope, nonce,
+			code_challenge, code_challenge_method, auth_datetime, expires_at)
+		VALUES (:code_hash, :client_id, :user_id, :redirect_uri, :scope, :nonce,
+			:code_challenge, :code_challenge_method, :auth_datetime, :expires_at)
+	`
+
+	if _, err := r.db.NamedExecContext(ctx, query, code); err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// UseAuthorizationCode атомарно помечает код использованным и возвращает его.
+// nil - кода нет или он уже был использован
+func (r *AuthorizationCodeRepository) UseAuthorizationCode(ctx context.Context, codeHash string) (*domain.AuthorizationCode, error) {
+	const op = "AuthorizationCode.UseAuthorizationCode"
+	const query = `
+		UPDATE authorization_codes SET used_at = now()
+		WHERE code_hash = $1 AND used_at IS NULL
+		RETURNING *
+	`
+	log := slog.With(
+		slog.String("op", op),
+	)
+	log.Info("attempting to use authorization code")
+
+	var code domain.AuthorizationCode
+	err := r.db.GetContext(ctx, &code, query, codeHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		log.Error("something went wrong", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &code, nil
+}
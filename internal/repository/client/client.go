@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/phenirain/sso/internal/domain"
+)
+
+type ClientRepository struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *ClientRepository {
+	return &ClientRepository{db: db}
+}
+
+func (r *ClientRepository) GetClient(ctx context.Context, id string) (*domain.Client, error) {
+	const op = "Client.GetClient"
+	log := slog.With(
+		slog.String("op", op),
+	)
+	log.Info("attempting to get client")
+
+	var client domain.Client
+	err := r.db.GetContext(ctx, &client, "SELECT * FROM clients WHERE id = $1", id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		log.Error("something went wrong", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &client, nil
+}
+
+func (r *ClientRepository) GetClients(ctx context.Context) ([]domain.Client, error) {
+	const op = "Client.GetClients"
+	log := slog.With(
+		slog.String("op", op),
+	)
+	log.Info("attempting to get clients")
+
+	clients := []domain.Client{}
+	err := r.db.SelectContext(ctx, &clients, "SELECT * FROM clients ORDER BY creation_datetime")
+	if err != nil {
+		log.Error("something went wrong", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return clients, nil
+}
+
+func (r *ClientRepository) CreateClient(ctx context.Context, client *domain.Client) error {
+	const op = "Client.CreateClient"
+	const query = `
+		INSERT INTO clients (id, name, secret_hash, public_key, redirect_uris, grant_types, scopes,
+			access_token_ttl, refresh_token_ttl, audience, is_disabled, creation_datetime, update_datetime)
+		VALUES (:id, :name, :secret_hash, :public_key, :redirect_uris, :grant_types, :scopes,
+			:access_token_ttl, :refresh_token_ttl, :audience, :is_disabled, :creation_datetime, :update_datetime)
+	`
+
+	if _, err := r.db.NamedExecContext(ctx, query, client); err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *ClientRepository) UpdateClient(ctx context.Context, client *domain.Client) error {
+	const op = "Client.UpdateClient"
+	const query = `
+		UPDATE clients SET name = :name, secret_hash = :secret_hash, public_key = :public_key, redirect_uris = :redirect_uris,
+			grant_types = :grant_types, scopes = :scopes, access_token_ttl = :access_token_ttl,
+			refresh_token_ttl = :refresh_token_ttl, audience = :audience, is_disabled = :is_disabled,
+			update_datetime = :update_datetime
+		WHERE id = :id
+	`
+
+	if _, err := r.db.NamedExecContext(ctx, query, client); err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package denylist
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type DenylistRepository struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *DenylistRepository {
+	return &DenylistRepository{db: db}
+}
+
+func (d *DenylistRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	const op = "Denylist.IsAccessTokenRevoked"
+
+	var revoked bool
+	err := d.db.GetContext(ctx, &revoked, "SELECT EXISTS(SELECT 1 FROM revoked_access_tokens WHERE jti = $1)", jti)
+	if err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return revoked, nil
+}
+
+// RevokeAccessToken добавляет токен в denylist. Запись нужна только до expiresAt,
+// заодно вычищаем уже истёкшие
+func (d *DenylistRepository) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	const op = "Denylist.RevokeAccessToken"
+	const query = `
+		INSERT INTO revoked_access_tokens (jti, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING
+	`
+
+	if _, err := d.db.ExecContext(ctx, query, jti, expiresAt); err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := d.db.ExecContext(ctx, "DELETE FROM revoked_access_tokens WHERE expires_at < now()"); err != nil {
+		slog.Warn("failed to purge expired access tokens", slog.String("op", op), "err", err)
+	}
+	return nil
+}
+
+func (d *DenylistRepository) IsSessionRevoked(ctx context.Context, sessionId string) (bool, error) {
+	const op = "Denylist.IsSessionRevoked"
+
+	var revoked bool
+	err := d.db.GetContext(ctx, &revoked, "SELECT EXISTS(SELECT 1 FROM revoked_sessions WHERE session_id = $1)", sessionId)
+	if err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return revoked, nil
+}
+
+// RevokeSession отзывает access токены сессии. Запись нужна, пока они не истекли
+func (d *DenylistRepository) RevokeSession(ctx context.Context, sessionId string, expiresAt time.Time) error {
+	const op = "Denylist.RevokeSession"
+	const query = `
+		INSERT INTO revoked_sessions (session_id, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (session_id) DO UPDATE SET expires_at = GREATEST(revoked_sessions.expires_at, EXCLUDED.expires_at)
+	`
+
+	if _, err := d.db.ExecContext(ctx, query, sessionId, expiresAt); err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := d.db.ExecContext(ctx, "DELETE FROM revoked_sessions WHERE expires_at < now()"); err != nil {
+		slog.Warn("failed to purge expired sessions", slog.String("op", op), "err", err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package loginattempt
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/phenirain/sso/internal/domain"
+)
+
+// LoginAttemptRepository — счётчики в Postgres, общие для всех реплик
+type LoginAttemptRepository struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+func (r *LoginAttemptRepository) GetAttempts(ctx context.Context, key string) (*domain.LoginAttempts, error) {
+	const op = "LoginAttempt.GetAttempts"
+
+	var attempts domain.LoginAttempts
+	err := r.db.GetContext(ctx, &attempts, "SELECT * FROM login_attempts WHERE key = $1", key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &attempts, nil
+}
+
+// SaveAttempts записывает next, только если счётчик в базе всё ещё равен previous
+// (nil - счётчика нет). false - его успел изменить параллельный запрос
+func (r *LoginAttemptRepository) SaveAttempts(ctx context.Context, previous, next *domain.LoginAttempts) (bool, error) {
+	const op = "LoginAttempt.SaveAttempts"
+
+	var result sql.Result
+	var err error
+	if previous == nil {
+		result, err = r.db.ExecContext(ctx, `
+			INSERT INTO login_attempts (key, failures, last_failure_datetime)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (key) DO NOTHING
+		`, next.Key, next.Failures, next.LastFailureTime)
+	} else {
+		result, err = r.db.ExecContext(ctx, `
+			UPDATE login_attempts SET failures = $2, last_failure_datetime = $3
+			WHERE key = $1 AND failures = $4 AND last_failure_datetime = $5
+		`, next.Key, next.Failures, next.LastFailureTime, previous.Failures, previous.LastFailureTime)
+	}
+	if err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return affected == 1, nil
+}
+
+// ReleaseAttempt уменьшает счётчик на одну попытку
+func (r *LoginAttemptRepository) ReleaseAttempt(ctx context.Context, key string) error {
+	const op = "LoginAttempt.ReleaseAttempt"
+
+	_, err := r.db.ExecContext(ctx, "UPDATE login_attempts SET failures = failures - 1 WHERE key = $1 AND failures > 0", key)
+	if err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *LoginAttemptRepository) DeleteAttempts(ctx context.Context, key string) error {
+	const op = "LoginAttempt.DeleteAttempts"
+
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM login_attempts WHERE key = $1", key); err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// DeleteExpired удаляет счётчики, последняя попытка в которых была раньше before
+func (r *LoginAttemptRepository) DeleteExpired(ctx context.Context, before time.Time) error {
+	const op = "LoginAttempt.DeleteExpired"
+
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM login_attempts WHERE last_failure_datetime < $1", before); err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
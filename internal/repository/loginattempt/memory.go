@@ -0,0 +1,80 @@
+package loginattempt
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/phenirain/sso/internal/domain"
+)
+
+// MemoryRepository — счётчики в памяти процесса. Подходит только для одной реплики:
+// у каждой реплики свои счётчики, и после перезапуска они обнуляются
+type MemoryRepository struct {
+	mu       sync.Mutex
+	attempts map[string]domain.LoginAttempts
+}
+
+func NewMemory() *MemoryRepository {
+	return &MemoryRepository{
+		attempts: make(map[string]domain.LoginAttempts),
+	}
+}
+
+func (r *MemoryRepository) GetAttempts(ctx context.Context, key string) (*domain.LoginAttempts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempts, ok := r.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	return &attempts, nil
+}
+
+// SaveAttempts записывает next, только если счётчик всё ещё равен previous, как и в Postgres
+func (r *MemoryRepository) SaveAttempts(ctx context.Context, previous, next *domain.LoginAttempts) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.attempts[next.Key]
+	if previous == nil && ok {
+		return false, nil
+	}
+	if previous != nil && (!ok || current.Failures != previous.Failures || !current.LastFailureTime.Equal(previous.LastFailureTime)) {
+		return false, nil
+	}
+	r.attempts[next.Key] = *next
+	return true, nil
+}
+
+func (r *MemoryRepository) ReleaseAttempt(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if attempts, ok := r.attempts[key]; ok && attempts.Failures > 0 {
+		attempts.Failures--
+		r.attempts[key] = attempts
+	}
+	return nil
+}
+
+func (r *MemoryRepository) DeleteAttempts(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attempts, key)
+	return nil
+}
+
+func (r *MemoryRepository) DeleteExpired(ctx context.Context, before time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, attempts := range r.attempts {
+		if attempts.LastFailureTime.Before(before) {
+			delete(r.attempts, key)
+		}
+	}
+	return nil
+}
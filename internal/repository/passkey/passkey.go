@@ -0,0 +1,132 @@
+package passkey
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/phenirain/sso/internal/domain"
+)
+
+type PasskeyRepository struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *PasskeyRepository {
+	return &PasskeyRepository{db: db}
+}
+
+func (r *PasskeyRepository) CreatePasskey(ctx context.Context, passkey *domain.Passkey) error {
+	const op = "Passkey.CreatePasskey"
+	const query = `
+		INSERT INTO passkeys (id, user_id, name, public_key, sign_count, transports, creation_datetime)
+		VALUES (:id, :user_id, :name, :public_key, :sign_count, :transports, :creation_datetime)
+	`
+
+	if _, err := r.db.NamedExecContext(ctx, query, passkey); err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PasskeyRepository) GetPasskey(ctx context.Context, id string) (*domain.Passkey, error) {
+	const op = "Passkey.GetPasskey"
+
+	var passkey domain.Passkey
+	err := r.db.GetContext(ctx, &passkey, "SELECT * FROM passkeys WHERE id = $1", id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &passkey, nil
+}
+
+func (r *PasskeyRepository) GetUserPasskeys(ctx context.Context, userId int64) ([]domain.Passkey, error) {
+	const op = "Passkey.GetUserPasskeys"
+
+	passkeys := []domain.Passkey{}
+	err := r.db.SelectContext(ctx, &passkeys, "SELECT * FROM passkeys WHERE user_id = $1 ORDER BY creation_datetime", userId)
+	if err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return passkeys, nil
+}
+
+// UsePasskey сохраняет новый счётчик подписей. Условие на старый счётчик защищает
+// от двух параллельных входов с одной и той же подписью
+func (r *PasskeyRepository) UsePasskey(ctx context.Context, passkey *domain.Passkey, previousSignCount int64) (bool, error) {
+	const op = "Passkey.UsePasskey"
+	const query = `
+		UPDATE passkeys SET sign_count = $2, last_used_datetime = $3
+		WHERE id = $1 AND sign_count = $4
+	`
+
+	result, err := r.db.ExecContext(ctx, query, passkey.Id, passkey.SignCount, passkey.LastUsedTime, previousSignCount)
+	if err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return affected == 1, nil
+}
+
+// DeletePasskey удаляет ключ пользователя. false - у пользователя нет такого ключа
+func (r *PasskeyRepository) DeletePasskey(ctx context.Context, userId int64, id string) (bool, error) {
+	const op = "Passkey.DeletePasskey"
+
+	result, err := r.db.ExecContext(ctx, "DELETE FROM passkeys WHERE id = $1 AND user_id = $2", id, userId)
+	if err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return affected == 1, nil
+}
+
+// CreateChallenge сохраняет выданный challenge, заодно вычищаем истёкшие
+func (r *PasskeyRepository) CreateChallenge(ctx context.Context, challenge *domain.PasskeyChallenge) error {
+	const op = "Passkey.CreateChallenge"
+	const query = `
+		INSERT INTO passkey_challenges (challenge_hash, user_id, ceremony, expires_at, creation_datetime)
+		VALUES (:challenge_hash, :user_id, :ceremony, :expires_at, :creation_datetime)
+	`
+
+	if _, err := r.db.NamedExecContext(ctx, query, challenge); err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM passkey_challenges WHERE expires_at < now()"); err != nil {
+		slog.Warn("failed to purge expired passkey challenges", slog.String("op", op), "err", err)
+	}
+	return nil
+}
+
+// UseChallenge атомарно удаляет challenge и возвращает его. nil - его нет или он уже использован
+func (r *PasskeyRepository) UseChallenge(ctx context.Context, challengeHash string) (*domain.PasskeyChallenge, error) {
+	const op = "Passkey.UseChallenge"
+
+	var challenge domain.PasskeyChallenge
+	err := r.db.GetContext(ctx, &challenge, "DELETE FROM passkey_challenges WHERE challenge_hash = $1 RETURNING *", challengeHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &challenge, nil
+}
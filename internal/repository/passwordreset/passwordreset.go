@@ -0,0 +1,76 @@
+package passwordreset
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/phenirain/sso/internal/domain"
+)
+
+type PasswordResetRepository struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *PasswordResetRepository {
+	return &PasswordResetRepository{db: db}
+}
+
+func (r *PasswordResetRepository) CreateResetToken(ctx context.Context, token *domain.PasswordResetToken) error {
+	const op = "PasswordReset.CreateResetToken"
+	const query = `
+		INSERT INTO password_reset_tokens (token_hash, user_id, expires_at, creation_datetime)
+		VALUES (:token_hash, :user_id, :expires_at, :creation_datetime)
+	`
+
+	if _, err := r.db.NamedExecContext(ctx, query, token); err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// UseResetToken атомарно помечает токен использованным и возвращает его.
+// nil - токена нет или он уже был использован
+func (r *PasswordResetRepository) UseResetToken(ctx context.Context, tokenHash string) (*domain.PasswordResetToken, error) {
+	const op = "PasswordReset.UseResetToken"
+	const query = `
+		UPDATE password_reset_tokens SET used_at = now()
+		WHERE token_hash = $1 AND used_at IS NULL
+		RETURNING *
+	`
+	log := slog.With(
+		slog.String("op", op),
+	)
+	log.Info("attempting to use password reset token")
+
+	var token domain.PasswordResetToken
+	err := r.db.GetContext(ctx, &token, query, tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		log.Error("something went wrong", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &token, nil
+}
+
+// InvalidateUserTokens гасит все неиспользованные токены пользователя: после сброса пароля
+// ссылки из предыдущих писем больше не работают
+func (r *PasswordResetRepository) InvalidateUserTokens(ctx context.Context, userId int64) error {
+	const op = "PasswordReset.InvalidateUserTokens"
+	const query = `
+		UPDATE password_reset_tokens SET used_at = now()
+		WHERE user_id = $1 AND used_at IS NULL
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userId); err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
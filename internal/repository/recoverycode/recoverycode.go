@@ -0,0 +1,66 @@
+package recoverycode
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/phenirain/sso/pkg/database"
+)
+
+type RecoveryCodeRepository struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *RecoveryCodeRepository {
+	return &RecoveryCodeRepository{db: db}
+}
+
+// ReplaceRecoveryCodes заменяет все резервные коды пользователя новыми
+func (r *RecoveryCodeRepository) ReplaceRecoveryCodes(ctx context.Context, userId int64, hashes []string) error {
+	const op = "RecoveryCode.ReplaceRecoveryCodes"
+
+	_, err := database.WithUserTransaction(r.db, ctx, func(tx *sqlx.Tx) (any, error) {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userId); err != nil {
+			return nil, err
+		}
+		for _, hash := range hashes {
+			if _, err := tx.ExecContext(ctx, "INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)", userId, hash); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// UseRecoveryCode атомарно погашает код. false - кода нет или он уже был использован
+func (r *RecoveryCodeRepository) UseRecoveryCode(ctx context.Context, userId int64, hash string) (bool, error) {
+	const op = "RecoveryCode.UseRecoveryCode"
+
+	result, err := r.db.ExecContext(ctx, "UPDATE recovery_codes SET used_at = now() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL", userId, hash)
+	if err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return affected == 1, nil
+}
+
+func (r *RecoveryCodeRepository) DeleteRecoveryCodes(ctx context.Context, userId int64) error {
+	const op = "RecoveryCode.DeleteRecoveryCodes"
+
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userId); err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package refreshtoken
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/phenirain/sso/internal/domain"
+)
+
+type RefreshTokenRepository struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+func (r *RefreshTokenRepository) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	const op = "RefreshToken.CreateRefreshToken"
+	const query = `
+		INSERT INTO refresh_tokens (id, family_id, user_id, client_id, scope, token_hash, expires_at, creation_datetime)
+		VALUES (:id, :family_id, :user_id, :client_id, :scope, :token_hash, :expires_at, :creation_datetime)
+	`
+
+	if _, err := r.db.NamedExecContext(ctx, query, token); err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *RefreshTokenRepository) GetRefreshToken(ctx context.Context, id string) (*domain.RefreshToken, error) {
+	const op = "RefreshToken.GetRefreshToken"
+	log := slog.With(
+		slog.String("op", op),
+	)
+	log.Info("attempting to get refresh token")
+
+	var token domain.RefreshToken
+	err := r.db.GetContext(ctx, &token, "SELECT * FROM refresh_tokens WHERE id = $1", id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		log.Error("something went wrong", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &token, nil
+}
+
+// MarkRefreshTokenUsed помечает токен использованным. Возвращает false,
+// если токен уже был использован или отозван (например, параллельным запросом)
+func (r *RefreshTokenRepository) MarkRefreshTokenUsed(ctx context.Context, id string) (bool, error) {
+	const op = "RefreshToken.MarkRefreshTokenUsed"
+	const query = `
+		UPDATE refresh_tokens SET used_at = now()
+		WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return affected == 1, nil
+}
+
+// RevokeFamily отзывает все токены сессии
+func (r *RefreshTokenRepository) RevokeFamily(ctx context.Context, familyId string) error {
+	const op = "RefreshToken.RevokeFamily"
+	const query = `
+		UPDATE refresh_tokens SET revoked_at = now()
+		WHERE family_id = $1 AND revoked_at IS NULL
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, familyId); err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// RevokeUserTokens отзывает все сессии пользователя
+func (r *RefreshTokenRepository) RevokeUserTokens(ctx context.Context, userId int64) error {
+	const op = "RefreshToken.RevokeUserTokens"
+	const query = `
+		UPDATE refresh_tokens SET revoked_at = now()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userId); err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
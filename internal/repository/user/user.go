@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/phenirain/sso/internal/domain"
@@ -16,6 +17,9 @@ type UserRepository struct {
 	db  *sqlx.DB
 }
 
+// selectUser — пользователь вместе с признаком, есть ли у него ключи WebAuthn
+const selectUser = "SELECT users.*, EXISTS(SELECT 1 FROM passkeys WHERE passkeys.user_id = users.id) AS has_passkeys FROM users"
+
 func New(db *sqlx.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
@@ -28,7 +32,7 @@ func (u *UserRepository) GetUserByLogin(ctx context.Context, login string) (*dom
 	log.Info("attempting to get user")
 
 	var user domain.User
-	err := u.db.Get(&user, "SELECT * FROM users WHERE login = $1", login)
+	err := u.db.Get(&user, selectUser+" WHERE login = $1", login)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -48,7 +52,7 @@ func (u *UserRepository) GetUserWithId(ctx context.Context, uid int64) (*domain.
 
 	var user domain.User
 
-	err := u.db.Get(&user, "SELECT * FROM users WHERE id = $1", uid)
+	err := u.db.Get(&user, selectUser+" WHERE id = $1", uid)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -61,8 +65,10 @@ func (u *UserRepository) GetUserWithId(ctx context.Context, uid int64) (*domain.
 
 func (u *UserRepository) CreateUser(ctx context.Context, user *domain.User) (int64, error) {
 	const query = `
-		INSERT INTO users (role_id, login, password, creation_datetime, update_datetime, is_archived)
-		VALUES (:role_id, :login, :password, :creation_datetime, :update_datetime, :is_archived)
+		INSERT INTO users (role_id, login, password, creation_datetime, update_datetime, is_archived,
+			email, email_verified, phone, phone_verified)
+		VALUES (:role_id, :login, :password, :creation_datetime, :update_datetime, :is_archived,
+			:email, :email_verified, :phone, :phone_verified)
 		RETURNING id
 	`
 
@@ -91,3 +97,126 @@ func (u *UserRepository) CreateUser(ctx context.Context, user *domain.User) (int
 	return result, nil
 }
 
+// UpdatePassword сохраняет новый пароль. Остальные поля не трогает: пользователь мог быть
+// прочитан до параллельного изменения. Границу токенов только сдвигает вперёд
+func (u *UserRepository) UpdatePassword(ctx context.Context, user *domain.User) error {
+	const op = "User.UpdatePassword"
+	const query = `
+		UPDATE users SET password = :password, update_datetime = :update_datetime,
+			tokens_not_before = GREATEST(tokens_not_before, :tokens_not_before)
+		WHERE id = :id
+	`
+	return u.namedUpdate(ctx, op, query, user)
+}
+
+// UpdateArchiveStatus сохраняет признак архивации и границу токенов при архивации
+func (u *UserRepository) UpdateArchiveStatus(ctx context.Context, user *domain.User) error {
+	const op = "User.UpdateArchiveStatus"
+	const query = `
+		UPDATE users SET is_archived = :is_archived, update_datetime = :update_datetime,
+			tokens_not_before = GREATEST(tokens_not_before, :tokens_not_before)
+		WHERE id = :id
+	`
+	return u.namedUpdate(ctx, op, query, user)
+}
+
+// UpdateContactVerification сохраняет, какие контакты пользователя подтверждены
+func (u *UserRepository) UpdateContactVerification(ctx context.Context, user *domain.User) error {
+	const op = "User.UpdateContactVerification"
+	const query = `
+		UPDATE users SET email_verified = :email_verified, phone_verified = :phone_verified,
+			update_datetime = :update_datetime
+		WHERE id = :id
+	`
+	return u.namedUpdate(ctx, op, query, user)
+}
+
+// UpdateTotp сохраняет секрет TOTP и признак подключения. Последний принятый шаг меняет только
+// UseTotpStep, здесь он обнуляется лишь вместе со сменой секрета
+func (u *UserRepository) UpdateTotp(ctx context.Context, user *domain.User) error {
+	const op = "User.UpdateTotp"
+	const query = `
+		UPDATE users SET totp_secret = :totp_secret, totp_enabled = :totp_enabled,
+			totp_last_step = CASE WHEN totp_secret IS DISTINCT FROM :totp_secret THEN 0 ELSE totp_last_step END
+		WHERE id = :id
+	`
+	return u.namedUpdate(ctx, op, query, user)
+}
+
+func (u *UserRepository) namedUpdate(ctx context.Context, op, query string, user *domain.User) error {
+	_, err := database.WithUserTransaction(u.db, ctx, func(tx *sqlx.Tx) (any, error) {
+		return tx.NamedExecContext(ctx, query, user)
+	})
+	if err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (u *UserRepository) GetTokensNotBefore(ctx context.Context, uid int64) (*time.Time, error) {
+	const op = "User.GetTokensNotBefore"
+
+	var notBefore *time.Time
+	err := u.db.GetContext(ctx, &notBefore, "SELECT tokens_not_before FROM users WHERE id = $1", uid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return notBefore, nil
+}
+
+func (u *UserRepository) SetTokensNotBefore(ctx context.Context, uid int64, notBefore time.Time) error {
+	const op = "User.SetTokensNotBefore"
+
+	_, err := u.db.ExecContext(ctx, "UPDATE users SET tokens_not_before = $2 WHERE id = $1", uid, notBefore)
+	if err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// UseTotpStep запоминает принятый шаг TOTP, если он новее сохранённого.
+// false - код этого шага уже принят параллельным запросом
+func (u *UserRepository) UseTotpStep(ctx context.Context, uid int64, step int64) (bool, error) {
+	const op = "User.UseTotpStep"
+
+	result, err := u.db.ExecContext(ctx, "UPDATE users SET totp_last_step = $2 WHERE id = $1 AND totp_last_step < $2", uid, step)
+	if err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return affected == 1, nil
+}
+
+// AddMfaAttempt атомарно учитывает проверку второго фактора и возвращает число проверок подряд
+func (u *UserRepository) AddMfaAttempt(ctx context.Context, uid int64) (int, error) {
+	const op = "User.AddMfaAttempt"
+
+	var attempts int
+	err := u.db.GetContext(ctx, &attempts, "UPDATE users SET mfa_attempts = mfa_attempts + 1 WHERE id = $1 RETURNING mfa_attempts", uid)
+	if err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return attempts, nil
+}
+
+func (u *UserRepository) ResetMfaAttempts(ctx context.Context, uid int64) error {
+	const op = "User.ResetMfaAttempts"
+
+	_, err := u.db.ExecContext(ctx, "UPDATE users SET mfa_attempts = 0 WHERE id = $1", uid)
+	if err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package verification
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/phenirain/sso/internal/domain"
+)
+
+type VerificationRepository struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *VerificationRepository {
+	return &VerificationRepository{db: db}
+}
+
+// SaveVerification сохраняет код подтверждения. Предыдущий код того же канала перестаёт действовать
+func (r *VerificationRepository) SaveVerification(ctx context.Context, verification *domain.ContactVerification) error {
+	const op = "Verification.SaveVerification"
+	const query = `
+		INSERT INTO contact_verifications (user_id, channel, code_hash, attempts, expires_at, creation_datetime)
+		VALUES (:user_id, :channel, :code_hash, :attempts, :expires_at, :creation_datetime)
+		ON CONFLICT (user_id, channel) DO UPDATE SET code_hash = excluded.code_hash, attempts = excluded.attempts,
+			expires_at = excluded.expires_at, creation_datetime = excluded.creation_datetime
+	`
+
+	if _, err := r.db.NamedExecContext(ctx, query, verification); err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *VerificationRepository) GetVerification(ctx context.Context, userId int64, channel string) (*domain.ContactVerification, error) {
+	const op = "Verification.GetVerification"
+
+	var verification domain.ContactVerification
+	err := r.db.GetContext(ctx, &verification, "SELECT * FROM contact_verifications WHERE user_id = $1 AND channel = $2", userId, channel)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &verification, nil
+}
+
+// AddAttempt учитывает неверно введённый код
+func (r *VerificationRepository) AddAttempt(ctx context.Context, userId int64, channel string) error {
+	const op = "Verification.AddAttempt"
+
+	_, err := r.db.ExecContext(ctx, "UPDATE contact_verifications SET attempts = attempts + 1 WHERE user_id = $1 AND channel = $2", userId, channel)
+	if err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *VerificationRepository) DeleteVerification(ctx context.Context, userId int64, channel string) error {
+	const op = "Verification.DeleteVerification"
+
+	_, err := r.db.ExecContext(ctx, "DELETE FROM contact_verifications WHERE user_id = $1 AND channel = $2", userId, channel)
+	if err != nil {
+		slog.Error("something went wrong", slog.String("op", op), "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
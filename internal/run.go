@@ -2,10 +2,12 @@ package internal
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"time"
@@ -13,14 +15,71 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/phenirain/sso/internal/application"
 	"github.com/phenirain/sso/internal/config"
+	"github.com/phenirain/sso/internal/lib/encryption"
 	"github.com/phenirain/sso/internal/lib/jwt"
+	"github.com/phenirain/sso/internal/lib/notify"
+	"github.com/phenirain/sso/internal/lib/webauthn"
+	"github.com/phenirain/sso/internal/migrations"
+	"github.com/phenirain/sso/internal/repository/authcode"
+	"github.com/phenirain/sso/internal/repository/client"
+	"github.com/phenirain/sso/internal/repository/denylist"
+	"github.com/phenirain/sso/internal/repository/loginattempt"
+	passkeyRepository "github.com/phenirain/sso/internal/repository/passkey"
+	"github.com/phenirain/sso/internal/repository/passwordreset"
+	"github.com/phenirain/sso/internal/repository/recoverycode"
+	"github.com/phenirain/sso/internal/repository/refreshtoken"
 	"github.com/phenirain/sso/internal/repository/user"
+	"github.com/phenirain/sso/internal/repository/verification"
 	"github.com/phenirain/sso/internal/services/auth"
+	clientAdmin "github.com/phenirain/sso/internal/services/client"
+	denylistService "github.com/phenirain/sso/internal/services/denylist"
+	"github.com/phenirain/sso/internal/services/lockout"
+	"github.com/phenirain/sso/internal/services/mfa"
+	"github.com/phenirain/sso/internal/services/oauth"
+	passkeyService "github.com/phenirain/sso/internal/services/passkey"
+	"github.com/phenirain/sso/internal/services/password"
+	verificationService "github.com/phenirain/sso/internal/services/verification"
 	"github.com/phenirain/sso/pkg/database"
 	"github.com/phenirain/sso/pkg/logger"
 	"golang.org/x/sync/errgroup"
 )
 
+const (
+	accessTokenTTL  = time.Minute * 60
+	refreshTokenTTL = time.Hour * 24 * 30
+	// время жизни ссылки сброса пароля, если не задано в конфиге
+	passwordResetTTL = time.Minute * 30
+	// время жизни кодов подтверждения, если не задано в конфиге
+	emailVerificationTTL = time.Hour * 24
+	phoneVerificationTTL = time.Minute * 10
+	// пауза между повторными отправками кода, если не задана в конфиге
+	verificationResendInterval = time.Minute
+	// название сервиса в приложении-аутентификаторе, если не задано в конфиге
+	defaultMfaIssuer = "SSO"
+	// название сервиса в окне выбора passkey, если не задано в конфиге
+	defaultWebAuthnRpName = "SSO"
+	// через сколько без неудач обнуляется счётчик попыток входа, если не задано в конфиге
+	defaultLockoutWindow = time.Hour * 24
+)
+
+// Защита входа от перебора, если не задана в конфиге
+var (
+	defaultLoginLockout = lockout.Policy{
+		FreeAttempts:    3,
+		MaxFailures:     10,
+		BaseDelay:       time.Second,
+		LockoutDuration: time.Minute * 15,
+		MaxLockout:      time.Hour * 24,
+	}
+	defaultIpLockout = lockout.Policy{
+		FreeAttempts:    20,
+		MaxFailures:     100,
+		BaseDelay:       time.Second,
+		LockoutDuration: time.Minute * 15,
+		MaxLockout:      time.Hour * 24,
+	}
+)
+
 func Run(cfg *config.Config) error {
 
 	if err := logger.Setup(cfg.Env); err != nil {
@@ -29,6 +88,12 @@ func Run(cfg *config.Config) error {
 
 	db := database.MustInitDb(cfg.ConnectionString)
 
+	if cfg.AutoMigrate {
+		if err := migrateUp(context.Background(), db); err != nil {
+			return err
+		}
+	}
+
 	g, ctx := errgroup.WithContext(context.Background())
 	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
 	defer stop()
@@ -43,12 +108,45 @@ func Run(cfg *config.Config) error {
 	return nil
 }
 
+func migrateUp(ctx context.Context, db *sqlx.DB) error {
+	migrator, err := migrations.New(db)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+	slog.Info("database schema is up to date", "applied", applied)
+	return nil
+}
+
 func startServers(ctx context.Context, g *errgroup.Group, db *sqlx.DB, cfg *config.Config) {
 	usersRepository := user.New(db)
-	jwtLib := jwt.NewJwtLib(time.Minute*60, []byte(cfg.Secret))
-	authService := auth.New(usersRepository, jwtLib)
+	refreshTokensRepository := refreshtoken.New(db)
+	denylistRepository := denylist.New(db)
+	clientsRepository := client.New(db)
+	codesRepository := authcode.New(db)
+	jwtLib := jwt.NewJwtLib(accessTokenTTL, mustLoadKeySet(cfg, refreshTokenTTL), cfg.OIDC.Issuer)
+	if !jwtLib.SupportsIdTokens() {
+		slog.Warn("текущий ключ подписи HMAC: OpenID Connect выключен, для ID токенов нужен ключ RS256, ES256 или EdDSA")
+	}
+	tokenDenylist := denylistService.New(usersRepository, denylistRepository, time.Second*30)
+	notifier := mustInitNotifier(cfg.Notifier)
+	loginLockout := newLockout(cfg.Lockout, db)
+	contactVerifier := newVerification(cfg.Verification, usersRepository, verification.New(db), loginLockout, notifier)
+	authService := auth.New(usersRepository, refreshTokensRepository, clientsRepository, tokenDenylist, contactVerifier, loginLockout, jwtLib, refreshTokenTTL, mustRequireVerified(cfg.Verification))
+	passkeys := newPasskey(cfg, passkeyRepository.New(db), usersRepository, authService)
+	mfaService := newMfa(cfg.Mfa, usersRepository, recoverycode.New(db), authService, jwtLib, tokenDenylist, passkeys, loginLockout)
+	oauthService := oauth.New(authService, mfaService, usersRepository, clientsRepository, codesRepository, jwtLib, tokenDenylist, accessTokenTTL)
+	clientService := clientAdmin.New(clientsRepository, refreshTokenTTL)
+	resetTTL := cfg.PasswordReset.TTL
+	if resetTTL == 0 {
+		resetTTL = passwordResetTTL
+	}
+	passwordService := password.New(usersRepository, passwordreset.New(db), authService, loginLockout, notifier, cfg.PasswordReset.Url, resetTTL)
 
-	httpServer := application.SetupHTTPServer(cfg, authService, jwtLib)
+	httpServer := application.SetupHTTPServer(cfg, authService, oauthService, clientService, passwordService, contactVerifier, mfaService, passkeys, loginLockout, authService, jwtLib, tokenDenylist)
 
 	server := &http.Server{
 		Addr:              fmt.Sprintf(":%d", cfg.HTTP.Port),
@@ -59,6 +157,179 @@ func startServers(ctx context.Context, g *errgroup.Group, db *sqlx.DB, cfg *conf
 	startGroup(ctx, g, "http", fmt.Sprintf("%d", cfg.HTTP.Port), server, time.Second*5)
 }
 
+func mustInitNotifier(cfg config.NotifierConfig) password.Notifier {
+	switch cfg.Type {
+	case "smtp":
+		return notify.NewSMTP(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.From)
+	case "", "file":
+		return notify.NewFile(cfg.FilePath)
+	default:
+		panic(fmt.Sprintf("unknown notifier type %q", cfg.Type))
+	}
+}
+
+func newVerification(cfg config.VerificationConfig, users *user.UserRepository, codes *verification.VerificationRepository, loginLockout *lockout.Lockout, notifier password.Notifier) *verificationService.Verification {
+	emailTTL := cfg.EmailTTL
+	if emailTTL == 0 {
+		emailTTL = emailVerificationTTL
+	}
+	phoneTTL := cfg.PhoneTTL
+	if phoneTTL == 0 {
+		phoneTTL = phoneVerificationTTL
+	}
+	resendInterval := cfg.ResendInterval
+	if resendInterval == 0 {
+		resendInterval = verificationResendInterval
+	}
+	return verificationService.New(users, codes, loginLockout, notifier, cfg.Url, emailTTL, phoneTTL, resendInterval)
+}
+
+func newMfa(cfg config.MfaConfig, users *user.UserRepository, codes *recoverycode.RecoveryCodeRepository, authService *auth.Auth, jwtLib *jwt.JwtLib, tokenDenylist *denylistService.Denylist, passkeys *passkeyService.Passkey, loginLockout *lockout.Lockout) *mfa.Mfa {
+	issuer := cfg.Issuer
+	if issuer == "" {
+		issuer = defaultMfaIssuer
+	}
+	// без ключа интерфейс должен остаться nil, а не указателем nil
+	var encryptor mfa.Encryptor
+	if cfg.EncryptionKey != "" {
+		encryptor = mustInitEncryptor(cfg.EncryptionKey)
+	}
+	return mfa.New(users, codes, authService, jwtLib, tokenDenylist, encryptor, passkeys, loginLockout, issuer)
+}
+
+func newPasskey(cfg *config.Config, passkeys *passkeyRepository.PasskeyRepository, users *user.UserRepository, authService *auth.Auth) *passkeyService.Passkey {
+	return passkeyService.New(passkeys, users, authService, mustInitRelyingParty(cfg), mustRequireVerified(cfg.Verification))
+}
+
+// mustInitRelyingParty — домен и адреса страниц для WebAuthn, по умолчанию из oidc.issuer
+func mustInitRelyingParty(cfg *config.Config) *webauthn.RelyingParty {
+	rpId := cfg.WebAuthn.RpId
+	origins := cfg.WebAuthn.Origins
+	if rpId == "" || len(origins) == 0 {
+		issuer, err := url.Parse(cfg.OIDC.Issuer)
+		if err != nil || issuer.Host == "" {
+			panic(fmt.Sprintf("webauthn.rp_id and webauthn.origins are required when oidc.issuer %q is not an absolute url", cfg.OIDC.Issuer))
+		}
+		if rpId == "" {
+			rpId = issuer.Hostname()
+		}
+		if len(origins) == 0 {
+			origins = []string{issuer.Scheme + "://" + issuer.Host}
+		}
+	}
+	rpName := cfg.WebAuthn.RpName
+	if rpName == "" {
+		rpName = defaultWebAuthnRpName
+	}
+	return webauthn.New(rpId, rpName, origins)
+}
+
+func mustInitEncryptor(encodedKey string) *encryption.Encryptor {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		panic(fmt.Sprintf("mfa.encryption_key must be base64: %s", err))
+	}
+	encryptor, err := encryption.New(key)
+	if err != nil {
+		panic(err.Error())
+	}
+	return encryptor
+}
+
+// newLockout — счётчики неудачных входов в памяти или в Postgres
+func newLockout(cfg config.LockoutConfig, db *sqlx.DB) *lockout.Lockout {
+	var repo lockout.Repository
+	switch cfg.Store {
+	case "", config.LockoutStoreMemory:
+		repo = loginattempt.NewMemory()
+	case config.LockoutStorePostgres:
+		repo = loginattempt.New(db)
+	default:
+		panic(fmt.Sprintf("unknown lockout store %q", cfg.Store))
+	}
+	window := cfg.Window
+	if window == 0 {
+		window = defaultLockoutWindow
+	}
+	return lockout.New(repo, lockoutPolicy(cfg.Login, defaultLoginLockout), lockoutPolicy(cfg.Ip, defaultIpLockout), window)
+}
+
+// lockoutPolicy — настройки из конфига, незаданные берутся из defaults
+func lockoutPolicy(cfg config.LockoutPolicyConfig, defaults lockout.Policy) lockout.Policy {
+	policy := defaults
+	if cfg.FreeAttempts != 0 {
+		policy.FreeAttempts = cfg.FreeAttempts
+	}
+	if cfg.MaxFailures != 0 {
+		policy.MaxFailures = cfg.MaxFailures
+	}
+	if cfg.BaseDelay != 0 {
+		policy.BaseDelay = cfg.BaseDelay
+	}
+	if cfg.LockoutDuration != 0 {
+		policy.LockoutDuration = cfg.LockoutDuration
+	}
+	if cfg.MaxLockout != 0 {
+		policy.MaxLockout = cfg.MaxLockout
+	}
+	return policy
+}
+
+// mustRequireVerified — нужно ли подтверждение контакта для входа
+func mustRequireVerified(cfg config.VerificationConfig) bool {
+	switch cfg.Mode {
+	case "", config.VerificationModeClaim:
+		return false
+	case config.VerificationModeRequire:
+		return true
+	default:
+		panic(fmt.Sprintf("unknown verification mode %q", cfg.Mode))
+	}
+}
+
+func mustLoadKeySet(cfg *config.Config, maxTokenLifetime time.Duration) *jwt.KeySet {
+	// без ключей в конфиге - подпись общим секретом, как раньше
+	if len(cfg.Jwt.Keys) == 0 {
+		keySet, err := jwt.NewKeySet("default", []*jwt.Key{jwt.NewHMACKey("default", []byte(cfg.Secret))}, maxTokenLifetime)
+		if err != nil {
+			panic(err.Error())
+		}
+		return keySet
+	}
+
+	keys := make([]*jwt.Key, 0, len(cfg.Jwt.Keys))
+	for _, keyCfg := range cfg.Jwt.Keys {
+		var key *jwt.Key
+		if keyCfg.Algorithm == "" || keyCfg.Algorithm == "HS256" {
+			secret := keyCfg.Secret
+			if secret == "" {
+				secret = cfg.Secret
+			}
+			key = jwt.NewHMACKey(keyCfg.Id, []byte(secret))
+		} else {
+			var err error
+			key, err = jwt.LoadKey(keyCfg.Id, keyCfg.Algorithm, keyCfg.PrivateKeyPath)
+			if err != nil {
+				panic(fmt.Sprintf("failed to load signing key %q: %s", keyCfg.Id, err.Error()))
+			}
+		}
+		if keyCfg.RetiredAt != "" {
+			retiredAt, err := time.Parse(time.RFC3339, keyCfg.RetiredAt)
+			if err != nil {
+				panic(fmt.Sprintf("invalid retired_at of signing key %q: %s", keyCfg.Id, err.Error()))
+			}
+			key.Retire(retiredAt)
+		}
+		keys = append(keys, key)
+	}
+
+	keySet, err := jwt.NewKeySet(cfg.Jwt.CurrentKeyId, keys, maxTokenLifetime)
+	if err != nil {
+		panic(fmt.Sprintf("invalid signing keys config: %s", err.Error()))
+	}
+	return keySet
+}
+
 func startPprofServer(ctx context.Context, g *errgroup.Group) {
 	pprofAddress := fmt.Sprintf("0.0.0.0:%d", 6060)
 	pprofServer := &http.Server{Addr: pprofAddress, Handler: http.DefaultServeMux}
@@ -5,111 +5,659 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"time"
 
 	"github.com/phenirain/sso/internal/domain"
 	"github.com/phenirain/sso/internal/dto/auth"
+	"github.com/phenirain/sso/internal/dto/oidc"
 	authErrors "github.com/phenirain/sso/internal/errors/auth"
 	"github.com/phenirain/sso/internal/errors/jwt"
+	jwtLib "github.com/phenirain/sso/internal/lib/jwt"
+	"github.com/phenirain/sso/pkg/claims"
+	"github.com/phenirain/sso/pkg/contextkeys"
 )
 
 type Jwt interface {
-	NewToken(userId int64) (accessToken string, refreshToken string, error error)
-	ParseToken(tokenString string) (int64, error)
+	NewToken(user *domain.User, session *domain.RefreshToken, params jwtLib.AccessTokenParams) (accessToken string, refreshToken string, error error)
+	ParseToken(tokenString string, tokenType claims.TokenType) (*claims.Claims, error)
+	NewIdToken(user *domain.User, params jwtLib.IdTokenParams) (string, error)
+	NewMfaToken(user *domain.User, params jwtLib.MfaTokenParams) (string, error)
+	SupportsIdTokens() bool
 }
 
 type Repository interface {
 	GetUserByLogin(ctx context.Context, login string) (*domain.User, error)
 	GetUserWithId(ctx context.Context, uid int64) (*domain.User, error)
 	CreateUser(ctx context.Context, user *domain.User) (int64, error)
+	UpdatePassword(ctx context.Context, user *domain.User) error
+	UpdateArchiveStatus(ctx context.Context, user *domain.User) error
 }
 
+type RefreshTokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error
+	GetRefreshToken(ctx context.Context, id string) (*domain.RefreshToken, error)
+	MarkRefreshTokenUsed(ctx context.Context, id string) (bool, error)
+	RevokeFamily(ctx context.Context, familyId string) error
+	RevokeUserTokens(ctx context.Context, userId int64) error
+}
+
+type ClientRepository interface {
+	GetClient(ctx context.Context, id string) (*domain.Client, error)
+}
+
+type Denylist interface {
+	IsRevoked(ctx context.Context, tokenClaims *claims.Claims) (bool, error)
+	RevokeSession(ctx context.Context, sessionId string, expiresAt time.Time) error
+	RevokeUserTokens(ctx context.Context, userId int64) error
+}
+
+// ContactVerifier отправляет коды подтверждения контактов нового пользователя
+type ContactVerifier interface {
+	Start(ctx context.Context, user *domain.User) error
+}
+
+// Limiter защищает проверку пароля от перебора: Attempt засчитывает попытку неудачной
+// заранее, Release и Success отменяют её после верных учётных данных
+type Limiter interface {
+	Attempt(ctx context.Context, login, ip string) error
+	Release(ctx context.Context, login, ip string)
+	Success(ctx context.Context, login, ip string)
+	Unlock(ctx context.Context, login, ip string) error
+}
+
+// mfaTokenTTL — сколько времени есть на ввод кода двухфакторной аутентификации после пароля
+const mfaTokenTTL = time.Minute * 5
+
+// phonePattern — телефон в формате E.164
+var phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
 
 type Auth struct {
 	repo  Repository
+	tokens RefreshTokenRepository
+	clients ClientRepository
+	denylist Denylist
+	verifier ContactVerifier
+	limiter Limiter
 	jwt Jwt
+	refreshTTL time.Duration
+	// вход только с подтверждённой почтой или телефоном
+	requireVerified bool
 }
 
-func New(repo Repository, jwt Jwt) *Auth {
+func New(repo Repository, tokens RefreshTokenRepository, clients ClientRepository, denylist Denylist, verifier ContactVerifier, limiter Limiter, jwt Jwt, refreshTTL time.Duration, requireVerified bool) *Auth {
 	return &Auth{
 		repo:  repo,
+		tokens: tokens,
+		clients: clients,
+		denylist: denylist,
+		verifier: verifier,
+		limiter: limiter,
 		jwt: jwt,
+		refreshTTL: refreshTTL,
+		requireVerified: requireVerified,
 	}
 }
 
 func (a *Auth) Auth(ctx context.Context, request auth.AuthRequest, isNew bool) (*auth.AuthResponse, error) {
-	const op string = "Auth.Login"
-
-	user, err := a.repo.GetUserByLogin(ctx, request.Login)
-	if err != nil {
-		slog.Error("failed to get user", "err", err)
-		return nil, fmt.Errorf("%s: %w", op, err)
+	// ID токен и токен mfa, из которого он потом выпускается, - только для существующего клиента
+	if request.ClientId != "" {
+		if err := a.CheckIdTokenClient(ctx, request.ClientId); err != nil {
+			return nil, err
+		}
 	}
-	var userId int64 
+
+	var user *domain.User
+	var err error
 	// если создание
 	if isNew {
-		// если пользователь найден - уже существует
-		if user != nil {
-			return nil, authErrors.ErrUserAlreadyExists
+		user, err = a.signUp(ctx, request)
+	} else { // если авторизация
+		user, err = a.Authenticate(ctx, request.Login, request.Password)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// после регистрации коды уже отправлены, токены - только после подтверждения.
+	// Сама регистрация удалась, ошибкой это только для входа
+	if a.requireVerified && !user.IsVerified() {
+		if isNew {
+			return &auth.AuthResponse{VerificationRequired: true}, nil
 		}
+		return nil, authErrors.ErrContactNotVerified
+	}
 
-		user = domain.NewUser(request.Login, request.Password, nil, nil)
-		userId, err = a.repo.CreateUser(ctx, user)
+	// пароль верен, но нужен второй фактор: вместо токенов - токен для /auth/mfa/verify
+	// или /auth/mfa/passkey/finish
+	if user.RequiresSecondFactor() {
+		mfaToken, err := a.jwt.NewMfaToken(user, jwtLib.MfaTokenParams{
+			TTL:      mfaTokenTTL,
+			ClientId: request.ClientId,
+			Nonce:    request.Nonce,
+		})
 		if err != nil {
-			errText := fmt.Errorf("ошибка в ходе создания пользователя: %w", err)
-			slog.Error(errText.Error())
-			return nil, errText
+			errorText := fmt.Errorf("ошибка генерации токена mfa: %w", err)
+			slog.Error(errorText.Error())
+			return nil, errorText
 		}
-	} else { // если авторизация
-		// если пользователь не найден
-		if user == nil {
-			return nil, authErrors.ErrInvalidUserCredentials
+		return &auth.AuthResponse{MfaToken: mfaToken}, nil
+	}
+
+	// OpenID Connect: клиент просит ID токен
+	var idToken *jwtLib.IdTokenParams
+	if request.ClientId != "" {
+		idToken = &jwtLib.IdTokenParams{
+			Audience: request.ClientId,
+			Nonce:    request.Nonce,
+			AuthTime: time.Now(),
 		}
-		valid := user.CheckPassword(request.Password)
-		// если пароль не верен
-		if !valid {
-			return nil, authErrors.ErrInvalidUserCredentials
+	}
+
+	return a.IssueTokens(ctx, user, nil, "", idToken)
+}
+
+// Authenticate проверяет логин и пароль пользователя. После серии неудач с того же логина
+// или адреса (IP берётся из контекста запроса) пароль не проверяется до конца блокировки.
+// Для пользователя с TOTP или ключами WebAuthn вход успешен только после второго фактора
+// (Mfa.CheckCode, Mfa.CheckPasskey или Mfa.VerifyPasskey)
+func (a *Auth) Authenticate(ctx context.Context, login, password string) (*domain.User, error) {
+	const op string = "Auth.Authenticate"
+
+	ip, _ := ctx.Value(contextkeys.ClientIPCtxKey).(string)
+	if err := a.limiter.Attempt(ctx, login, ip); err != nil {
+		return nil, err
+	}
+
+	user, err := a.repo.GetUserByLogin(ctx, login)
+	if err != nil {
+		slog.Error("failed to get user", "err", err)
+		a.limiter.Release(ctx, login, ip)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	// если пользователь не найден или удален
+	if user == nil || user.IsArchived {
+		return nil, authErrors.ErrInvalidUserCredentials
+	}
+	valid := user.CheckPassword(password)
+	// если пароль не верен - попытка так и остаётся засчитанной
+	if !valid {
+		return nil, authErrors.ErrInvalidUserCredentials
+	}
+	// вход завершится только после второго фактора: его проверки считает mfa,
+	// а до тех пор прежние неудачи по логину не прощаются
+	if user.RequiresSecondFactor() {
+		a.limiter.Release(ctx, login, ip)
+	} else {
+		a.limiter.Success(ctx, login, ip)
+	}
+	if a.requireVerified && !user.IsVerified() {
+		return nil, authErrors.ErrContactNotVerified
+	}
+	return user, nil
+}
+
+// signUp регистрирует покупателя и отправляет коды подтверждения на его почту (логин) и телефон
+func (a *Auth) signUp(ctx context.Context, request auth.AuthRequest) (*domain.User, error) {
+	if request.Phone != "" && !phonePattern.MatchString(request.Phone) {
+		return nil, authErrors.ErrInvalidPhone
+	}
+
+	user, err := a.createUser(ctx, request.Login, request.Password, domain.RoleBuyer, func(user *domain.User) {
+		user.SetPhone(request.Phone)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// не удалось отправить - пользователь запросит код повторно, регистрация от этого не ломается
+	if err := a.verifier.Start(ctx, user); err != nil {
+		slog.Error("failed to send verification codes", "user_id", user.Id, "err", err)
+	}
+	return user, nil
+}
+
+// CreateUser регистрирует пользователя с ролью roleId от имени администратора:
+// почта из логина сразу считается подтверждённой
+func (a *Auth) CreateUser(ctx context.Context, login, password string, roleId int64) (*domain.User, error) {
+	return a.createUser(ctx, login, password, roleId, func(user *domain.User) {
+		user.VerifyContact(domain.ChannelEmail)
+	})
+}
+
+// createUser создаёт пользователя, логин-почта становится его почтой. setup дополняет
+// пользователя перед сохранением
+func (a *Auth) createUser(ctx context.Context, login, password string, roleId int64, setup func(user *domain.User)) (*domain.User, error) {
+	const op string = "Auth.CreateUser"
+
+	user, err := a.repo.GetUserByLogin(ctx, login)
+	if err != nil {
+		slog.Error("failed to get user", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	// если пользователь найден - уже существует
+	if user != nil {
+		return nil, authErrors.ErrUserAlreadyExists
+	}
+
+	user, err = domain.NewUser(login, password, &roleId, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := mail.ParseAddress(login); err == nil {
+		user.SetEmail(login)
+	}
+	setup(user)
+	user.Id, err = a.repo.CreateUser(ctx, user)
+	if err != nil {
+		errText := fmt.Errorf("ошибка в ходе создания пользователя: %w", err)
+		slog.Error(errText.Error())
+		return nil, errText
+	}
+	return user, nil
+}
+
+// ArchiveUser архивирует пользователя: войти он больше не сможет, его сессии и токены отзываются
+func (a *Auth) ArchiveUser(ctx context.Context, login string) error {
+	user, err := a.getUser(ctx, login)
+	if err != nil {
+		return err
+	}
+
+	user.ChangeArchiveStatus(true)
+	if err := a.repo.UpdateArchiveStatus(ctx, user); err != nil {
+		errorText := fmt.Errorf("ошибка архивации пользователя: %w", err)
+		slog.Error(errorText.Error())
+		return errorText
+	}
+	return a.LogoutAll(ctx, user.Id)
+}
+
+// ChangePassword меняет пароль пользователя после проверки текущего. Все сессии пользователя
+// завершаются, а вызывающему выдаётся новая пара токенов, чтобы он остался в системе
+func (a *Auth) ChangePassword(ctx context.Context, userId int64, request auth.ChangePasswordRequest) (*auth.AuthResponse, error) {
+	const op string = "Auth.ChangePassword"
+
+	user, err := a.repo.GetUserWithId(ctx, userId)
+	if err != nil {
+		slog.Error("failed to get user", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if user == nil || user.IsArchived {
+		return nil, authErrors.ErrInvalidAccessToken
+	}
+
+	if err := user.UpdatePassword(request.OldPassword, request.NewPassword); err != nil {
+		return nil, err
+	}
+	if err := a.repo.UpdatePassword(ctx, user); err != nil {
+		errorText := fmt.Errorf("ошибка сохранения пароля: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+	if err := a.LogoutAll(ctx, user.Id); err != nil {
+		return nil, err
+	}
+
+	return a.IssueTokens(ctx, user, nil, "", nil)
+}
+
+// ResetPassword задаёт пользователю новый пароль без проверки старого, завершает все его сессии
+// и снимает блокировку входа
+func (a *Auth) ResetPassword(ctx context.Context, login, password string) error {
+	user, err := a.getUser(ctx, login)
+	if err != nil {
+		return err
+	}
+
+	if err := user.SetPassword(password); err != nil {
+		return err
+	}
+	if err := a.repo.UpdatePassword(ctx, user); err != nil {
+		errorText := fmt.Errorf("ошибка сохранения пароля: %w", err)
+		slog.Error(errorText.Error())
+		return errorText
+	}
+	if err := a.LogoutAll(ctx, user.Id); err != nil {
+		return err
+	}
+	return a.limiter.Unlock(ctx, user.Login, "")
+}
+
+func (a *Auth) getUser(ctx context.Context, login string) (*domain.User, error) {
+	user, err := a.repo.GetUserByLogin(ctx, login)
+	if err != nil {
+		errorText := fmt.Errorf("ошибка получения пользователя: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+	if user == nil {
+		return nil, authErrors.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// CheckIdTokenClient проверяет, что клиент, для которого просят ID токен, существует и не отключён.
+// Иначе любой, кто знает пароль, получил бы ID токен с aud чужого приложения
+func (a *Auth) CheckIdTokenClient(ctx context.Context, clientId string) error {
+	if !a.jwt.SupportsIdTokens() {
+		return authErrors.ErrOpenIdUnavailable
+	}
+	client, err := a.clients.GetClient(ctx, clientId)
+	if err != nil {
+		errorText := fmt.Errorf("ошибка получения клиента: %w", err)
+		slog.Error(errorText.Error())
+		return errorText
+	}
+	if client == nil || client.IsDisabled {
+		return authErrors.ErrUnknownClient
+	}
+	return nil
+}
+
+// IssueTokens начинает новую сессию пользователя с настройками client (nil - вход без клиента OAuth).
+// Если передан idToken - выпускает и ID токен
+func (a *Auth) IssueTokens(ctx context.Context, user *domain.User, client *domain.Client, scope string, idToken *jwtLib.IdTokenParams) (*auth.AuthResponse, error) {
+	response, err := a.getAuthResponse(ctx, user, "", client, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	if idToken != nil {
+		response.IdToken, err = a.jwt.NewIdToken(user, *idToken)
+		if err != nil {
+			errorText := fmt.Errorf("ошибка генерации ID токена: %w", err)
+			slog.Error(errorText.Error())
+			return nil, errorText
 		}
-		userId = user.Id
 	}
 
-	return a.getAuthResponse(userId)
+	return response, nil
 }
 
+// UserInfo возвращает claims пользователя для /userinfo
+func (a *Auth) UserInfo(ctx context.Context, userId int64) (*oidc.UserInfo, error) {
+	user, err := a.repo.GetUserWithId(ctx, userId)
+	if err != nil {
+		errorText := fmt.Errorf("ошибка получения пользователя по идентфикатору: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+	if user == nil || user.IsArchived {
+		return nil, authErrors.ErrUserNotFound
+	}
+
+	info := &oidc.UserInfo{
+		Sub:               strconv.FormatInt(user.Id, 10),
+		PreferredUsername: user.Login,
+		RoleId:            user.RoleId,
+	}
+	if user.Email != "" {
+		info.Email = user.Email
+		info.EmailVerified = &user.EmailVerified
+	}
+	if user.Phone != "" {
+		info.PhoneNumber = user.Phone
+		info.PhoneNumberVerified = &user.PhoneVerified
+	}
+	return info, nil
+}
+
+// Verify проверяет access токен для forward auth: подпись, срок, отзыв и что пользователь не удалён.
+// Роль и логин берутся из базы - они могли поменяться после выпуска токена
+func (a *Auth) Verify(ctx context.Context, accessToken string) (*auth.Identity, error) {
+	tokenClaims, err := a.jwt.ParseToken(accessToken, claims.TokenTypeAccess)
+	if err != nil {
+		return nil, authErrors.ErrInvalidAccessToken
+	}
+	revoked, err := a.denylist.IsRevoked(ctx, tokenClaims)
+	if err != nil {
+		errorText := fmt.Errorf("ошибка проверки отзыва токена: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+	if revoked {
+		return nil, authErrors.ErrInvalidAccessToken
+	}
+	if tokenClaims.IsService() {
+		return &auth.Identity{ClientId: tokenClaims.ClientId}, nil
+	}
+
+	user, err := a.repo.GetUserWithId(ctx, tokenClaims.UserId)
+	if err != nil {
+		errorText := fmt.Errorf("ошибка получения пользователя по идентфикатору: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+	if user == nil || user.IsArchived {
+		return nil, authErrors.ErrInvalidAccessToken
+	}
+
+	return &auth.Identity{
+		UserId:   user.Id,
+		RoleId:   user.RoleId,
+		Login:    user.Login,
+		ClientId: tokenClaims.ClientId,
+	}, nil
+}
+
+// Refresh обменивает refresh токен, выданный через /auth/logIn
 func (a *Auth) Refresh(ctx context.Context, refreshToken string) (*auth.AuthResponse, error) {
+	return a.refresh(ctx, refreshToken, "")
+}
+
+// RefreshForClient обменивает refresh токен, выданный клиенту OAuth clientId
+func (a *Auth) RefreshForClient(ctx context.Context, refreshToken, clientId string) (*auth.AuthResponse, error) {
+	return a.refresh(ctx, refreshToken, clientId)
+}
+
+func (a *Auth) refresh(ctx context.Context, refreshToken, clientId string) (*auth.AuthResponse, error) {
 
 	// проверка токена
-	userId, err := a.jwt.ParseToken(refreshToken)
+	tokenClaims, err := a.jwt.ParseToken(refreshToken, claims.TokenTypeRefresh)
 	if err != nil {
-		if errors.Is(err, jwt.ErrInvalidToken) {
+		if errors.Is(err, jwt.ErrInvalidToken) || errors.Is(err, jwt.ErrInvalidTokenType) || errors.Is(err, jwt.ErrTokenExpired) {
 			return nil, err
 		}
 		slog.Error("ошибка парсинга токена", "err", err)
 		return nil, err
 	}
 
+	// проверка сессии
+	session, err := a.getSession(ctx, tokenClaims.TokenId, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	// токен одного клиента нельзя обменять от имени другого
+	if session.ClientId != clientId {
+		return nil, authErrors.ErrInvalidRefreshToken
+	}
+	client, err := a.getSessionClient(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.useRefreshToken(ctx, session); err != nil {
+		return nil, err
+	}
+
 	// проверка пользователя
-	user, err := a.repo.GetUserWithId(ctx, userId)
+	user, err := a.repo.GetUserWithId(ctx, tokenClaims.UserId)
 	if err != nil {
 		errorText := fmt.Errorf("ошибка получения пользователя по идентфикатору: %w", err)
 		slog.Error(errorText.Error())
 		return nil, errorText
 	}
-	// если его нет или удален - нахуй
+	// если его нет или удален - нахуй. Для клиента это такой же недействительный refresh токен:
+	// 404 подсказал бы, что пользователя архивировали
 	if user == nil || user.IsArchived {
-		return nil, authErrors.ErrUserNotFound
+		slog.Info("refresh токен удалённого или архивного пользователя", "user_id", tokenClaims.UserId)
+		return nil, authErrors.ErrInvalidRefreshToken
 	}
 
-	return a.getAuthResponse(userId)
+	// роль берём из базы, а не из токена - она могла поменяться
+	return a.getAuthResponse(ctx, user, session.FamilyId, client, session.Scope)
 }
 
-func (a *Auth) getAuthResponse(userId int64) (*auth.AuthResponse, error) {
-	accessToken, refreshToken, err := a.jwt.NewToken(userId)
+// Logout завершает сессию, к которой относится refresh токен
+func (a *Auth) Logout(ctx context.Context, refreshToken string) error {
+	tokenClaims, err := a.jwt.ParseToken(refreshToken, claims.TokenTypeRefresh)
+	if err != nil {
+		return err
+	}
+	session, err := a.getSession(ctx, tokenClaims.TokenId, refreshToken)
+	if err != nil {
+		return err
+	}
+	return a.revokeSession(ctx, session)
+}
+
+// ActiveRefreshToken возвращает claims refresh токена, если его ещё можно обменять
+func (a *Auth) ActiveRefreshToken(ctx context.Context, refreshToken string) (*claims.Claims, error) {
+	tokenClaims, err := a.jwt.ParseToken(refreshToken, claims.TokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+	session, err := a.getSession(ctx, tokenClaims.TokenId, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if !session.IsActive() {
+		return nil, authErrors.ErrInvalidRefreshToken
+	}
+	return tokenClaims, nil
+}
+
+// LogoutAll завершает все сессии пользователя и отзывает выданные ему access токены
+func (a *Auth) LogoutAll(ctx context.Context, userId int64) error {
+	if err := a.tokens.RevokeUserTokens(ctx, userId); err != nil {
+		errorText := fmt.Errorf("ошибка отзыва сессий пользователя: %w", err)
+		slog.Error(errorText.Error())
+		return errorText
+	}
+	if err := a.denylist.RevokeUserTokens(ctx, userId); err != nil {
+		errorText := fmt.Errorf("ошибка отзыва access токенов пользователя: %w", err)
+		slog.Error(errorText.Error())
+		return errorText
+	}
+	return nil
+}
+
+// useRefreshToken ротирует refresh токен: помечает его использованным.
+// Повторное предъявление уже использованного токена отзывает всю сессию
+func (a *Auth) useRefreshToken(ctx context.Context, session *domain.RefreshToken) error {
+	used, err := a.tokens.MarkRefreshTokenUsed(ctx, session.Id)
+	if err != nil {
+		errorText := fmt.Errorf("ошибка ротации refresh токена: %w", err)
+		slog.Error(errorText.Error())
+		return errorText
+	}
+	if !used {
+		// токен отозван - просто отказываем
+		if session.RevokedAt != nil {
+			return authErrors.ErrInvalidRefreshToken
+		}
+		// токен уже обменивали - скорее всего его украли, завершаем сессию целиком
+		slog.Warn("повторное использование refresh токена", "user_id", session.UserId, "family_id", session.FamilyId)
+		if err := a.revokeSession(ctx, session); err != nil {
+			return err
+		}
+		return authErrors.ErrRefreshTokenReused
+	}
+
+	return nil
+}
+
+// revokeSession отзывает семейство refresh токенов и access токены, выпущенные в этой сессии.
+// Access токен живёт не дольше refresh токена, поэтому отзыв помним на срок refresh токена
+func (a *Auth) revokeSession(ctx context.Context, session *domain.RefreshToken) error {
+	if err := a.tokens.RevokeFamily(ctx, session.FamilyId); err != nil {
+		errorText := fmt.Errorf("ошибка отзыва сессии: %w", err)
+		slog.Error(errorText.Error())
+		return errorText
+	}
+	if err := a.denylist.RevokeSession(ctx, session.FamilyId, time.Now().Add(a.refreshTTL)); err != nil {
+		errorText := fmt.Errorf("ошибка отзыва access токенов сессии: %w", err)
+		slog.Error(errorText.Error())
+		return errorText
+	}
+	return nil
+}
+
+// getSession находит сохранённый refresh токен и сверяет его хеш с предъявленным
+func (a *Auth) getSession(ctx context.Context, tokenId, refreshToken string) (*domain.RefreshToken, error) {
+	if tokenId == "" {
+		return nil, authErrors.ErrInvalidRefreshToken
+	}
+
+	session, err := a.tokens.GetRefreshToken(ctx, tokenId)
+	if err != nil {
+		errorText := fmt.Errorf("ошибка получения refresh токена: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+	if session == nil || !session.CheckToken(refreshToken) {
+		return nil, authErrors.ErrInvalidRefreshToken
+	}
+	return session, nil
+}
+
+// getSessionClient возвращает клиента сессии, если ему всё ещё можно обновлять токены.
+// nil без ошибки - сессия без клиента OAuth
+func (a *Auth) getSessionClient(ctx context.Context, session *domain.RefreshToken) (*domain.Client, error) {
+	if session.ClientId == "" {
+		return nil, nil
+	}
+
+	client, err := a.clients.GetClient(ctx, session.ClientId)
+	if err != nil {
+		errorText := fmt.Errorf("ошибка получения клиента: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+	if client == nil || client.IsDisabled || !client.AllowsGrant(domain.GrantRefreshToken) {
+		return nil, authErrors.ErrInvalidRefreshToken
+	}
+	return client, nil
+}
+
+// getAuthResponse выпускает пару токенов с настройками client и сохраняет refresh токен.
+// Пустой familyId - новая сессия. Клиенту без гранта refresh_token refresh токен не выдаётся
+func (a *Auth) getAuthResponse(ctx context.Context, user *domain.User, familyId string, client *domain.Client, scope string) (*auth.AuthResponse, error) {
+	refreshTTL := a.refreshTTL
+	var params jwtLib.AccessTokenParams
+	if client != nil {
+		refreshTTL = client.RefreshTTL(a.refreshTTL)
+		params = jwtLib.AccessTokenParams{
+			TTL:      client.AccessTTL(0),
+			ClientId: client.Id,
+			Audience: client.Audience,
+			Scope:    scope,
+		}
+	}
+
+	session := domain.NewRefreshToken(user.Id, familyId, params.ClientId, scope, refreshTTL)
+	accessToken, refreshToken, err := a.jwt.NewToken(user, session, params)
 	if err != nil {
 		errorText := fmt.Errorf("ошибка генерации токенов доступа: %w", err)
 		slog.Error(errorText.Error())
 		return nil, errorText
 	}
 
+	if client != nil && !client.AllowsGrant(domain.GrantRefreshToken) {
+		return &auth.AuthResponse{
+			AccessToken: accessToken,
+		}, nil
+	}
+
+	session.SetToken(refreshToken)
+	if err := a.tokens.CreateRefreshToken(ctx, session); err != nil {
+		errorText := fmt.Errorf("ошибка сохранения refresh токена: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+
 	return &auth.AuthResponse{
 		AccessToken: accessToken,
 		RefreshToken: refreshToken,
@@ -0,0 +1,570 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/internal/dto/auth"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	jwtErrors "github.com/phenirain/sso/internal/errors/jwt"
+	jwtLib "github.com/phenirain/sso/internal/lib/jwt"
+	"github.com/phenirain/sso/internal/services/denylist"
+	"github.com/phenirain/sso/pkg/claims"
+)
+
+const testPassword = "P@ssw0rd!"
+
+// fakeUsers отдаёт копии, как база: изменения видны только после Update*
+type fakeUsers struct {
+	users map[int64]*domain.User
+}
+
+func (r *fakeUsers) GetUserByLogin(ctx context.Context, login string) (*domain.User, error) {
+	for _, user := range r.users {
+		if user.Login == login {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeUsers) GetUserWithId(ctx context.Context, uid int64) (*domain.User, error) {
+	user, ok := r.users[uid]
+	if !ok {
+		return nil, nil
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *fakeUsers) CreateUser(ctx context.Context, user *domain.User) (int64, error) {
+	user.Id = int64(len(r.users) + 1)
+	copied := *user
+	r.users[user.Id] = &copied
+	return user.Id, nil
+}
+
+func (r *fakeUsers) UpdatePassword(ctx context.Context, user *domain.User) error {
+	r.users[user.Id].PasswordHash = user.PasswordHash
+	r.users[user.Id].TokensNotBefore = user.TokensNotBefore
+	return nil
+}
+
+func (r *fakeUsers) UpdateArchiveStatus(ctx context.Context, user *domain.User) error {
+	r.users[user.Id].IsArchived = user.IsArchived
+	r.users[user.Id].TokensNotBefore = user.TokensNotBefore
+	return nil
+}
+
+func (r *fakeUsers) GetTokensNotBefore(ctx context.Context, uid int64) (*time.Time, error) {
+	if user, ok := r.users[uid]; ok {
+		return user.TokensNotBefore, nil
+	}
+	return nil, nil
+}
+
+func (r *fakeUsers) SetTokensNotBefore(ctx context.Context, uid int64, notBefore time.Time) error {
+	r.users[uid].TokensNotBefore = &notBefore
+	return nil
+}
+
+type fakeRefreshTokens struct {
+	tokens map[string]*domain.RefreshToken
+}
+
+func (r *fakeRefreshTokens) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	copied := *token
+	r.tokens[token.Id] = &copied
+	return nil
+}
+
+func (r *fakeRefreshTokens) GetRefreshToken(ctx context.Context, id string) (*domain.RefreshToken, error) {
+	token, ok := r.tokens[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *token
+	return &copied, nil
+}
+
+func (r *fakeRefreshTokens) MarkRefreshTokenUsed(ctx context.Context, id string) (bool, error) {
+	token := r.tokens[id]
+	if token.UsedAt != nil || token.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	token.UsedAt = &now
+	return true, nil
+}
+
+func (r *fakeRefreshTokens) RevokeFamily(ctx context.Context, familyId string) error {
+	r.revoke(func(token *domain.RefreshToken) bool { return token.FamilyId == familyId })
+	return nil
+}
+
+func (r *fakeRefreshTokens) RevokeUserTokens(ctx context.Context, userId int64) error {
+	r.revoke(func(token *domain.RefreshToken) bool { return token.UserId == userId })
+	return nil
+}
+
+func (r *fakeRefreshTokens) revoke(match func(token *domain.RefreshToken) bool) {
+	now := time.Now()
+	for _, token := range r.tokens {
+		if match(token) && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+}
+
+type fakeClients struct {
+	clients map[string]*domain.Client
+}
+
+func (r *fakeClients) GetClient(ctx context.Context, id string) (*domain.Client, error) {
+	return r.clients[id], nil
+}
+
+// fakeDenylistRepo — хранилище отозванных jti и сессий для настоящего denylist.Denylist
+type fakeDenylistRepo struct {
+	tokens   map[string]bool
+	sessions map[string]bool
+}
+
+func (r *fakeDenylistRepo) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	return r.tokens[jti], nil
+}
+
+func (r *fakeDenylistRepo) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	r.tokens[jti] = true
+	return nil
+}
+
+func (r *fakeDenylistRepo) IsSessionRevoked(ctx context.Context, sessionId string) (bool, error) {
+	return r.sessions[sessionId], nil
+}
+
+func (r *fakeDenylistRepo) RevokeSession(ctx context.Context, sessionId string, expiresAt time.Time) error {
+	r.sessions[sessionId] = true
+	return nil
+}
+
+type noopVerifier struct{}
+
+func (noopVerifier) Start(ctx context.Context, user *domain.User) error { return nil }
+
+type noopLimiter struct{}
+
+func (noopLimiter) Attempt(ctx context.Context, login, ip string) error { return nil }
+func (noopLimiter) Release(ctx context.Context, login, ip string)       {}
+func (noopLimiter) Success(ctx context.Context, login, ip string)       {}
+func (noopLimiter) Unlock(ctx context.Context, login, ip string) error  { return nil }
+
+// testAuth — Auth на фейках с настоящими jwtLib и denylist.Denylist. Пользователь bob (id 1),
+// клиенты OAuth: spa, worker без гранта refresh_token и отключённый old
+type testAuth struct {
+	*Auth
+	users  *fakeUsers
+	tokens *fakeRefreshTokens
+	jwt    *jwtLib.JwtLib
+}
+
+func newTestAuth(t *testing.T, refreshTTL time.Duration) *testAuth {
+	t.Helper()
+	key, _, err := jwtLib.GenerateKey("test", "ES256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keySet, err := jwtLib.NewKeySet("test", []*jwtLib.Key{key}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwt := jwtLib.NewJwtLib(time.Minute, keySet, "http://sso")
+
+	bob, err := domain.NewUser("bob@example.com", testPassword, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob.Id = 1
+	users := &fakeUsers{users: map[int64]*domain.User{1: bob}}
+
+	clients := &fakeClients{clients: make(map[string]*domain.Client)}
+	for _, id := range []string{"spa", "worker", "old"} {
+		client, _ := domain.NewClient(id, id, false)
+		grants := []string{domain.GrantAuthorizationCode, domain.GrantRefreshToken}
+		if id == "worker" {
+			grants = []string{domain.GrantAuthorizationCode}
+		}
+		client.UpdateSettings(id, []string{"http://app/cb"}, grants, []string{"openid"}, 0, 0, "")
+		clients.clients[id] = client
+	}
+	clients.clients["old"].ChangeDisabledStatus(true)
+
+	tokens := &fakeRefreshTokens{tokens: make(map[string]*domain.RefreshToken)}
+	denylistRepo := &fakeDenylistRepo{tokens: make(map[string]bool), sessions: make(map[string]bool)}
+	a := New(users, tokens, clients, denylist.New(users, denylistRepo, time.Minute), noopVerifier{}, noopLimiter{}, jwt, refreshTTL, false)
+	return &testAuth{Auth: a, users: users, tokens: tokens, jwt: jwt}
+}
+
+// login выдаёт bob пару токенов: без клиента или клиенту OAuth clientId
+func (a *testAuth) login(t *testing.T, clientId string) *auth.AuthResponse {
+	t.Helper()
+	ctx := context.Background()
+	user, err := a.users.GetUserWithId(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var client *domain.Client
+	if clientId != "" {
+		client = a.clients.(*fakeClients).clients[clientId]
+	}
+	tokens, err := a.IssueTokens(ctx, user, client, "openid", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens
+}
+
+// accessActive — access токен проходит Verify, как в forward auth
+func (a *testAuth) accessActive(accessToken string) bool {
+	_, err := a.Verify(context.Background(), accessToken)
+	return err == nil
+}
+
+func TestRefresh(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare возвращает refresh токен и клиента, от имени которого его обменивают
+		prepare    func(t *testing.T, a *testAuth) (string, string)
+		refreshTTL time.Duration
+		wantErr    error
+	}{
+		{
+			name: "fresh token",
+			prepare: func(t *testing.T, a *testAuth) (string, string) {
+				return a.login(t, "").RefreshToken, ""
+			},
+		},
+		{
+			name: "rotated token",
+			prepare: func(t *testing.T, a *testAuth) (string, string) {
+				rotated, err := a.Refresh(context.Background(), a.login(t, "").RefreshToken)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return rotated.RefreshToken, ""
+			},
+		},
+		{
+			name: "used token",
+			prepare: func(t *testing.T, a *testAuth) (string, string) {
+				refreshToken := a.login(t, "").RefreshToken
+				if _, err := a.Refresh(context.Background(), refreshToken); err != nil {
+					t.Fatal(err)
+				}
+				return refreshToken, ""
+			},
+			wantErr: authErrors.ErrRefreshTokenReused,
+		},
+		{
+			name: "token of session ended by reuse",
+			prepare: func(t *testing.T, a *testAuth) (string, string) {
+				refreshToken := a.login(t, "").RefreshToken
+				rotated, err := a.Refresh(context.Background(), refreshToken)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := a.Refresh(context.Background(), refreshToken); !errors.Is(err, authErrors.ErrRefreshTokenReused) {
+					t.Fatalf("reuse: got %v", err)
+				}
+				return rotated.RefreshToken, ""
+			},
+			wantErr: authErrors.ErrInvalidRefreshToken,
+		},
+		{
+			name:       "expired token",
+			refreshTTL: -time.Minute,
+			prepare: func(t *testing.T, a *testAuth) (string, string) {
+				return a.login(t, "").RefreshToken, ""
+			},
+			wantErr: jwtErrors.ErrTokenExpired,
+		},
+		{
+			name: "access token instead of refresh",
+			prepare: func(t *testing.T, a *testAuth) (string, string) {
+				return a.login(t, "").AccessToken, ""
+			},
+			wantErr: jwtErrors.ErrInvalidTokenType,
+		},
+		{
+			name: "client token for its client",
+			prepare: func(t *testing.T, a *testAuth) (string, string) {
+				return a.login(t, "spa").RefreshToken, "spa"
+			},
+		},
+		{
+			name: "client token without client",
+			prepare: func(t *testing.T, a *testAuth) (string, string) {
+				return a.login(t, "spa").RefreshToken, ""
+			},
+			wantErr: authErrors.ErrInvalidRefreshToken,
+		},
+		{
+			name: "client token for another client",
+			prepare: func(t *testing.T, a *testAuth) (string, string) {
+				return a.login(t, "spa").RefreshToken, "worker"
+			},
+			wantErr: authErrors.ErrInvalidRefreshToken,
+		},
+		{
+			name: "token without client for a client",
+			prepare: func(t *testing.T, a *testAuth) (string, string) {
+				return a.login(t, "").RefreshToken, "spa"
+			},
+			wantErr: authErrors.ErrInvalidRefreshToken,
+		},
+		{
+			name: "token of disabled client",
+			prepare: func(t *testing.T, a *testAuth) (string, string) {
+				refreshToken := a.login(t, "spa").RefreshToken
+				a.clients.(*fakeClients).clients["spa"].ChangeDisabledStatus(true)
+				return refreshToken, "spa"
+			},
+			wantErr: authErrors.ErrInvalidRefreshToken,
+		},
+		{
+			name: "archived user",
+			prepare: func(t *testing.T, a *testAuth) (string, string) {
+				refreshToken := a.login(t, "").RefreshToken
+				a.users.users[1].IsArchived = true
+				return refreshToken, ""
+			},
+			wantErr: authErrors.ErrInvalidRefreshToken,
+		},
+		{
+			name: "deleted user",
+			prepare: func(t *testing.T, a *testAuth) (string, string) {
+				refreshToken := a.login(t, "").RefreshToken
+				delete(a.users.users, 1)
+				return refreshToken, ""
+			},
+			wantErr: authErrors.ErrInvalidRefreshToken,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refreshTTL := tt.refreshTTL
+			if refreshTTL == 0 {
+				refreshTTL = time.Hour
+			}
+			a := newTestAuth(t, refreshTTL)
+			refreshToken, clientId := tt.prepare(t, a)
+
+			tokens, err := a.RefreshForClient(context.Background(), refreshToken, clientId)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RefreshForClient: got %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if tokens.RefreshToken == "" || tokens.RefreshToken == refreshToken {
+				t.Error("refresh token was not rotated")
+			}
+			tokenClaims, err := a.jwt.ParseToken(tokens.AccessToken, claims.TokenTypeAccess)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tokenClaims.ClientId != clientId {
+				t.Errorf("access token client_id: got %q, want %q", tokenClaims.ClientId, clientId)
+			}
+		})
+	}
+}
+
+// Повторный обмен refresh токена завершает всю сессию: и новый refresh токен, и access токены
+// сессии, но не другие сессии пользователя
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	ctx := context.Background()
+	a := newTestAuth(t, time.Hour)
+	stolen := a.login(t, "")
+	other := a.login(t, "")
+
+	rotated, err := a.Refresh(ctx, stolen.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Refresh(ctx, stolen.RefreshToken); !errors.Is(err, authErrors.ErrRefreshTokenReused) {
+		t.Fatalf("reuse: got %v, want ErrRefreshTokenReused", err)
+	}
+
+	tests := []struct {
+		name   string
+		active bool
+		check  func() bool
+	}{
+		{"rotated refresh token", false, func() bool {
+			_, err := a.ActiveRefreshToken(ctx, rotated.RefreshToken)
+			return err == nil
+		}},
+		{"access token before rotation", false, func() bool { return a.accessActive(stolen.AccessToken) }},
+		{"access token after rotation", false, func() bool { return a.accessActive(rotated.AccessToken) }},
+		{"other session refresh token", true, func() bool {
+			_, err := a.ActiveRefreshToken(ctx, other.RefreshToken)
+			return err == nil
+		}},
+		{"other session access token", true, func() bool { return a.accessActive(other.AccessToken) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.check(); got != tt.active {
+				t.Errorf("active: got %v, want %v", got, tt.active)
+			}
+		})
+	}
+}
+
+// Logout завершает сессию целиком: её refresh токены, включая выданные при ротации, и её
+// access токены. Другие сессии пользователя живут
+func TestLogout(t *testing.T) {
+	ctx := context.Background()
+	a := newTestAuth(t, time.Hour)
+	first := a.login(t, "")
+	session, err := a.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := a.login(t, "spa")
+
+	if err := a.Logout(ctx, session.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		active bool
+		check  func() bool
+	}{
+		{"refresh token", false, func() bool {
+			_, err := a.ActiveRefreshToken(ctx, session.RefreshToken)
+			return err == nil
+		}},
+		{"refresh token can not be exchanged", false, func() bool {
+			_, err := a.Refresh(ctx, session.RefreshToken)
+			return err == nil
+		}},
+		{"access token", false, func() bool { return a.accessActive(session.AccessToken) }},
+		{"access token before rotation", false, func() bool { return a.accessActive(first.AccessToken) }},
+		{"other session refresh token", true, func() bool {
+			_, err := a.ActiveRefreshToken(ctx, other.RefreshToken)
+			return err == nil
+		}},
+		{"other session access token", true, func() bool { return a.accessActive(other.AccessToken) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.check(); got != tt.active {
+				t.Errorf("active: got %v, want %v", got, tt.active)
+			}
+		})
+	}
+}
+
+// LogoutAll отзывает все refresh токены пользователя, а access токены - через tokens_not_before.
+// Токены, выданные после выхода, действуют
+func TestLogoutAll(t *testing.T) {
+	ctx := context.Background()
+	a := newTestAuth(t, time.Hour)
+	sessions := []*auth.AuthResponse{a.login(t, ""), a.login(t, "spa")}
+
+	if err := a.LogoutAll(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if a.users.users[1].TokensNotBefore == nil {
+		t.Fatal("tokens_not_before not set")
+	}
+	after := a.login(t, "")
+
+	for i, session := range sessions {
+		if _, err := a.ActiveRefreshToken(ctx, session.RefreshToken); err == nil {
+			t.Errorf("session %d: refresh token still active", i)
+		}
+		if _, err := a.Refresh(ctx, session.RefreshToken); err == nil {
+			t.Errorf("session %d: refresh token exchanged", i)
+		}
+		if a.accessActive(session.AccessToken) {
+			t.Errorf("session %d: access token still active", i)
+		}
+	}
+	if _, err := a.ActiveRefreshToken(ctx, after.RefreshToken); err != nil {
+		t.Errorf("refresh token issued after logout: %v", err)
+	}
+	if !a.accessActive(after.AccessToken) {
+		t.Error("access token issued after logout is revoked")
+	}
+}
+
+func TestIssueTokensRefreshGrant(t *testing.T) {
+	tests := []struct {
+		clientId    string
+		wantRefresh bool
+	}{
+		{"", true},
+		{"spa", true},
+		// клиенту без гранта refresh_token сессия не сохраняется
+		{"worker", false},
+	}
+	for _, tt := range tests {
+		t.Run("client "+tt.clientId, func(t *testing.T) {
+			a := newTestAuth(t, time.Hour)
+			tokens := a.login(t, tt.clientId)
+			if got := tokens.RefreshToken != ""; got != tt.wantRefresh {
+				t.Errorf("refresh token issued: got %v, want %v", got, tt.wantRefresh)
+			}
+			if got := len(a.tokens.tokens) == 1; got != tt.wantRefresh {
+				t.Errorf("session stored: got %v, want %v", got, tt.wantRefresh)
+			}
+		})
+	}
+}
+
+func TestAuthLogin(t *testing.T) {
+	tests := []struct {
+		name        string
+		request     auth.AuthRequest
+		archived    bool
+		wantErr     error
+		wantIdToken bool
+	}{
+		{"valid credentials", auth.AuthRequest{Login: "bob@example.com", Password: testPassword}, false, nil, false},
+		{"wrong password", auth.AuthRequest{Login: "bob@example.com", Password: "wrong"}, false, authErrors.ErrInvalidUserCredentials, false},
+		{"unknown login", auth.AuthRequest{Login: "eve@example.com", Password: testPassword}, false, authErrors.ErrInvalidUserCredentials, false},
+		// для клиента архивный пользователь неотличим от несуществующего
+		{"archived user", auth.AuthRequest{Login: "bob@example.com", Password: testPassword}, true, authErrors.ErrInvalidUserCredentials, false},
+		{"id token for known client", auth.AuthRequest{Login: "bob@example.com", Password: testPassword, ClientId: "spa"}, false, nil, true},
+		{"id token for unknown client", auth.AuthRequest{Login: "bob@example.com", Password: testPassword, ClientId: "evil"}, false, authErrors.ErrUnknownClient, false},
+		{"id token for disabled client", auth.AuthRequest{Login: "bob@example.com", Password: testPassword, ClientId: "old"}, false, authErrors.ErrUnknownClient, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAuth(t, time.Hour)
+			a.users.users[1].IsArchived = tt.archived
+
+			tokens, err := a.Auth.Auth(context.Background(), tt.request, false)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Auth: got %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+				t.Error("tokens were not issued")
+			}
+			if got := tokens.IdToken != ""; got != tt.wantIdToken {
+				t.Errorf("id token issued: got %v, want %v", got, tt.wantIdToken)
+			}
+		})
+	}
+}
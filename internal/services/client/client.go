@@ -0,0 +1,205 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/phenirain/sso/internal/domain"
+	clientModels "github.com/phenirain/sso/internal/dto/client"
+	clientErrors "github.com/phenirain/sso/internal/errors/client"
+	"github.com/phenirain/sso/internal/lib/jwt"
+)
+
+type Repository interface {
+	GetClient(ctx context.Context, id string) (*domain.Client, error)
+	GetClients(ctx context.Context) ([]domain.Client, error)
+	CreateClient(ctx context.Context, client *domain.Client) error
+	UpdateClient(ctx context.Context, client *domain.Client) error
+}
+
+type Client struct {
+	repo Repository
+	// Дольше ключи подписи не хранятся после ротации: токен с большим сроком жизни
+	// перестал бы проверяться раньше, чем истёк
+	maxTokenTTL time.Duration
+}
+
+func New(repo Repository, maxTokenTTL time.Duration) *Client {
+	return &Client{
+		repo:        repo,
+		maxTokenTTL: maxTokenTTL,
+	}
+}
+
+func (s *Client) List(ctx context.Context) ([]clientModels.ClientResponse, error) {
+	clients, err := s.repo.GetClients(ctx)
+	if err != nil {
+		errorText := fmt.Errorf("ошибка получения клиентов: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+
+	result := make([]clientModels.ClientResponse, 0, len(clients))
+	for i := range clients {
+		result = append(result, toResponse(&clients[i], ""))
+	}
+	return result, nil
+}
+
+func (s *Client) Create(ctx context.Context, request clientModels.ClientRequest) (*clientModels.ClientResponse, error) {
+	if err := s.validate(request); err != nil {
+		return nil, err
+	}
+	if request.Id != "" {
+		existing, err := s.getClient(ctx, request.Id)
+		if err != nil && !errors.Is(err, clientErrors.ErrClientNotFound) {
+			return nil, err
+		}
+		if existing != nil {
+			return nil, clientErrors.ErrClientAlreadyExists
+		}
+	}
+
+	client, secret := domain.NewClient(request.Id, request.Name, request.IsConfidential)
+	client.UpdateSettings(request.Name, request.RedirectUris, request.GrantTypes, request.Scopes,
+		ttlSeconds(request.AccessTokenTTL), ttlSeconds(request.RefreshTokenTTL), request.Audience)
+	client.PublicKey = request.PublicKey
+	if err := s.repo.CreateClient(ctx, client); err != nil {
+		errorText := fmt.Errorf("ошибка создания клиента: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+
+	response := toResponse(client, secret)
+	return &response, nil
+}
+
+func (s *Client) Update(ctx context.Context, id string, request clientModels.ClientRequest) (*clientModels.ClientResponse, error) {
+	if err := s.validate(request); err != nil {
+		return nil, err
+	}
+	client, err := s.getClient(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	client.UpdateSettings(request.Name, request.RedirectUris, request.GrantTypes, request.Scopes,
+		ttlSeconds(request.AccessTokenTTL), ttlSeconds(request.RefreshTokenTTL), request.Audience)
+	client.SetPublicKey(request.PublicKey)
+	if err := s.update(ctx, client); err != nil {
+		return nil, err
+	}
+
+	response := toResponse(client, "")
+	return &response, nil
+}
+
+// RotateSecret выпускает новый секрет. Публичный клиент после этого становится конфиденциальным
+func (s *Client) RotateSecret(ctx context.Context, id string) (*clientModels.ClientResponse, error) {
+	client, err := s.getClient(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := client.RotateSecret()
+	if err := s.update(ctx, client); err != nil {
+		return nil, err
+	}
+
+	response := toResponse(client, secret)
+	return &response, nil
+}
+
+func (s *Client) SetDisabled(ctx context.Context, id string, disabled bool) (*clientModels.ClientResponse, error) {
+	client, err := s.getClient(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	client.ChangeDisabledStatus(disabled)
+	if err := s.update(ctx, client); err != nil {
+		return nil, err
+	}
+
+	response := toResponse(client, "")
+	return &response, nil
+}
+
+func (s *Client) getClient(ctx context.Context, id string) (*domain.Client, error) {
+	client, err := s.repo.GetClient(ctx, id)
+	if err != nil {
+		errorText := fmt.Errorf("ошибка получения клиента: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+	if client == nil {
+		return nil, clientErrors.ErrClientNotFound
+	}
+	return client, nil
+}
+
+func (s *Client) update(ctx context.Context, client *domain.Client) error {
+	if err := s.repo.UpdateClient(ctx, client); err != nil {
+		errorText := fmt.Errorf("ошибка обновления клиента: %w", err)
+		slog.Error(errorText.Error())
+		return errorText
+	}
+	return nil
+}
+
+func (s *Client) validate(request clientModels.ClientRequest) error {
+	for _, grantType := range request.GrantTypes {
+		switch grantType {
+		case domain.GrantAuthorizationCode:
+			if len(request.RedirectUris) == 0 {
+				return clientErrors.ErrRedirectUriRequired
+			}
+		case domain.GrantRefreshToken, domain.GrantClientCredentials:
+		default:
+			return fmt.Errorf("%w: %s", clientErrors.ErrUnknownGrantType, grantType)
+		}
+	}
+	if request.PublicKey != "" {
+		if _, err := jwt.ParsePublicKey([]byte(request.PublicKey)); err != nil {
+			return fmt.Errorf("%w: %s", clientErrors.ErrInvalidPublicKey, err.Error())
+		}
+	}
+	for _, ttl := range []*int64{request.AccessTokenTTL, request.RefreshTokenTTL} {
+		if ttl == nil {
+			continue
+		}
+		if *ttl <= 0 || *ttl > int64(s.maxTokenTTL.Seconds()) {
+			return fmt.Errorf("%w: от 1 до %d секунд", clientErrors.ErrInvalidTokenTTL, int64(s.maxTokenTTL.Seconds()))
+		}
+	}
+	return nil
+}
+
+// ttlSeconds — время жизни для хранения, 0 - по умолчанию
+func ttlSeconds(ttl *int64) int64 {
+	if ttl == nil {
+		return 0
+	}
+	return *ttl
+}
+
+func toResponse(client *domain.Client, secret string) clientModels.ClientResponse {
+	return clientModels.ClientResponse{
+		Id:              client.Id,
+		Name:            client.Name,
+		RedirectUris:    client.RedirectUris,
+		GrantTypes:      client.GrantTypes,
+		Scopes:          client.Scopes,
+		AccessTokenTTL:  client.AccessTokenTTL,
+		RefreshTokenTTL: client.RefreshTokenTTL,
+		Audience:        client.Audience,
+		PublicKey:       client.PublicKey,
+		IsConfidential:  client.IsConfidential(),
+		IsDisabled:      client.IsDisabled,
+		CreationTime:    client.CreationTime,
+		Secret:          secret,
+	}
+}
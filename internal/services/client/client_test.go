@@ -0,0 +1,36 @@
+package client
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	clientModels "github.com/phenirain/sso/internal/dto/client"
+	clientErrors "github.com/phenirain/sso/internal/errors/client"
+)
+
+func TestValidateTokenTTL(t *testing.T) {
+	s := New(nil, time.Hour)
+	seconds := func(v int64) *int64 { return &v }
+	tests := []struct {
+		name    string
+		access  *int64
+		refresh *int64
+		wantErr bool
+	}{
+		{"defaults", nil, nil, false},
+		{"within limit", seconds(60), seconds(3600), false},
+		{"zero access", seconds(0), nil, true},
+		{"negative refresh", nil, seconds(-1), true},
+		{"refresh above key lifetime", nil, seconds(3601), true},
+		{"access above key lifetime", seconds(7200), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.validate(clientModels.ClientRequest{AccessTokenTTL: tt.access, RefreshTokenTTL: tt.refresh})
+			if got := errors.Is(err, clientErrors.ErrInvalidTokenTTL); got != tt.wantErr {
+				t.Errorf("validate = %v, want ErrInvalidTokenTTL: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
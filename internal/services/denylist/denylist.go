@@ -0,0 +1,122 @@
+package denylist
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/phenirain/sso/internal/lib/cache"
+	"github.com/phenirain/sso/pkg/claims"
+)
+
+type UserRepository interface {
+	GetTokensNotBefore(ctx context.Context, uid int64) (*time.Time, error)
+	SetTokensNotBefore(ctx context.Context, uid int64, notBefore time.Time) error
+}
+
+type Repository interface {
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsSessionRevoked(ctx context.Context, sessionId string) (bool, error)
+	RevokeSession(ctx context.Context, sessionId string, expiresAt time.Time) error
+}
+
+// Denylist — отозванные access токены: по jti, по сессии и по времени выпуска для пользователя.
+// Проверка идёт на каждый запрос, поэтому перед базой стоит кеш на cacheTTL:
+// отзыв на других репликах подхватывается не позже чем через cacheTTL
+type Denylist struct {
+	users     UserRepository
+	repo      Repository
+	notBefore *cache.TTLCache[int64, *time.Time]
+	revoked   *cache.TTLCache[string, bool]
+	sessions  *cache.TTLCache[string, bool]
+}
+
+func New(users UserRepository, repo Repository, cacheTTL time.Duration) *Denylist {
+	return &Denylist{
+		users:     users,
+		repo:      repo,
+		notBefore: cache.New[int64, *time.Time](cacheTTL),
+		revoked:   cache.New[string, bool](cacheTTL),
+		sessions:  cache.New[string, bool](cacheTTL),
+	}
+}
+
+func (d *Denylist) IsRevoked(ctx context.Context, tokenClaims *claims.Claims) (bool, error) {
+	// у токена сервиса нет пользователя - отзывается только по jti
+	if !tokenClaims.IsService() {
+		notBefore, ok := d.notBefore.Get(tokenClaims.UserId)
+		if !ok {
+			var err error
+			notBefore, err = d.users.GetTokensNotBefore(ctx, tokenClaims.UserId)
+			if err != nil {
+				return false, fmt.Errorf("ошибка получения времени отзыва токенов пользователя: %w", err)
+			}
+			d.notBefore.Set(tokenClaims.UserId, notBefore)
+		}
+		// токен, выпущенный в ту же микросекунду, что и отзыв, тоже отозван: порядок не различить
+		if notBefore != nil && !tokenClaims.IssuedAt.After(*notBefore) {
+			return true, nil
+		}
+	}
+
+	// токены, выпущенные до появления sid, отзываются только по jti и времени выпуска
+	if tokenClaims.SessionId != "" {
+		revoked, ok := d.sessions.Get(tokenClaims.SessionId)
+		if !ok {
+			var err error
+			revoked, err = d.repo.IsSessionRevoked(ctx, tokenClaims.SessionId)
+			if err != nil {
+				return false, fmt.Errorf("ошибка проверки отзыва сессии: %w", err)
+			}
+			d.sessions.Set(tokenClaims.SessionId, revoked)
+		}
+		if revoked {
+			return true, nil
+		}
+	}
+
+	if tokenClaims.TokenId == "" {
+		return false, nil
+	}
+	revoked, ok := d.revoked.Get(tokenClaims.TokenId)
+	if !ok {
+		var err error
+		revoked, err = d.repo.IsAccessTokenRevoked(ctx, tokenClaims.TokenId)
+		if err != nil {
+			return false, fmt.Errorf("ошибка проверки отзыва токена: %w", err)
+		}
+		d.revoked.Set(tokenClaims.TokenId, revoked)
+	}
+	return revoked, nil
+}
+
+// RevokeAccessToken отзывает один access токен
+func (d *Denylist) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	if err := d.repo.RevokeAccessToken(ctx, jti, expiresAt); err != nil {
+		return err
+	}
+	d.revoked.Set(jti, true)
+	return nil
+}
+
+// RevokeSession отзывает все access токены сессии sessionId
+func (d *Denylist) RevokeSession(ctx context.Context, sessionId string, expiresAt time.Time) error {
+	if err := d.repo.RevokeSession(ctx, sessionId, expiresAt); err != nil {
+		return err
+	}
+	d.sessions.Set(sessionId, true)
+	return nil
+}
+
+// RevokeUserTokens отзывает все access токены пользователя, выпущенные до текущего момента
+func (d *Denylist) RevokeUserTokens(ctx context.Context, userId int64) error {
+	notBefore := time.Now().Truncate(time.Microsecond)
+	if err := d.users.SetTokensNotBefore(ctx, userId, notBefore); err != nil {
+		return err
+	}
+	d.notBefore.Set(userId, &notBefore)
+	slog.Info("access tokens revoked", "user_id", userId)
+	return nil
+}
@@ -0,0 +1,150 @@
+package denylist
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/phenirain/sso/pkg/claims"
+)
+
+type fakeUsers struct {
+	notBefore map[int64]*time.Time
+	reads     int
+}
+
+func (r *fakeUsers) GetTokensNotBefore(ctx context.Context, uid int64) (*time.Time, error) {
+	r.reads++
+	return r.notBefore[uid], nil
+}
+
+func (r *fakeUsers) SetTokensNotBefore(ctx context.Context, uid int64, notBefore time.Time) error {
+	r.notBefore[uid] = &notBefore
+	return nil
+}
+
+type fakeRepo struct {
+	tokens   map[string]bool
+	sessions map[string]bool
+}
+
+func (r *fakeRepo) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	return r.tokens[jti], nil
+}
+
+func (r *fakeRepo) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	r.tokens[jti] = true
+	return nil
+}
+
+func (r *fakeRepo) IsSessionRevoked(ctx context.Context, sessionId string) (bool, error) {
+	return r.sessions[sessionId], nil
+}
+
+func (r *fakeRepo) RevokeSession(ctx context.Context, sessionId string, expiresAt time.Time) error {
+	r.sessions[sessionId] = true
+	return nil
+}
+
+func newTestDenylist(cacheTTL time.Duration) (*Denylist, *fakeUsers, *fakeRepo) {
+	users := &fakeUsers{notBefore: make(map[int64]*time.Time)}
+	repo := &fakeRepo{tokens: make(map[string]bool), sessions: make(map[string]bool)}
+	return New(users, repo, cacheTTL), users, repo
+}
+
+func userToken(jti, sid string, issuedAt time.Time) *claims.Claims {
+	return &claims.Claims{UserId: 1, RoleId: 1, TokenType: claims.TokenTypeAccess, TokenId: jti, SessionId: sid, IssuedAt: issuedAt}
+}
+
+func TestIsRevoked(t *testing.T) {
+	ctx := context.Background()
+	d, _, _ := newTestDenylist(time.Minute)
+
+	beforeLogout := time.Now().Truncate(time.Microsecond)
+	if err := d.RevokeUserTokens(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	notBefore, _ := d.notBefore.Get(2)
+	if err := d.RevokeAccessToken(ctx, "revoked", time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.RevokeSession(ctx, "closed", time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	otherUser := func(c *claims.Claims) *claims.Claims {
+		c.UserId = 2
+		return c
+	}
+	tests := []struct {
+		name   string
+		claims *claims.Claims
+		want   bool
+	}{
+		{"active token", userToken("active", "open", time.Now()), false},
+		{"revoked jti", userToken("revoked", "open", time.Now()), true},
+		{"revoked session", userToken("active", "closed", time.Now()), true},
+		{"token without sid", userToken("active", "", time.Now()), false},
+		{"issued before logout all", otherUser(userToken("active", "open", beforeLogout)), true},
+		// iat в ту же секунду, что и отзыв, но раньше него - раньше округление до секунды его пропускало
+		{"issued earlier in the same second", otherUser(userToken("active", "open", notBefore.Add(-time.Microsecond))), true},
+		{"issued at the same microsecond", otherUser(userToken("active", "open", *notBefore)), true},
+		{"issued after logout all", otherUser(userToken("active", "open", notBefore.Add(time.Microsecond))), false},
+		{"service token revoked by jti", &claims.Claims{ClientId: "worker", TokenId: "revoked"}, true},
+		{"service token", &claims.Claims{ClientId: "worker", TokenId: "active"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := d.IsRevoked(ctx, tt.claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("IsRevoked: got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Отзыв на другой реплике пишется только в базу: здесь он виден после истечения кеша
+func TestIsRevokedCacheExpiry(t *testing.T) {
+	const cacheTTL = time.Millisecond * 50
+	ctx := context.Background()
+	d, users, repo := newTestDenylist(cacheTTL)
+	token := userToken("jti", "sid", time.Now())
+
+	tests := []struct {
+		name   string
+		revoke func()
+	}{
+		{"jti", func() { repo.tokens["jti"] = true }},
+		{"session", func() { repo.sessions["sid"] = true }},
+		{"not before", func() {
+			notBefore := time.Now()
+			users.notBefore[1] = &notBefore
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users.notBefore, repo.tokens, repo.sessions = make(map[int64]*time.Time), make(map[string]bool), make(map[string]bool)
+			time.Sleep(cacheTTL * 2)
+			if revoked, _ := d.IsRevoked(ctx, token); revoked {
+				t.Fatal("token revoked before revocation")
+			}
+
+			tt.revoke()
+			reads := users.reads
+			if revoked, _ := d.IsRevoked(ctx, token); revoked {
+				t.Error("revocation seen before cache expiry")
+			}
+			if users.reads != reads {
+				t.Error("tokens_not_before read again before cache expiry")
+			}
+
+			time.Sleep(cacheTTL * 2)
+			if revoked, _ := d.IsRevoked(ctx, token); !revoked {
+				t.Error("revocation not seen after cache expiry")
+			}
+		})
+	}
+}
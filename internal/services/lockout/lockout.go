@@ -0,0 +1,232 @@
+package lockout
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phenirain/sso/internal/domain"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+)
+
+type Repository interface {
+	GetAttempts(ctx context.Context, key string) (*domain.LoginAttempts, error)
+	SaveAttempts(ctx context.Context, previous, next *domain.LoginAttempts) (bool, error)
+	ReleaseAttempt(ctx context.Context, key string) error
+	DeleteAttempts(ctx context.Context, key string) error
+	DeleteExpired(ctx context.Context, before time.Time) error
+}
+
+// Policy — как растёт задержка с числом неудачных попыток подряд
+type Policy struct {
+	// Сколько неудач подряд проходят без задержки
+	FreeAttempts int
+	// После стольких неудач - блокировка на LockoutDuration
+	MaxFailures int
+	// Задержка после первой неудачи сверх FreeAttempts, дальше удваивается
+	BaseDelay time.Duration
+	// Блокировка после MaxFailures, каждая следующая неудача её удваивает
+	LockoutDuration time.Duration
+	// Верхняя граница задержки и блокировки
+	MaxLockout time.Duration
+}
+
+// Delay — сколько ждать после failures неудач подряд
+func (p Policy) Delay(failures int) time.Duration {
+	var delay time.Duration
+	switch {
+	case failures <= p.FreeAttempts:
+		return 0
+	case failures < p.MaxFailures:
+		delay = backoff(p.BaseDelay, failures-p.FreeAttempts-1)
+	default:
+		delay = backoff(p.LockoutDuration, failures-p.MaxFailures)
+	}
+	if delay > p.MaxLockout {
+		return p.MaxLockout
+	}
+	return delay
+}
+
+// backoff — base * 2^exponent без переполнения
+func backoff(base time.Duration, exponent int) time.Duration {
+	delay := base
+	for i := 0; i < exponent && delay < time.Hour*24*365; i++ {
+		delay *= 2
+	}
+	return delay
+}
+
+// Префиксы ключей счётчиков
+const (
+	loginKeyPrefix = "login:"
+	ipKeyPrefix    = "ip:"
+)
+
+// maxSaveRetries — сколько раз перечитываем счётчик, если его успел изменить параллельный запрос
+const maxSaveRetries = 5
+
+// purgeInterval — как часто вычищаются счётчики старше window
+const purgeInterval = time.Minute
+
+// Lockout защищает вход по паролю от перебора: считает неудачные попытки отдельно
+// по логину (перебор паролей одного пользователя) и по IP (перебор логинов с одного адреса).
+// Попытка засчитывается неудачной заранее, в Attempt, и только потом проверяется пароль:
+// так параллельные запросы не проскакивают проверку до того, как счётчик увеличится
+type Lockout struct {
+	repo  Repository
+	login Policy
+	ip    Policy
+	// через сколько после последней неудачи счётчик обнуляется
+	window time.Duration
+
+	mu        sync.Mutex
+	lastPurge time.Time
+}
+
+// New — window не меньше максимальной блокировки, иначе счётчик обнулится раньше,
+// чем она закончится
+func New(repo Repository, login, ip Policy, window time.Duration) *Lockout {
+	window = max(window, login.MaxLockout, ip.MaxLockout)
+	return &Lockout{
+		repo:      repo,
+		login:     login,
+		ip:        ip,
+		window:    window,
+		lastPurge: time.Now(),
+	}
+}
+
+// Attempt засчитывает попытку входа по логину с адреса ip как неудачную. Если вход сейчас
+// заблокирован, попытка не засчитывается и возвращается *authErrors.LockoutError.
+// Пустой ip не учитывается. После верных учётных данных нужно вызвать Release или Success
+func (l *Lockout) Attempt(ctx context.Context, login, ip string) error {
+	l.purge(ctx)
+
+	if err := l.reserve(ctx, loginKey(login), l.login); err != nil {
+		return err
+	}
+	if ip == "" {
+		return nil
+	}
+	if err := l.reserve(ctx, ipKeyPrefix+ip, l.ip); err != nil {
+		// отказ по IP - попытку логина тоже не считаем
+		l.release(ctx, loginKey(login))
+		return err
+	}
+	return nil
+}
+
+// AttemptIp засчитывает запрос с адреса ip в счётчик IP - для действий, которые не проверяют
+// пароль, но позволяют перебирать логины (сброс пароля). Такая попытка не отменяется.
+// Пустой ip не учитывается
+func (l *Lockout) AttemptIp(ctx context.Context, ip string) error {
+	if ip == "" {
+		return nil
+	}
+	l.purge(ctx)
+	return l.reserve(ctx, ipKeyPrefix+ip, l.ip)
+}
+
+// Release отменяет попытку из Attempt: пароль верен, но вход ещё не завершён -
+// впереди второй фактор. Счётчик логина при этом не обнуляется
+func (l *Lockout) Release(ctx context.Context, login, ip string) {
+	l.release(ctx, loginKey(login))
+	if ip != "" {
+		l.release(ctx, ipKeyPrefix+ip)
+	}
+}
+
+// Success — вход завершён: счётчик логина обнуляется, попытка с ip не засчитывается.
+// Прежние неудачи с ip не прощаются: иначе вход в свою учётную запись позволял бы
+// перебирать чужие дальше
+func (l *Lockout) Success(ctx context.Context, login, ip string) {
+	if err := l.repo.DeleteAttempts(ctx, loginKey(login)); err != nil {
+		slog.Error("failed to reset login failures", "err", err)
+	}
+	if ip != "" {
+		l.release(ctx, ipKeyPrefix+ip)
+	}
+}
+
+// Unlock снимает блокировку логина и (или) адреса - для администратора и после сброса пароля
+func (l *Lockout) Unlock(ctx context.Context, login, ip string) error {
+	if login != "" {
+		if err := l.repo.DeleteAttempts(ctx, loginKey(login)); err != nil {
+			return err
+		}
+	}
+	if ip != "" {
+		if err := l.repo.DeleteAttempts(ctx, ipKeyPrefix+ip); err != nil {
+			return err
+		}
+	}
+
+	slog.Info("login unlocked", "login", login, "ip", ip)
+	return nil
+}
+
+// reserve увеличивает счётчик key, если он не заблокирован. Запись условная: если счётчик
+// изменился с момента чтения, читаем его заново
+func (l *Lockout) reserve(ctx context.Context, key string, policy Policy) error {
+	for range maxSaveRetries {
+		previous, err := l.repo.GetAttempts(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		next := &domain.LoginAttempts{Key: key, Failures: 1, LastFailureTime: now}
+		if previous != nil && !previous.LastFailureTime.Before(now.Add(-l.window)) {
+			lockedUntil := previous.LastFailureTime.Add(policy.Delay(previous.Failures))
+			if now.Before(lockedUntil) {
+				return &authErrors.LockoutError{RetryAfter: lockedUntil.Sub(now)}
+			}
+			next.Failures = previous.Failures + 1
+		}
+
+		saved, err := l.repo.SaveAttempts(ctx, previous, next)
+		if err != nil {
+			return err
+		}
+		if saved {
+			if next.Failures == policy.MaxFailures {
+				slog.Warn("login locked out", "key", key, "failures", next.Failures)
+			}
+			return nil
+		}
+	}
+	// счётчик непрерывно меняют параллельные запросы - это и есть перебор
+	return &authErrors.LockoutError{RetryAfter: time.Second}
+}
+
+// release возвращает попытку, засчитанную в reserve. Ошибки хранилища только логируются:
+// в худшем случае верный вход останется засчитанным как неудачный
+func (l *Lockout) release(ctx context.Context, key string) {
+	if err := l.repo.ReleaseAttempt(ctx, key); err != nil {
+		slog.Error("failed to release login attempt", "err", err)
+	}
+}
+
+// purge вычищает счётчики, которые не менялись дольше window, не чаще раза в purgeInterval
+func (l *Lockout) purge(ctx context.Context) {
+	l.mu.Lock()
+	now := time.Now()
+	if now.Sub(l.lastPurge) < purgeInterval {
+		l.mu.Unlock()
+		return
+	}
+	l.lastPurge = now
+	l.mu.Unlock()
+
+	if err := l.repo.DeleteExpired(ctx, now.Add(-l.window)); err != nil {
+		slog.Warn("failed to purge expired login attempts", "err", err)
+	}
+}
+
+// loginKey — регистр и пробелы по краям не дают обойти счётчик
+func loginKey(login string) string {
+	return loginKeyPrefix + strings.ToLower(strings.TrimSpace(login))
+}
@@ -0,0 +1,231 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/phenirain/sso/internal/domain"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	"github.com/phenirain/sso/internal/repository/loginattempt"
+)
+
+var testPolicy = Policy{
+	FreeAttempts:    3,
+	MaxFailures:     10,
+	BaseDelay:       time.Second,
+	LockoutDuration: time.Minute * 15,
+	MaxLockout:      time.Hour * 24,
+}
+
+func TestPolicyDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, 0},
+		{3, 0},
+		{4, time.Second},
+		{5, time.Second * 2},
+		{9, time.Second * 32},
+		{10, time.Minute * 15},
+		{11, time.Minute * 30},
+		{16, time.Hour * 16},
+		{17, time.Hour * 24},
+		{1000, time.Hour * 24},
+	}
+	for _, tt := range tests {
+		if got := testPolicy.Delay(tt.failures); got != tt.want {
+			t.Errorf("Delay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestAttemptLocksAfterFreeAttempts(t *testing.T) {
+	ctx := context.Background()
+	l := New(loginattempt.NewMemory(), testPolicy, testPolicy, time.Hour)
+
+	for i := range testPolicy.FreeAttempts + 1 {
+		if err := l.Attempt(ctx, "user@example.com", ""); err != nil {
+			t.Fatalf("attempt %d: unexpected error %v", i+1, err)
+		}
+	}
+
+	err := l.Attempt(ctx, " USER@example.com", "")
+	var lockoutErr *authErrors.LockoutError
+	if !errors.As(err, &lockoutErr) || !errors.Is(err, authErrors.ErrTooManyAttempts) {
+		t.Fatalf("expected lockout error, got %v", err)
+	}
+	if lockoutErr.RetryAfter <= 0 || lockoutErr.RetryAfter > testPolicy.BaseDelay {
+		t.Errorf("RetryAfter = %v, want (0, %v]", lockoutErr.RetryAfter, testPolicy.BaseDelay)
+	}
+}
+
+func TestConcurrentAttemptsCannotBypassLimit(t *testing.T) {
+	ctx := context.Background()
+	policy := testPolicy
+	policy.BaseDelay = time.Hour
+	l := New(loginattempt.NewMemory(), policy, policy, time.Hour)
+
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if l.Attempt(ctx, "user@example.com", "10.0.0.1") == nil {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// без задержки проходят FreeAttempts неудач и ещё одна попытка после них
+	if got := allowed.Load(); got != int32(policy.FreeAttempts+1) {
+		t.Errorf("allowed %d attempts, want %d", got, policy.FreeAttempts+1)
+	}
+}
+
+func TestLockLifted(t *testing.T) {
+	ctx := context.Background()
+	window := time.Hour * 24
+	tests := []struct {
+		name         string
+		failures     int
+		lastFailure  time.Duration
+		wantFailures int
+	}{
+		{"after lockout", testPolicy.MaxFailures, testPolicy.LockoutDuration + time.Second, testPolicy.MaxFailures + 1},
+		{"after window", 100, window + time.Second, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := loginattempt.NewMemory()
+			key := loginKey("user@example.com")
+			previous := &domain.LoginAttempts{Key: key, Failures: tt.failures, LastFailureTime: time.Now().Add(-tt.lastFailure)}
+			if _, err := repo.SaveAttempts(ctx, nil, previous); err != nil {
+				t.Fatal(err)
+			}
+			l := New(repo, testPolicy, testPolicy, window)
+
+			if err := l.Attempt(ctx, "user@example.com", ""); err != nil {
+				t.Fatalf("expected lock to be lifted, got %v", err)
+			}
+			attempts, _ := repo.GetAttempts(ctx, key)
+			if attempts.Failures != tt.wantFailures {
+				t.Errorf("failures = %d, want %d", attempts.Failures, tt.wantFailures)
+			}
+		})
+	}
+}
+
+func TestSuccessAndRelease(t *testing.T) {
+	ctx := context.Background()
+	repo := loginattempt.NewMemory()
+	l := New(repo, testPolicy, testPolicy, time.Hour)
+
+	for range 2 {
+		if err := l.Attempt(ctx, "user@example.com", "10.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// верный пароль, впереди второй фактор: попытка отменяется, прежняя неудача остаётся
+	if err := l.Attempt(ctx, "user@example.com", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	l.Release(ctx, "user@example.com", "10.0.0.1")
+	assertFailures(t, repo, loginKey("user@example.com"), 2)
+	assertFailures(t, repo, ipKeyPrefix+"10.0.0.1", 2)
+
+	if err := l.Attempt(ctx, "user@example.com", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	l.Success(ctx, "user@example.com", "10.0.0.1")
+	assertFailures(t, repo, loginKey("user@example.com"), 0)
+	assertFailures(t, repo, ipKeyPrefix+"10.0.0.1", 2)
+}
+
+func TestIpLockDoesNotCountLogin(t *testing.T) {
+	ctx := context.Background()
+	repo := loginattempt.NewMemory()
+	ipPolicy := testPolicy
+	ipPolicy.FreeAttempts = 0
+	ipPolicy.BaseDelay = time.Hour
+	l := New(repo, testPolicy, ipPolicy, time.Hour)
+
+	if err := l.Attempt(ctx, "first@example.com", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Attempt(ctx, "second@example.com", "10.0.0.1"); !errors.Is(err, authErrors.ErrTooManyAttempts) {
+		t.Fatalf("expected ip lockout, got %v", err)
+	}
+	assertFailures(t, repo, loginKey("second@example.com"), 0)
+
+	if err := l.Unlock(ctx, "", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Attempt(ctx, "second@example.com", "10.0.0.1"); err != nil {
+		t.Fatalf("expected unlocked ip, got %v", err)
+	}
+}
+
+func TestAttemptIpSharesIpCounter(t *testing.T) {
+	ctx := context.Background()
+	repo := loginattempt.NewMemory()
+	l := New(repo, testPolicy, testPolicy, time.Hour)
+
+	for i := 0; i < testPolicy.FreeAttempts; i++ {
+		if err := l.AttemptIp(ctx, "10.0.0.1"); err != nil {
+			t.Fatalf("attempt %d: %v", i+1, err)
+		}
+	}
+	assertFailures(t, repo, ipKeyPrefix+"10.0.0.1", testPolicy.FreeAttempts)
+
+	// счётчик общий с попытками входа
+	if err := l.Attempt(ctx, "user@example.com", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.AttemptIp(ctx, "10.0.0.1"); !errors.Is(err, authErrors.ErrTooManyAttempts) {
+		t.Fatalf("expected ip lockout, got %v", err)
+	}
+	if err := l.AttemptIp(ctx, ""); err != nil {
+		t.Fatalf("empty ip must not be limited, got %v", err)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	ctx := context.Background()
+	repo := loginattempt.NewMemory()
+	stale := &domain.LoginAttempts{Key: ipKeyPrefix + "10.0.0.1", Failures: 5, LastFailureTime: time.Now().Add(-time.Hour * 48)}
+	if _, err := repo.SaveAttempts(ctx, nil, stale); err != nil {
+		t.Fatal(err)
+	}
+	l := New(repo, testPolicy, testPolicy, time.Hour)
+	l.lastPurge = time.Now().Add(-purgeInterval)
+
+	if err := l.Attempt(ctx, "user@example.com", ""); err != nil {
+		t.Fatal(err)
+	}
+	if attempts, _ := repo.GetAttempts(ctx, stale.Key); attempts != nil {
+		t.Errorf("expected stale counter to be purged, got %+v", attempts)
+	}
+}
+
+func assertFailures(t *testing.T, repo Repository, key string, want int) {
+	t.Helper()
+	attempts, err := repo.GetAttempts(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := 0
+	if attempts != nil {
+		got = attempts.Failures
+	}
+	if got != want {
+		t.Errorf("%s: failures = %d, want %d", key, got, want)
+	}
+}
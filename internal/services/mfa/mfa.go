@@ -0,0 +1,466 @@
+package mfa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/internal/dto/auth"
+	passkeyModels "github.com/phenirain/sso/internal/dto/passkey"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	jwtLib "github.com/phenirain/sso/internal/lib/jwt"
+	"github.com/phenirain/sso/internal/lib/totp"
+	"github.com/phenirain/sso/pkg/claims"
+	"github.com/phenirain/sso/pkg/contextkeys"
+)
+
+type Repository interface {
+	GetUserWithId(ctx context.Context, uid int64) (*domain.User, error)
+	UpdateTotp(ctx context.Context, user *domain.User) error
+	UseTotpStep(ctx context.Context, uid int64, step int64) (bool, error)
+	AddMfaAttempt(ctx context.Context, uid int64) (int, error)
+	ResetMfaAttempts(ctx context.Context, uid int64) error
+}
+
+type RecoveryCodeRepository interface {
+	ReplaceRecoveryCodes(ctx context.Context, userId int64, hashes []string) error
+	UseRecoveryCode(ctx context.Context, userId int64, hash string) (bool, error)
+	DeleteRecoveryCodes(ctx context.Context, userId int64) error
+}
+
+// Sessions — выпуск токенов после второго шага входа
+type Sessions interface {
+	CheckIdTokenClient(ctx context.Context, clientId string) error
+	IssueTokens(ctx context.Context, user *domain.User, client *domain.Client, scope string, idToken *jwtLib.IdTokenParams) (*auth.AuthResponse, error)
+}
+
+type Jwt interface {
+	ParseToken(tokenString string, tokenType claims.TokenType) (*claims.Claims, error)
+}
+
+type Denylist interface {
+	IsRevoked(ctx context.Context, tokenClaims *claims.Claims) (bool, error)
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+}
+
+// Encryptor шифрует секрет TOTP для хранения в базе
+type Encryptor interface {
+	Encrypt(plaintext string) (string, error)
+	Decrypt(ciphertext string) (string, error)
+}
+
+// Passkeys — ключ WebAuthn вместо кода на втором шаге входа
+type Passkeys interface {
+	SecondFactorOptions(ctx context.Context, user *domain.User) (*passkeyModels.RequestOptions, error)
+	VerifySecondFactor(ctx context.Context, user *domain.User, credential passkeyModels.Credential) error
+}
+
+// Limiter — блокировка входа по логину и IP. Проверка второго фактора - такая же попытка входа,
+// как проверка пароля: неверный код засчитывается неудачей, верный - завершает вход
+type Limiter interface {
+	Attempt(ctx context.Context, login, ip string) error
+	Release(ctx context.Context, login, ip string)
+	Success(ctx context.Context, login, ip string)
+}
+
+type Mfa struct {
+	repo      Repository
+	codes     RecoveryCodeRepository
+	sessions  Sessions
+	jwt       Jwt
+	denylist  Denylist
+	encryptor Encryptor
+	passkeys  Passkeys
+	limiter   Limiter
+	// название сервиса в приложении-аутентификаторе
+	issuer string
+}
+
+// New — encryptor nil, если ключ шифрования не настроен: подключить 2FA тогда нельзя
+func New(repo Repository, codes RecoveryCodeRepository, sessions Sessions, jwt Jwt, denylist Denylist, encryptor Encryptor, passkeys Passkeys, limiter Limiter, issuer string) *Mfa {
+	return &Mfa{
+		repo:      repo,
+		codes:     codes,
+		sessions:  sessions,
+		jwt:       jwt,
+		denylist:  denylist,
+		encryptor: encryptor,
+		passkeys:  passkeys,
+		limiter:   limiter,
+		issuer:    issuer,
+	}
+}
+
+// Enroll создаёт новый секрет TOTP. Двухфакторная аутентификация включится только после
+// Confirm с первым кодом из приложения, до этого повторный Enroll заменяет секрет
+func (m *Mfa) Enroll(ctx context.Context, userId int64) (*auth.MfaEnrollResponse, error) {
+	if m.encryptor == nil {
+		return nil, authErrors.ErrMfaUnavailable
+	}
+	user, err := m.getUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.TotpEnabled {
+		return nil, authErrors.ErrMfaAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := m.encryptor.Encrypt(secret)
+	if err != nil {
+		errorText := fmt.Errorf("ошибка шифрования секрета TOTP: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+	user.TotpSecret = &encrypted
+	user.TotpLastStep = 0
+	if err := m.repo.UpdateTotp(ctx, user); err != nil {
+		errorText := fmt.Errorf("ошибка сохранения секрета TOTP: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+
+	return &auth.MfaEnrollResponse{
+		Secret: secret,
+		Uri:    totp.URI(m.issuer, user.Login, secret),
+	}, nil
+}
+
+// Confirm включает двухфакторную аутентификацию, если code сгенерирован приложением
+// по секрету из Enroll, и выдаёт резервные коды
+func (m *Mfa) Confirm(ctx context.Context, userId int64, code string) (*auth.RecoveryCodesResponse, error) {
+	user, err := m.getUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.TotpEnabled {
+		return nil, authErrors.ErrMfaAlreadyEnabled
+	}
+	if user.TotpSecret == nil {
+		return nil, authErrors.ErrMfaNotEnabled
+	}
+
+	ok, err := m.checkTotp(user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, authErrors.ErrInvalidMfaCode
+	}
+
+	response, err := m.newRecoveryCodes(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+	user.TotpEnabled = true
+	if err := m.repo.UpdateTotp(ctx, user); err != nil {
+		errorText := fmt.Errorf("ошибка включения двухфакторной аутентификации: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+
+	slog.Info("mfa enabled", "user_id", user.Id)
+	return response, nil
+}
+
+// Disable отключает двухфакторную аутентификацию. Нужен действующий код или резервный код
+func (m *Mfa) Disable(ctx context.Context, userId int64, code string) error {
+	user, err := m.getUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if !user.TotpEnabled {
+		return authErrors.ErrMfaNotEnabled
+	}
+	if err := m.CheckCode(ctx, user, code); err != nil {
+		return err
+	}
+	return m.disable(ctx, user)
+}
+
+// Reset отключает двухфакторную аутентификацию без кода - для администратора, когда
+// пользователь потерял и телефон, и резервные коды
+func (m *Mfa) Reset(ctx context.Context, userId int64) error {
+	user, err := m.getUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if !user.TotpEnabled && user.TotpSecret == nil {
+		return authErrors.ErrMfaNotEnabled
+	}
+	return m.disable(ctx, user)
+}
+
+func (m *Mfa) disable(ctx context.Context, user *domain.User) error {
+	user.DisableTotp()
+	if err := m.repo.UpdateTotp(ctx, user); err != nil {
+		errorText := fmt.Errorf("ошибка отключения двухфакторной аутентификации: %w", err)
+		slog.Error(errorText.Error())
+		return errorText
+	}
+	if err := m.codes.DeleteRecoveryCodes(ctx, user.Id); err != nil {
+		return err
+	}
+	if err := m.repo.ResetMfaAttempts(ctx, user.Id); err != nil {
+		return err
+	}
+
+	slog.Info("mfa disabled", "user_id", user.Id)
+	return nil
+}
+
+// RegenerateRecoveryCodes выдаёт новые резервные коды взамен всех прежних
+func (m *Mfa) RegenerateRecoveryCodes(ctx context.Context, userId int64, code string) (*auth.RecoveryCodesResponse, error) {
+	user, err := m.getUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TotpEnabled {
+		return nil, authErrors.ErrMfaNotEnabled
+	}
+	if err := m.CheckCode(ctx, user, code); err != nil {
+		return nil, err
+	}
+	return m.newRecoveryCodes(ctx, user.Id)
+}
+
+// Verify завершает вход: проверяет токен из Auth и код, выпускает токены.
+// Токен mfa одноразовый - после неверного кода нужно снова ввести пароль
+func (m *Mfa) Verify(ctx context.Context, mfaToken, code string) (*auth.AuthResponse, error) {
+	tokenClaims, user, err := m.useMfaToken(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.CheckCode(ctx, user, code); err != nil {
+		return nil, err
+	}
+	return m.issueTokens(ctx, user, tokenClaims)
+}
+
+// PasskeyOptions выдаёт options для подтверждения входа ключом вместо кода.
+// Токен mfa здесь не гасится - он понадобится в VerifyPasskey
+func (m *Mfa) PasskeyOptions(ctx context.Context, mfaToken string) (*passkeyModels.RequestOptions, error) {
+	tokenClaims, err := m.parseMfaToken(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	user, err := m.getUser(ctx, tokenClaims.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsArchived || !user.RequiresSecondFactor() {
+		return nil, authErrors.ErrInvalidMfaToken
+	}
+	return m.passkeys.SecondFactorOptions(ctx, user)
+}
+
+// VerifyPasskey завершает вход ключом вместо кода, токен mfa одноразовый так же, как в Verify
+func (m *Mfa) VerifyPasskey(ctx context.Context, mfaToken string, credential passkeyModels.Credential) (*auth.AuthResponse, error) {
+	tokenClaims, user, err := m.useMfaToken(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.CheckPasskey(ctx, user, credential); err != nil {
+		return nil, err
+	}
+	return m.issueTokens(ctx, user, tokenClaims)
+}
+
+// UserPasskeyOptions выдаёт options для проверки ключа пользователя, который уже ввёл пароль, -
+// для страницы входа OAuth, где токена mfa нет
+func (m *Mfa) UserPasskeyOptions(ctx context.Context, user *domain.User) (*passkeyModels.RequestOptions, error) {
+	return m.passkeys.SecondFactorOptions(ctx, user)
+}
+
+// CheckPasskey проверяет второй фактор пользователя ключом WebAuthn, как CheckCode - кодом
+func (m *Mfa) CheckPasskey(ctx context.Context, user *domain.User, credential passkeyModels.Credential) error {
+	return m.guard(ctx, user, func() error {
+		return m.passkeys.VerifySecondFactor(ctx, user, credential)
+	})
+}
+
+// parseMfaToken проверяет подпись и отзыв токена mfa
+func (m *Mfa) parseMfaToken(ctx context.Context, mfaToken string) (*claims.Claims, error) {
+	tokenClaims, err := m.jwt.ParseToken(mfaToken, claims.TokenTypeMfa)
+	if err != nil {
+		slog.Info("invalid mfa token", "err", err)
+		return nil, authErrors.ErrInvalidMfaToken
+	}
+	revoked, err := m.denylist.IsRevoked(ctx, tokenClaims)
+	if err != nil {
+		errorText := fmt.Errorf("ошибка проверки отзыва токена: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+	if revoked {
+		return nil, authErrors.ErrInvalidMfaToken
+	}
+	return tokenClaims, nil
+}
+
+// useMfaToken гасит токен mfa и возвращает пользователя, который ввёл пароль
+func (m *Mfa) useMfaToken(ctx context.Context, mfaToken string) (*claims.Claims, *domain.User, error) {
+	tokenClaims, err := m.parseMfaToken(ctx, mfaToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := m.denylist.RevokeAccessToken(ctx, tokenClaims.TokenId, tokenClaims.ExpiresAt); err != nil {
+		return nil, nil, err
+	}
+
+	user, err := m.getUser(ctx, tokenClaims.UserId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.IsArchived || !user.RequiresSecondFactor() {
+		return nil, nil, authErrors.ErrInvalidMfaToken
+	}
+	return tokenClaims, user, nil
+}
+
+// issueTokens выпускает токены после второго фактора, ID токен - если его просил клиент при входе
+func (m *Mfa) issueTokens(ctx context.Context, user *domain.User, tokenClaims *claims.Claims) (*auth.AuthResponse, error) {
+	var idToken *jwtLib.IdTokenParams
+	if tokenClaims.ClientId != "" {
+		// клиента могли отключить, пока пользователь вводил код
+		if err := m.sessions.CheckIdTokenClient(ctx, tokenClaims.ClientId); err != nil {
+			return nil, err
+		}
+		idToken = &jwtLib.IdTokenParams{
+			Audience: tokenClaims.ClientId,
+			Nonce:    tokenClaims.Nonce,
+			// пароль введён при выпуске токена mfa
+			AuthTime: tokenClaims.IssuedAt,
+		}
+	}
+	return m.sessions.IssueTokens(ctx, user, nil, "", idToken)
+}
+
+// CheckCode проверяет второй фактор пользователя: код TOTP или резервный код.
+// После domain.MaxMfaAttempts неверных проверок подряд принимаются только резервные коды
+func (m *Mfa) CheckCode(ctx context.Context, user *domain.User, code string) error {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return authErrors.ErrMfaRequired
+	}
+	return m.guard(ctx, user, func() error {
+		return m.checkCode(ctx, user, code)
+	})
+}
+
+func (m *Mfa) checkCode(ctx context.Context, user *domain.User, code string) error {
+	// попытку учитываем до проверки, чтобы параллельные запросы не обошли предел
+	attempts, err := m.repo.AddMfaAttempt(ctx, user.Id)
+	if err != nil {
+		return err
+	}
+
+	if len(code) == totp.Digits {
+		if attempts > domain.MaxMfaAttempts {
+			return authErrors.ErrTooManyMfaCodes
+		}
+		ok, err := m.checkTotp(user, code)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return authErrors.ErrInvalidMfaCode
+		}
+		// шаг запоминаем, чтобы тот же код нельзя было ввести ещё раз, в том числе параллельно
+		used, err := m.repo.UseTotpStep(ctx, user.Id, user.TotpLastStep)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return authErrors.ErrInvalidMfaCode
+		}
+	} else {
+		used, err := m.codes.UseRecoveryCode(ctx, user.Id, domain.HashRecoveryCode(code))
+		if err != nil {
+			return err
+		}
+		if !used {
+			return authErrors.ErrInvalidMfaCode
+		}
+		slog.Info("recovery code used", "user_id", user.Id)
+	}
+
+	return m.repo.ResetMfaAttempts(ctx, user.Id)
+}
+
+// guard выполняет проверку второго фактора как попытку входа: неудача остаётся засчитанной
+// в блокировке по логину и IP, успех завершает вход и обнуляет счётчик логина
+func (m *Mfa) guard(ctx context.Context, user *domain.User, check func() error) error {
+	ip, _ := ctx.Value(contextkeys.ClientIPCtxKey).(string)
+	if err := m.limiter.Attempt(ctx, user.Login, ip); err != nil {
+		return err
+	}
+	if err := check(); err != nil {
+		// ошибка сервера, а не неверный код - попытку не засчитываем
+		if !isFailure(err) {
+			m.limiter.Release(ctx, user.Login, ip)
+		}
+		return err
+	}
+	m.limiter.Success(ctx, user.Login, ip)
+	return nil
+}
+
+// isFailure — второй фактор предъявлен, но не подошёл
+func isFailure(err error) bool {
+	return errors.Is(err, authErrors.ErrInvalidMfaCode) ||
+		errors.Is(err, authErrors.ErrTooManyMfaCodes) ||
+		errors.Is(err, authErrors.ErrInvalidPasskey) ||
+		errors.Is(err, authErrors.ErrPasskeyCloned)
+}
+
+// checkTotp сверяет код с секретом пользователя и при успехе запоминает его шаг в user
+func (m *Mfa) checkTotp(user *domain.User, code string) (bool, error) {
+	if user.TotpSecret == nil {
+		return false, nil
+	}
+	if m.encryptor == nil {
+		return false, authErrors.ErrMfaUnavailable
+	}
+	secret, err := m.encryptor.Decrypt(*user.TotpSecret)
+	if err != nil {
+		errorText := fmt.Errorf("ошибка расшифровки секрета TOTP: %w", err)
+		slog.Error(errorText.Error())
+		return false, errorText
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now(), user.TotpLastStep)
+	if !ok {
+		return false, nil
+	}
+	user.TotpLastStep = step
+	return true, nil
+}
+
+func (m *Mfa) newRecoveryCodes(ctx context.Context, userId int64) (*auth.RecoveryCodesResponse, error) {
+	codes, hashes := domain.NewRecoveryCodes()
+	if err := m.codes.ReplaceRecoveryCodes(ctx, userId, hashes); err != nil {
+		return nil, err
+	}
+	return &auth.RecoveryCodesResponse{Codes: codes}, nil
+}
+
+func (m *Mfa) getUser(ctx context.Context, userId int64) (*domain.User, error) {
+	user, err := m.repo.GetUserWithId(ctx, userId)
+	if err != nil {
+		errorText := fmt.Errorf("ошибка получения пользователя по идентфикатору: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+	if user == nil {
+		return nil, authErrors.ErrUserNotFound
+	}
+	return user, nil
+}
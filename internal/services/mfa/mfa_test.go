@@ -0,0 +1,350 @@
+package mfa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/internal/dto/auth"
+	passkeyModels "github.com/phenirain/sso/internal/dto/passkey"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	jwtLib "github.com/phenirain/sso/internal/lib/jwt"
+	"github.com/phenirain/sso/internal/lib/totp"
+	"github.com/phenirain/sso/pkg/claims"
+)
+
+// plainEncryptor хранит секрет как есть
+type plainEncryptor struct{}
+
+func (plainEncryptor) Encrypt(plaintext string) (string, error)  { return plaintext, nil }
+func (plainEncryptor) Decrypt(ciphertext string) (string, error) { return ciphertext, nil }
+
+type fakeUsers struct {
+	lastStep    int64
+	mfaAttempts int
+}
+
+func (r *fakeUsers) GetUserWithId(ctx context.Context, uid int64) (*domain.User, error) {
+	return nil, nil
+}
+
+func (r *fakeUsers) UpdateTotp(ctx context.Context, user *domain.User) error {
+	return nil
+}
+
+func (r *fakeUsers) UseTotpStep(ctx context.Context, uid int64, step int64) (bool, error) {
+	if step <= r.lastStep {
+		return false, nil
+	}
+	r.lastStep = step
+	return true, nil
+}
+
+func (r *fakeUsers) AddMfaAttempt(ctx context.Context, uid int64) (int, error) {
+	r.mfaAttempts++
+	return r.mfaAttempts, nil
+}
+
+func (r *fakeUsers) ResetMfaAttempts(ctx context.Context, uid int64) error {
+	r.mfaAttempts = 0
+	return nil
+}
+
+type fakeCodes struct {
+	used map[string]bool
+}
+
+func (r *fakeCodes) ReplaceRecoveryCodes(ctx context.Context, userId int64, hashes []string) error {
+	r.used = make(map[string]bool)
+	for _, hash := range hashes {
+		r.used[hash] = false
+	}
+	return nil
+}
+
+func (r *fakeCodes) UseRecoveryCode(ctx context.Context, userId int64, hash string) (bool, error) {
+	used, ok := r.used[hash]
+	if !ok || used {
+		return false, nil
+	}
+	r.used[hash] = true
+	return true, nil
+}
+
+func (r *fakeCodes) DeleteRecoveryCodes(ctx context.Context, userId int64) error {
+	r.used = nil
+	return nil
+}
+
+// fakeLimiter считает попытки, которые остались засчитанными неудачами
+type fakeLimiter struct {
+	failures  int
+	successes int
+}
+
+func (l *fakeLimiter) Attempt(ctx context.Context, login, ip string) error {
+	l.failures++
+	return nil
+}
+
+func (l *fakeLimiter) Release(ctx context.Context, login, ip string) {
+	l.failures--
+}
+
+func (l *fakeLimiter) Success(ctx context.Context, login, ip string) {
+	l.failures--
+	l.successes++
+}
+
+// fakePasskeys принимает ключ, только если он совпадает с valid
+type fakePasskeys struct {
+	valid string
+}
+
+func (p *fakePasskeys) SecondFactorOptions(ctx context.Context, user *domain.User) (*passkeyModels.RequestOptions, error) {
+	return &passkeyModels.RequestOptions{}, nil
+}
+
+func (p *fakePasskeys) VerifySecondFactor(ctx context.Context, user *domain.User, credential passkeyModels.Credential) error {
+	if credential.Id != p.valid {
+		return authErrors.ErrInvalidPasskey
+	}
+	return nil
+}
+
+// fakeSessions знает только клиента grafana
+type fakeSessions struct{}
+
+func (fakeSessions) CheckIdTokenClient(ctx context.Context, clientId string) error {
+	if clientId != "grafana" {
+		return authErrors.ErrUnknownClient
+	}
+	return nil
+}
+
+func (fakeSessions) IssueTokens(ctx context.Context, user *domain.User, client *domain.Client, scope string, idToken *jwtLib.IdTokenParams) (*auth.AuthResponse, error) {
+	response := &auth.AuthResponse{AccessToken: user.Login}
+	if idToken != nil {
+		response.IdToken = idToken.Audience
+	}
+	return response, nil
+}
+
+func newTestMfa(t *testing.T) (*Mfa, *domain.User, *fakeUsers, *fakeCodes, *fakeLimiter) {
+	t.Helper()
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := &fakeUsers{}
+	codes := &fakeCodes{}
+	limiter := &fakeLimiter{}
+	m := New(users, codes, nil, nil, nil, plainEncryptor{}, nil, limiter, "SSO")
+	user := &domain.User{Id: 1, Login: "user@example.com", TotpSecret: &secret, TotpEnabled: true}
+	return m, user, users, codes, limiter
+}
+
+// waitStepStart не даёт тесту попасть на границу шага TOTP
+func waitStepStart() {
+	if time.Now().Unix()%totp.Period == totp.Period-1 {
+		time.Sleep(time.Second)
+	}
+}
+
+func codeAt(t *testing.T, user *domain.User, offset time.Duration) string {
+	t.Helper()
+	code, err := totp.Code(*user.TotpSecret, time.Now().Add(offset))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// wrongCode — код, который не подходит ни к одному шагу окна
+func wrongCode(t *testing.T, user *domain.User) string {
+	t.Helper()
+	window := map[string]bool{}
+	for _, offset := range []time.Duration{-totp.Period * time.Second, 0, totp.Period * time.Second} {
+		window[codeAt(t, user, offset)] = true
+	}
+	for i := 0; ; i++ {
+		code := fmt.Sprintf("%06d", i)
+		if !window[code] {
+			return code
+		}
+	}
+}
+
+func TestCheckTotpWindow(t *testing.T) {
+	waitStepStart()
+	step := time.Second * totp.Period
+	tests := []struct {
+		name   string
+		offset time.Duration
+		want   bool
+	}{
+		{"previous step", -step, true},
+		{"current step", 0, true},
+		{"next step", step, true},
+		{"two steps back", -step * 2, false},
+		{"two steps ahead", step * 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, user, _, _, _ := newTestMfa(t)
+			ok, err := m.checkTotp(user, codeAt(t, user, tt.offset))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.want {
+				t.Errorf("checkTotp = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckTotpRejectsReplay(t *testing.T) {
+	waitStepStart()
+	m, user, _, _, _ := newTestMfa(t)
+	code := codeAt(t, user, 0)
+
+	if ok, _ := m.checkTotp(user, code); !ok {
+		t.Fatal("expected valid code to be accepted")
+	}
+	if ok, _ := m.checkTotp(user, code); ok {
+		t.Error("expected code of used step to be rejected")
+	}
+	if ok, _ := m.checkTotp(user, codeAt(t, user, -time.Second*totp.Period)); ok {
+		t.Error("expected code of step before used one to be rejected")
+	}
+}
+
+func TestCheckCodeRejectsConcurrentReplay(t *testing.T) {
+	waitStepStart()
+	m, user, _, _, _ := newTestMfa(t)
+	code := codeAt(t, user, 0)
+	// два запроса прочитали пользователя до того, как один из них сохранил шаг
+	first, second := *user, *user
+
+	if err := m.CheckCode(context.Background(), &first, code); err != nil {
+		t.Fatalf("first check: %v", err)
+	}
+	if err := m.CheckCode(context.Background(), &second, code); !errors.Is(err, authErrors.ErrInvalidMfaCode) {
+		t.Errorf("second check: got %v, want ErrInvalidMfaCode", err)
+	}
+}
+
+func TestRecoveryCodeSingleUse(t *testing.T) {
+	ctx := context.Background()
+	m, user, _, codes, _ := newTestMfa(t)
+	plain, hashes := domain.NewRecoveryCodes()
+	_ = codes.ReplaceRecoveryCodes(ctx, user.Id, hashes)
+
+	if err := m.CheckCode(ctx, user, plain[0]); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := m.CheckCode(ctx, user, plain[0]); !errors.Is(err, authErrors.ErrInvalidMfaCode) {
+		t.Errorf("second use: got %v, want ErrInvalidMfaCode", err)
+	}
+	// регистр и дефис при вводе не важны
+	if err := m.CheckCode(ctx, user, " "+strings.ToUpper(strings.ReplaceAll(plain[1], "-", ""))+" "); err != nil {
+		t.Errorf("code without dash: %v", err)
+	}
+}
+
+func TestCheckCodeAttemptLimit(t *testing.T) {
+	waitStepStart()
+	ctx := context.Background()
+	m, user, _, codes, _ := newTestMfa(t)
+	plain, hashes := domain.NewRecoveryCodes()
+	_ = codes.ReplaceRecoveryCodes(ctx, user.Id, hashes)
+
+	wrong := wrongCode(t, user)
+	for i := range domain.MaxMfaAttempts {
+		if err := m.CheckCode(ctx, user, wrong); !errors.Is(err, authErrors.ErrInvalidMfaCode) {
+			t.Fatalf("attempt %d: got %v, want ErrInvalidMfaCode", i+1, err)
+		}
+	}
+	if err := m.CheckCode(ctx, user, codeAt(t, user, 0)); !errors.Is(err, authErrors.ErrTooManyMfaCodes) {
+		t.Fatalf("valid code after limit: got %v, want ErrTooManyMfaCodes", err)
+	}
+
+	// резервный код по-прежнему принимается и снимает ограничение
+	if err := m.CheckCode(ctx, user, plain[0]); err != nil {
+		t.Fatalf("recovery code after limit: %v", err)
+	}
+	if err := m.CheckCode(ctx, user, codeAt(t, user, 0)); err != nil {
+		t.Errorf("valid code after recovery code: %v", err)
+	}
+}
+
+func TestCheckCodeCountsLoginAttempts(t *testing.T) {
+	waitStepStart()
+	ctx := context.Background()
+	m, user, _, _, limiter := newTestMfa(t)
+
+	if err := m.CheckCode(ctx, user, ""); !errors.Is(err, authErrors.ErrMfaRequired) {
+		t.Fatalf("empty code: got %v, want ErrMfaRequired", err)
+	}
+	if err := m.CheckCode(ctx, user, wrongCode(t, user)); !errors.Is(err, authErrors.ErrInvalidMfaCode) {
+		t.Fatalf("wrong code: got %v, want ErrInvalidMfaCode", err)
+	}
+	if limiter.failures != 1 || limiter.successes != 0 {
+		t.Fatalf("after wrong code: failures = %d, successes = %d, want 1, 0", limiter.failures, limiter.successes)
+	}
+
+	if err := m.CheckCode(ctx, user, codeAt(t, user, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if limiter.failures != 1 || limiter.successes != 1 {
+		t.Errorf("after valid code: failures = %d, successes = %d, want 1, 1", limiter.failures, limiter.successes)
+	}
+}
+
+func TestCheckPasskeyCountsLoginAttempts(t *testing.T) {
+	ctx := context.Background()
+	limiter := &fakeLimiter{}
+	m := New(&fakeUsers{}, &fakeCodes{}, nil, nil, nil, nil, &fakePasskeys{valid: "key"}, limiter, "SSO")
+	// без TOTP: второй фактор нужен из-за ключа
+	user := &domain.User{Id: 1, Login: "user@example.com", HasPasskeys: true}
+
+	if err := m.CheckPasskey(ctx, user, passkeyModels.Credential{Id: "other"}); !errors.Is(err, authErrors.ErrInvalidPasskey) {
+		t.Fatalf("wrong passkey: got %v, want ErrInvalidPasskey", err)
+	}
+	if err := m.CheckPasskey(ctx, user, passkeyModels.Credential{Id: "key"}); err != nil {
+		t.Fatal(err)
+	}
+	if limiter.failures != 1 || limiter.successes != 1 {
+		t.Errorf("failures = %d, successes = %d, want 1, 1", limiter.failures, limiter.successes)
+	}
+}
+
+func TestIssueTokensChecksIdTokenClient(t *testing.T) {
+	m := New(&fakeUsers{}, &fakeCodes{}, fakeSessions{}, nil, nil, nil, nil, &fakeLimiter{}, "SSO")
+	user := &domain.User{Id: 1, Login: "user@example.com"}
+	tests := []struct {
+		clientId    string
+		wantIdToken string
+		wantErr     error
+	}{
+		{"", "", nil},
+		{"grafana", "grafana", nil},
+		// клиента отключили, пока пользователь вводил код
+		{"disabled", "", authErrors.ErrUnknownClient},
+	}
+	for _, tt := range tests {
+		t.Run(tt.clientId, func(t *testing.T) {
+			result, err := m.issueTokens(context.Background(), user, &claims.Claims{UserId: user.Id, ClientId: tt.clientId})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("issueTokens: got %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && result.IdToken != tt.wantIdToken {
+				t.Errorf("id token audience = %q, want %q", result.IdToken, tt.wantIdToken)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/phenirain/sso/internal/dto/oauth"
+	oauthErrors "github.com/phenirain/sso/internal/errors/oauth"
+	"github.com/phenirain/sso/pkg/claims"
+)
+
+const (
+	tokenTypeHintAccess  = "access_token"
+	tokenTypeHintRefresh = "refresh_token"
+)
+
+// Introspect отвечает, действителен ли токен сейчас (RFC 7662), с учётом отзыва.
+// Спрашивать могут только конфиденциальные клиенты - обычно это resource server
+func (o *OAuth) Introspect(ctx context.Context, request oauth.IntrospectRequest) (*oauth.IntrospectionResponse, error) {
+	client, err := o.authenticateClient(ctx, request.ClientAuth)
+	if err != nil {
+		return nil, err
+	}
+	if !client.IsConfidential() {
+		return nil, oauthErrors.ErrConfidentialClientRequired
+	}
+	if request.Token == "" {
+		return nil, oauthErrors.ErrInvalidRequest
+	}
+
+	tokenClaims, tokenType, err := o.activeToken(ctx, request.Token, request.TokenTypeHint)
+	if err != nil {
+		return nil, err
+	}
+	if tokenClaims == nil {
+		return &oauth.IntrospectionResponse{Active: false}, nil
+	}
+
+	response := &oauth.IntrospectionResponse{
+		Active:    true,
+		Scope:     tokenClaims.Scope,
+		ClientId:  tokenClaims.ClientId,
+		TokenType: tokenType,
+		Exp:       tokenClaims.ExpiresAt.Unix(),
+		Iat:       tokenClaims.IssuedAt.Unix(),
+		Aud:       tokenClaims.Audience,
+		Iss:       o.jwt.Issuer(),
+		Jti:       tokenClaims.TokenId,
+	}
+	if tokenClaims.IsService() {
+		response.Sub = tokenClaims.ClientId
+		return response, nil
+	}
+
+	// токен пользователя жив, пока жив сам пользователь
+	user, err := o.users.GetUserWithId(ctx, tokenClaims.UserId)
+	if err != nil {
+		errorText := fmt.Errorf("ошибка получения пользователя по идентфикатору: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+	if user == nil || user.IsArchived {
+		return &oauth.IntrospectionResponse{Active: false}, nil
+	}
+	response.Sub = strconv.FormatInt(user.Id, 10)
+	response.Username = user.Login
+	// роль из базы, а не из токена - она могла поменяться после выпуска
+	response.RoleId = user.RoleId
+	return response, nil
+}
+
+// Revoke отзывает refresh или access токен клиента (RFC 7009). Недействительный
+// токен - не ошибка: клиенту важно лишь, что токеном больше нельзя воспользоваться
+func (o *OAuth) Revoke(ctx context.Context, request oauth.RevokeRequest) error {
+	client, err := o.authenticateClient(ctx, request.ClientAuth)
+	if err != nil {
+		return err
+	}
+	if request.Token == "" {
+		return oauthErrors.ErrInvalidRequest
+	}
+
+	tokenClaims, tokenType, err := o.activeToken(ctx, request.Token, request.TokenTypeHint)
+	if err != nil {
+		return err
+	}
+	if tokenClaims == nil {
+		return nil
+	}
+	if tokenClaims.ClientId != client.Id {
+		return oauthErrors.ErrTokenNotOwned
+	}
+
+	if tokenType == tokenTypeHintRefresh {
+		// вместе с refresh токеном завершается вся сессия, её access токены тоже отзываются
+		if err := o.auth.Logout(ctx, request.Token); err != nil {
+			return err
+		}
+	} else if err := o.denylist.RevokeAccessToken(ctx, tokenClaims.TokenId, tokenClaims.ExpiresAt); err != nil {
+		errorText := fmt.Errorf("ошибка отзыва access токена: %w", err)
+		slog.Error(errorText.Error())
+		return errorText
+	}
+
+	slog.Info("token revoked", "client_id", client.Id, "token_type", tokenType)
+	return nil
+}
+
+// activeToken определяет тип токена, начиная с подсказки, и проверяет, что он не отозван.
+// nil claims без ошибки - токен недействителен
+func (o *OAuth) activeToken(ctx context.Context, token, tokenTypeHint string) (*claims.Claims, string, error) {
+	if tokenTypeHint == tokenTypeHintRefresh {
+		if tokenClaims := o.activeRefreshToken(ctx, token); tokenClaims != nil {
+			return tokenClaims, tokenTypeHintRefresh, nil
+		}
+		tokenClaims, err := o.activeAccessToken(ctx, token)
+		return tokenClaims, tokenTypeHintAccess, err
+	}
+
+	tokenClaims, err := o.activeAccessToken(ctx, token)
+	if err != nil || tokenClaims != nil {
+		return tokenClaims, tokenTypeHintAccess, err
+	}
+	return o.activeRefreshToken(ctx, token), tokenTypeHintRefresh, nil
+}
+
+func (o *OAuth) activeAccessToken(ctx context.Context, token string) (*claims.Claims, error) {
+	tokenClaims, err := o.jwt.ParseToken(token, claims.TokenTypeAccess)
+	if err != nil {
+		return nil, nil
+	}
+	revoked, err := o.denylist.IsRevoked(ctx, tokenClaims)
+	if err != nil {
+		errorText := fmt.Errorf("ошибка проверки отзыва токена: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+	if revoked {
+		return nil, nil
+	}
+	return tokenClaims, nil
+}
+
+func (o *OAuth) activeRefreshToken(ctx context.Context, token string) *claims.Claims {
+	tokenClaims, err := o.auth.ActiveRefreshToken(ctx, token)
+	if err != nil {
+		return nil
+	}
+	return tokenClaims
+}
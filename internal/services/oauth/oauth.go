@@ -0,0 +1,442 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/internal/dto/auth"
+	"github.com/phenirain/sso/internal/dto/oauth"
+	passkeyModels "github.com/phenirain/sso/internal/dto/passkey"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	oauthErrors "github.com/phenirain/sso/internal/errors/oauth"
+	"github.com/phenirain/sso/internal/lib/cache"
+	jwtLib "github.com/phenirain/sso/internal/lib/jwt"
+	"github.com/phenirain/sso/pkg/claims"
+)
+
+// authorizationCodeTTL — код обменивается на токены сразу после редиректа
+const authorizationCodeTTL = time.Minute
+
+// clientAssertionReplayTTL — сколько помним jti client_assertion, не меньше его максимального срока жизни
+const clientAssertionReplayTTL = time.Minute * 5
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, login, password string) (*domain.User, error)
+	IssueTokens(ctx context.Context, user *domain.User, client *domain.Client, scope string, idToken *jwtLib.IdTokenParams) (*auth.AuthResponse, error)
+	RefreshForClient(ctx context.Context, refreshToken, clientId string) (*auth.AuthResponse, error)
+	ActiveRefreshToken(ctx context.Context, refreshToken string) (*claims.Claims, error)
+	Logout(ctx context.Context, refreshToken string) error
+}
+
+// SecondFactor проверяет код двухфакторной аутентификации пользователя
+type SecondFactor interface {
+	CheckCode(ctx context.Context, user *domain.User, code string) error
+	CheckPasskey(ctx context.Context, user *domain.User, credential passkeyModels.Credential) error
+	UserPasskeyOptions(ctx context.Context, user *domain.User) (*passkeyModels.RequestOptions, error)
+}
+
+// SecondFactorError — пароль верен, но второй фактор не предъявлен или не подошёл.
+// PasskeyOptions - новый challenge, если у пользователя есть ключи: страница входа предложит ключ
+type SecondFactorError struct {
+	Err            error
+	PasskeyOptions *passkeyModels.RequestOptions
+}
+
+func (e *SecondFactorError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *SecondFactorError) Unwrap() error {
+	return e.Err
+}
+
+type Jwt interface {
+	ParseToken(tokenString string, tokenType claims.TokenType) (*claims.Claims, error)
+	Issuer() string
+	SupportsIdTokens() bool
+	NewClientToken(params jwtLib.AccessTokenParams) (string, error)
+	ParseClientAssertion(assertion string, publicKey func(clientId string) (string, error)) (*jwtLib.ClientAssertion, error)
+}
+
+type Denylist interface {
+	IsRevoked(ctx context.Context, tokenClaims *claims.Claims) (bool, error)
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+}
+
+type UserRepository interface {
+	GetUserWithId(ctx context.Context, uid int64) (*domain.User, error)
+}
+
+type ClientRepository interface {
+	GetClient(ctx context.Context, id string) (*domain.Client, error)
+}
+
+type CodeRepository interface {
+	CreateAuthorizationCode(ctx context.Context, code *domain.AuthorizationCode) error
+	UseAuthorizationCode(ctx context.Context, codeHash string) (*domain.AuthorizationCode, error)
+}
+
+type OAuth struct {
+	auth      Authenticator
+	mfa       SecondFactor
+	users     UserRepository
+	clients   ClientRepository
+	codes     CodeRepository
+	jwt       Jwt
+	denylist  Denylist
+	accessTTL time.Duration
+	// использованные client_assertion. Хранятся в памяти реплики: повтор на другой
+	// реплике не поймается, но окно ограничено сроком жизни assertion
+	assertions *cache.TTLCache[string, struct{}]
+}
+
+func New(auth Authenticator, mfa SecondFactor, users UserRepository, clients ClientRepository, codes CodeRepository, jwt Jwt, denylist Denylist, accessTTL time.Duration) *OAuth {
+	return &OAuth{
+		auth:       auth,
+		mfa:        mfa,
+		users:      users,
+		clients:    clients,
+		codes:      codes,
+		jwt:        jwt,
+		denylist:   denylist,
+		accessTTL:  accessTTL,
+		assertions: cache.New[string, struct{}](clientAssertionReplayTTL),
+	}
+}
+
+// ValidateAuthorize проверяет запрос /oauth/authorize. На ErrInvalidClient и ErrInvalidRedirectUri
+// нельзя редиректить обратно клиенту - redirect_uri не подтверждён
+func (o *OAuth) ValidateAuthorize(ctx context.Context, request oauth.AuthorizeRequest) (*domain.Client, error) {
+	client, err := o.clients.GetClient(ctx, request.ClientId)
+	if err != nil {
+		errorText := fmt.Errorf("ошибка получения клиента: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+	if client == nil || client.IsDisabled {
+		return nil, oauthErrors.ErrInvalidClient
+	}
+	if !client.HasRedirectUri(request.RedirectUri) {
+		return nil, oauthErrors.ErrInvalidRedirectUri
+	}
+
+	if request.ResponseType != "code" {
+		return client, oauthErrors.ErrUnsupportedResponseType
+	}
+	if !client.AllowsGrant(domain.GrantAuthorizationCode) {
+		return client, oauthErrors.ErrUnauthorizedClient
+	}
+	scope, ok := client.ResolveScope(request.Scope)
+	if !ok {
+		return client, oauthErrors.ErrInvalidScope
+	}
+	if slices.Contains(strings.Fields(scope), "openid") && !o.jwt.SupportsIdTokens() {
+		return client, oauthErrors.ErrOpenIdUnavailable
+	}
+	if request.CodeChallenge == "" || request.CodeChallengeMethod != domain.CodeChallengeMethodS256 {
+		return client, oauthErrors.ErrPKCERequired
+	}
+	return client, nil
+}
+
+// Authorize проверяет учётные данные со страницы входа и возвращает адрес редиректа с кодом.
+// otp или passkey - второй фактор, нужен только если у пользователя подключён TOTP или есть ключи
+func (o *OAuth) Authorize(ctx context.Context, request oauth.AuthorizeRequest, login, password, otp string, passkey *passkeyModels.Credential) (string, error) {
+	client, err := o.ValidateAuthorize(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	scope, _ := client.ResolveScope(request.Scope)
+
+	user, err := o.auth.Authenticate(ctx, login, password)
+	if err != nil {
+		return "", err
+	}
+	if user.RequiresSecondFactor() {
+		if err := o.checkSecondFactor(ctx, user, otp, passkey); err != nil {
+			return "", err
+		}
+	}
+
+	code, plainCode := domain.NewAuthorizationCode(request.ClientId, user.Id, request.RedirectUri,
+		scope, request.Nonce, request.CodeChallenge, authorizationCodeTTL)
+	if err := o.codes.CreateAuthorizationCode(ctx, code); err != nil {
+		errorText := fmt.Errorf("ошибка сохранения кода авторизации: %w", err)
+		slog.Error(errorText.Error())
+		return "", errorText
+	}
+
+	return RedirectUri(request.RedirectUri, map[string]string{
+		"code":  plainCode,
+		"state": request.State,
+	})
+}
+
+// checkSecondFactor проверяет ключ, если он пришёл, иначе код. Без кода у пользователя только
+// с ключами сразу нужен ключ
+func (o *OAuth) checkSecondFactor(ctx context.Context, user *domain.User, otp string, passkey *passkeyModels.Credential) error {
+	var err error
+	switch {
+	case passkey != nil:
+		err = o.mfa.CheckPasskey(ctx, user, *passkey)
+	case otp != "" || !user.HasPasskeys:
+		err = o.mfa.CheckCode(ctx, user, otp)
+	default:
+		err = authErrors.ErrMfaRequired
+	}
+	if err == nil || !user.HasPasskeys || !isSecondFactorFailure(err) {
+		return err
+	}
+
+	options, optionsErr := o.mfa.UserPasskeyOptions(ctx, user)
+	if optionsErr != nil {
+		return optionsErr
+	}
+	return &SecondFactorError{Err: err, PasskeyOptions: options}
+}
+
+// isSecondFactorFailure — второй фактор не предъявлен или не подошёл, можно попробовать снова
+func isSecondFactorFailure(err error) bool {
+	return errors.Is(err, authErrors.ErrMfaRequired) ||
+		errors.Is(err, authErrors.ErrInvalidMfaCode) ||
+		errors.Is(err, authErrors.ErrTooManyMfaCodes) ||
+		errors.Is(err, authErrors.ErrInvalidPasskey) ||
+		errors.Is(err, authErrors.ErrPasskeyCloned)
+}
+
+// Token обрабатывает запрос /oauth/token
+func (o *OAuth) Token(ctx context.Context, request oauth.TokenRequest) (*oauth.TokenResponse, error) {
+	switch request.GrantType {
+	case "authorization_code":
+		return o.exchangeCode(ctx, request)
+	case "refresh_token":
+		return o.refresh(ctx, request)
+	case "client_credentials":
+		return o.clientCredentials(ctx, request)
+	default:
+		return nil, oauthErrors.ErrUnsupportedGrantType
+	}
+}
+
+func (o *OAuth) exchangeCode(ctx context.Context, request oauth.TokenRequest) (*oauth.TokenResponse, error) {
+	if request.Code == "" || request.CodeVerifier == "" {
+		return nil, oauthErrors.ErrInvalidRequest
+	}
+	client, err := o.authenticateGrant(ctx, request.ClientAuth, domain.GrantAuthorizationCode)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := o.codes.UseAuthorizationCode(ctx, domain.HashToken(request.Code))
+	if err != nil {
+		errorText := fmt.Errorf("ошибка получения кода авторизации: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+	if code == nil || code.IsExpired() {
+		return nil, oauthErrors.ErrInvalidGrant
+	}
+	// код сверяем с аутентифицированным клиентом: с client_assertion client_id в запросе может не быть
+	if code.ClientId != client.Id || code.RedirectUri != request.RedirectUri {
+		return nil, oauthErrors.ErrInvalidGrant
+	}
+	if !code.CheckVerifier(request.CodeVerifier) {
+		return nil, oauthErrors.ErrInvalidGrant
+	}
+
+	user, err := o.users.GetUserWithId(ctx, code.UserId)
+	if err != nil {
+		errorText := fmt.Errorf("ошибка получения пользователя по идентфикатору: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+	if user == nil || user.IsArchived {
+		return nil, oauthErrors.ErrInvalidGrant
+	}
+
+	var idToken *jwtLib.IdTokenParams
+	if code.HasScope("openid") {
+		idToken = &jwtLib.IdTokenParams{
+			Audience: code.ClientId,
+			Nonce:    code.Nonce,
+			AuthTime: code.AuthTime,
+		}
+	}
+
+	tokens, err := o.auth.IssueTokens(ctx, user, client, code.Scope, idToken)
+	if err != nil {
+		return nil, err
+	}
+	response := o.tokenResponse(client, tokens)
+	response.Scope = code.Scope
+	return response, nil
+}
+
+func (o *OAuth) refresh(ctx context.Context, request oauth.TokenRequest) (*oauth.TokenResponse, error) {
+	if request.RefreshToken == "" {
+		return nil, oauthErrors.ErrInvalidRequest
+	}
+	client, err := o.authenticateGrant(ctx, request.ClientAuth, domain.GrantRefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := o.auth.RefreshForClient(ctx, request.RefreshToken, client.Id)
+	if err != nil {
+		// внутренние ошибки Refresh уже залогировал, клиенту - invalid_grant
+		return nil, oauthErrors.ErrInvalidGrant
+	}
+	return o.tokenResponse(client, tokens), nil
+}
+
+// clientCredentials выдаёт токен самому клиенту, без пользователя. Только конфиденциальным клиентам
+func (o *OAuth) clientCredentials(ctx context.Context, request oauth.TokenRequest) (*oauth.TokenResponse, error) {
+	client, err := o.authenticateGrant(ctx, request.ClientAuth, domain.GrantClientCredentials)
+	if err != nil {
+		return nil, err
+	}
+	if !client.IsConfidential() {
+		return nil, oauthErrors.ErrUnauthorizedClient
+	}
+	scope, ok := client.ResolveScope(request.Scope)
+	if !ok {
+		return nil, oauthErrors.ErrInvalidScope
+	}
+
+	accessToken, err := o.jwt.NewClientToken(jwtLib.AccessTokenParams{
+		TTL:      client.AccessTTL(o.accessTTL),
+		ClientId: client.Id,
+		Audience: client.Audience,
+		Scope:    scope,
+	})
+	if err != nil {
+		errorText := fmt.Errorf("ошибка создания токена клиента: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+
+	slog.Info("client token issued", "client_id", client.Id)
+	return &oauth.TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(client.AccessTTL(o.accessTTL).Seconds()),
+		Scope:       scope,
+	}, nil
+}
+
+// authenticateGrant аутентифицирует клиента и проверяет, что ему разрешён grantType
+func (o *OAuth) authenticateGrant(ctx context.Context, request oauth.ClientAuth, grantType string) (*domain.Client, error) {
+	client, err := o.authenticateClient(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if !client.AllowsGrant(grantType) {
+		return nil, oauthErrors.ErrUnauthorizedClient
+	}
+	return client, nil
+}
+
+// authenticateClient проверяет клиента: конфиденциальный клиент обязан предъявить
+// секрет или client_assertion, публичному достаточно client_id (его защищает PKCE)
+func (o *OAuth) authenticateClient(ctx context.Context, request oauth.ClientAuth) (*domain.Client, error) {
+	if request.ClientAssertionType != "" || request.ClientAssertion != "" {
+		return o.authenticateAssertion(ctx, request)
+	}
+	return o.authenticateSecret(ctx, request)
+}
+
+func (o *OAuth) authenticateSecret(ctx context.Context, request oauth.ClientAuth) (*domain.Client, error) {
+	if request.ClientId == "" {
+		return nil, oauthErrors.ErrInvalidClient
+	}
+
+	client, err := o.getClient(ctx, request.ClientId)
+	if err != nil {
+		return nil, err
+	}
+	if client.IsConfidential() && !client.CheckSecret(request.ClientSecret) {
+		return nil, oauthErrors.ErrInvalidClient
+	}
+	return client, nil
+}
+
+// authenticateAssertion — private_key_jwt: клиент подписывает JWT своим ключом
+func (o *OAuth) authenticateAssertion(ctx context.Context, request oauth.ClientAuth) (*domain.Client, error) {
+	if request.ClientAssertionType != jwtLib.ClientAssertionType || request.ClientAssertion == "" {
+		return nil, oauthErrors.ErrInvalidClient
+	}
+
+	var client *domain.Client
+	assertion, err := o.jwt.ParseClientAssertion(request.ClientAssertion, func(clientId string) (string, error) {
+		if request.ClientId != "" && request.ClientId != clientId {
+			return "", oauthErrors.ErrInvalidClient
+		}
+		var err error
+		if client, err = o.getClient(ctx, clientId); err != nil {
+			return "", err
+		}
+		if client.PublicKey == "" {
+			return "", oauthErrors.ErrInvalidClient
+		}
+		return client.PublicKey, nil
+	})
+	if err != nil {
+		slog.Warn("client assertion rejected", "err", err)
+		return nil, oauthErrors.ErrInvalidClient
+	}
+
+	replayKey := assertion.ClientId + ":" + assertion.TokenId
+	if _, used := o.assertions.Get(replayKey); used {
+		slog.Warn("client assertion replayed", "client_id", assertion.ClientId)
+		return nil, oauthErrors.ErrInvalidClient
+	}
+	o.assertions.Set(replayKey, struct{}{})
+	return client, nil
+}
+
+// getClient возвращает активного клиента, иначе ErrInvalidClient
+func (o *OAuth) getClient(ctx context.Context, clientId string) (*domain.Client, error) {
+	client, err := o.clients.GetClient(ctx, clientId)
+	if err != nil {
+		errorText := fmt.Errorf("ошибка получения клиента: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+	if client == nil || client.IsDisabled {
+		return nil, oauthErrors.ErrInvalidClient
+	}
+	return client, nil
+}
+
+func (o *OAuth) tokenResponse(client *domain.Client, tokens *auth.AuthResponse) *oauth.TokenResponse {
+	return &oauth.TokenResponse{
+		AccessToken:  tokens.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(client.AccessTTL(o.accessTTL).Seconds()),
+		RefreshToken: tokens.RefreshToken,
+		IdToken:      tokens.IdToken,
+	}
+}
+
+// RedirectUri добавляет параметры к зарегистрированному redirect_uri клиента
+func RedirectUri(redirectUri string, params map[string]string) (string, error) {
+	u, err := url.Parse(redirectUri)
+	if err != nil {
+		return "", fmt.Errorf("некорректный redirect_uri: %w", err)
+	}
+	query := u.Query()
+	for key, value := range params {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
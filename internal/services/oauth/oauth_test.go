@@ -0,0 +1,555 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/internal/dto/auth"
+	"github.com/phenirain/sso/internal/dto/oauth"
+	passkeyModels "github.com/phenirain/sso/internal/dto/passkey"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	jwtErrors "github.com/phenirain/sso/internal/errors/jwt"
+	oauthErrors "github.com/phenirain/sso/internal/errors/oauth"
+	jwtLib "github.com/phenirain/sso/internal/lib/jwt"
+	"github.com/phenirain/sso/pkg/claims"
+)
+
+const (
+	testRedirectUri  = "http://app/cb"
+	testCodeVerifier = "verifier-verifier-verifier-verifier-verifier"
+)
+
+// fakeAuth выдаёт токены вида "access:<client_id>", а refresh токены считает живыми, пока их
+// не отозвал Logout
+type fakeAuth struct {
+	refresh   map[string]*claims.Claims
+	loggedOut []string
+}
+
+func (a *fakeAuth) Authenticate(ctx context.Context, login, password string) (*domain.User, error) {
+	return nil, nil
+}
+
+func (a *fakeAuth) IssueTokens(ctx context.Context, user *domain.User, client *domain.Client, scope string, idToken *jwtLib.IdTokenParams) (*auth.AuthResponse, error) {
+	response := &auth.AuthResponse{AccessToken: "access:" + client.Id, RefreshToken: "refresh:" + client.Id}
+	if idToken != nil {
+		response.IdToken = "id:" + idToken.Audience
+	}
+	return response, nil
+}
+
+func (a *fakeAuth) RefreshForClient(ctx context.Context, refreshToken, clientId string) (*auth.AuthResponse, error) {
+	return nil, nil
+}
+
+func (a *fakeAuth) ActiveRefreshToken(ctx context.Context, refreshToken string) (*claims.Claims, error) {
+	tokenClaims, ok := a.refresh[refreshToken]
+	if !ok {
+		return nil, authErrors.ErrInvalidRefreshToken
+	}
+	return tokenClaims, nil
+}
+
+func (a *fakeAuth) Logout(ctx context.Context, refreshToken string) error {
+	delete(a.refresh, refreshToken)
+	a.loggedOut = append(a.loggedOut, refreshToken)
+	return nil
+}
+
+type fakeSecondFactor struct{}
+
+func (fakeSecondFactor) CheckCode(ctx context.Context, user *domain.User, code string) error {
+	return nil
+}
+
+func (fakeSecondFactor) CheckPasskey(ctx context.Context, user *domain.User, credential passkeyModels.Credential) error {
+	return nil
+}
+
+func (fakeSecondFactor) UserPasskeyOptions(ctx context.Context, user *domain.User) (*passkeyModels.RequestOptions, error) {
+	return nil, nil
+}
+
+type fakeUsers struct {
+	users map[int64]*domain.User
+}
+
+func (r *fakeUsers) GetUserWithId(ctx context.Context, uid int64) (*domain.User, error) {
+	return r.users[uid], nil
+}
+
+type fakeClients struct {
+	clients map[string]*domain.Client
+}
+
+func (r *fakeClients) GetClient(ctx context.Context, id string) (*domain.Client, error) {
+	return r.clients[id], nil
+}
+
+type fakeCodes struct {
+	codes map[string]*domain.AuthorizationCode
+}
+
+func (r *fakeCodes) CreateAuthorizationCode(ctx context.Context, code *domain.AuthorizationCode) error {
+	r.codes[code.CodeHash] = code
+	return nil
+}
+
+func (r *fakeCodes) UseAuthorizationCode(ctx context.Context, codeHash string) (*domain.AuthorizationCode, error) {
+	code := r.codes[codeHash]
+	delete(r.codes, codeHash)
+	return code, nil
+}
+
+// fakeJwt принимает client_assertion вида "<client_id>:<jti>", а access токены - только
+// выпущенные через access. Подпись проверяет jwtLib, здесь важна логика сервиса вокруг неё
+type fakeJwt struct {
+	tokens map[string]*claims.Claims
+}
+
+func (j *fakeJwt) ParseToken(tokenString string, tokenType claims.TokenType) (*claims.Claims, error) {
+	tokenClaims, ok := j.tokens[tokenString]
+	if !ok {
+		return nil, jwtErrors.ErrInvalidToken
+	}
+	if tokenClaims.TokenType != tokenType {
+		return nil, jwtErrors.ErrInvalidTokenType
+	}
+	return tokenClaims, nil
+}
+
+func (j *fakeJwt) Issuer() string {
+	return "http://sso"
+}
+
+func (j *fakeJwt) SupportsIdTokens() bool {
+	return true
+}
+
+func (j *fakeJwt) NewClientToken(params jwtLib.AccessTokenParams) (string, error) {
+	return "client-token", nil
+}
+
+func (j *fakeJwt) ParseClientAssertion(assertion string, publicKey func(clientId string) (string, error)) (*jwtLib.ClientAssertion, error) {
+	clientId, tokenId, _ := strings.Cut(assertion, ":")
+	if _, err := publicKey(clientId); err != nil {
+		return nil, err
+	}
+	return &jwtLib.ClientAssertion{ClientId: clientId, TokenId: tokenId, ExpiresAt: time.Now().Add(time.Minute)}, nil
+}
+
+type fakeDenylist struct {
+	revoked map[string]bool
+}
+
+func (d *fakeDenylist) IsRevoked(ctx context.Context, tokenClaims *claims.Claims) (bool, error) {
+	return d.revoked[tokenClaims.TokenId], nil
+}
+
+func (d *fakeDenylist) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	d.revoked[jti] = true
+	return nil
+}
+
+// testOAuth — OAuth на фейках. Клиенты: публичный spa, backend с private_key_jwt,
+// worker с секретом (client_credentials) и отключённый old. Пользователь bob (id 1)
+type testOAuth struct {
+	o            *OAuth
+	auth         *fakeAuth
+	users        *fakeUsers
+	codes        *fakeCodes
+	jwt          *fakeJwt
+	denylist     *fakeDenylist
+	workerSecret string
+}
+
+func newTestOAuth() *testOAuth {
+	spa, _ := domain.NewClient("spa", "SPA", false)
+	backend, _ := domain.NewClient("backend", "Backend", false)
+	backend.SetPublicKey("backend public key")
+	worker, workerSecret := domain.NewClient("worker", "Worker", true)
+	old, _ := domain.NewClient("old", "Old", false)
+	for _, client := range []*domain.Client{spa, backend, old} {
+		client.UpdateSettings(client.Name, []string{testRedirectUri},
+			[]string{domain.GrantAuthorizationCode, domain.GrantRefreshToken}, []string{"openid"}, 0, 0, "")
+	}
+	worker.UpdateSettings(worker.Name, nil, []string{domain.GrantClientCredentials}, []string{"orders.read"}, 0, 0, "")
+	old.ChangeDisabledStatus(true)
+
+	env := &testOAuth{
+		auth:         &fakeAuth{refresh: make(map[string]*claims.Claims)},
+		users:        &fakeUsers{users: map[int64]*domain.User{1: {Id: 1, RoleId: domain.RoleBuyer, Login: "bob"}}},
+		codes:        &fakeCodes{codes: make(map[string]*domain.AuthorizationCode)},
+		jwt:          &fakeJwt{tokens: make(map[string]*claims.Claims)},
+		denylist:     &fakeDenylist{revoked: make(map[string]bool)},
+		workerSecret: workerSecret,
+	}
+	clients := &fakeClients{clients: map[string]*domain.Client{spa.Id: spa, backend.Id: backend, worker.Id: worker, old.Id: old}}
+	env.o = New(env.auth, fakeSecondFactor{}, env.users, clients, env.codes, env.jwt, env.denylist, time.Minute)
+	return env
+}
+
+// issueCode выдаёт код клиенту clientId так же, как Authorize
+func (e *testOAuth) issueCode(t *testing.T, clientId string) string {
+	t.Helper()
+	sum := sha256.Sum256([]byte(testCodeVerifier))
+	code, plainCode := domain.NewAuthorizationCode(clientId, 1, testRedirectUri, "openid", "",
+		base64.RawURLEncoding.EncodeToString(sum[:]), time.Minute)
+	if err := e.codes.CreateAuthorizationCode(context.Background(), code); err != nil {
+		t.Fatal(err)
+	}
+	return plainCode
+}
+
+// access регистрирует access токен пользователя bob, выданный клиенту clientId
+func (e *testOAuth) access(jti, clientId string) string {
+	token := "access-token:" + jti
+	e.jwt.tokens[token] = &claims.Claims{UserId: 1, RoleId: domain.RoleBuyer, TokenType: claims.TokenTypeAccess,
+		TokenId: jti, ClientId: clientId, IssuedAt: time.Now(), ExpiresAt: time.Now().Add(time.Minute)}
+	return token
+}
+
+// refresh регистрирует активный refresh токен пользователя bob, выданный клиенту clientId
+func (e *testOAuth) refresh(jti, clientId string) string {
+	token := "refresh-token:" + jti
+	e.auth.refresh[token] = &claims.Claims{UserId: 1, RoleId: domain.RoleBuyer, TokenType: claims.TokenTypeRefresh,
+		TokenId: jti, ClientId: clientId, IssuedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+	return token
+}
+
+func assertion(clientId, jti string) oauth.ClientAuth {
+	return oauth.ClientAuth{ClientAssertionType: jwtLib.ClientAssertionType, ClientAssertion: clientId + ":" + jti}
+}
+
+func TestExchangeCodeClientBinding(t *testing.T) {
+	tests := []struct {
+		name       string
+		codeClient string
+		clientAuth oauth.ClientAuth
+		wantErr    error
+		wantClient string
+	}{
+		{"public client by client_id", "spa", oauth.ClientAuth{ClientId: "spa"}, nil, "spa"},
+		// client_id в запросе необязателен: клиента называет сама assertion
+		{"assertion without client_id", "backend", assertion("backend", "1"), nil, "backend"},
+		{"assertion with matching client_id", "backend", oauth.ClientAuth{ClientId: "backend",
+			ClientAssertionType: jwtLib.ClientAssertionType, ClientAssertion: "backend:2"}, nil, "backend"},
+		{"code of another client by client_id", "backend", oauth.ClientAuth{ClientId: "spa"}, oauthErrors.ErrInvalidGrant, ""},
+		{"code of another client by assertion", "spa", assertion("backend", "3"), oauthErrors.ErrInvalidGrant, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestOAuth()
+			response, err := e.o.Token(context.Background(), oauth.TokenRequest{
+				ClientAuth:   tt.clientAuth,
+				GrantType:    "authorization_code",
+				Code:         e.issueCode(t, tt.codeClient),
+				RedirectUri:  testRedirectUri,
+				CodeVerifier: testCodeVerifier,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Token: got %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && response.AccessToken != "access:"+tt.wantClient {
+				t.Errorf("access token issued for %q, want %q", response.AccessToken, tt.wantClient)
+			}
+		})
+	}
+}
+
+func TestExchangeCode(t *testing.T) {
+	tests := []struct {
+		name string
+		// tweak меняет запрос обмена кода spa; код уже выдан
+		tweak   func(e *testOAuth, request *oauth.TokenRequest)
+		wantErr error
+	}{
+		{"valid code", nil, nil},
+		{"wrong verifier", func(e *testOAuth, request *oauth.TokenRequest) {
+			request.CodeVerifier = testCodeVerifier + "x"
+		}, oauthErrors.ErrInvalidGrant},
+		{"missing verifier", func(e *testOAuth, request *oauth.TokenRequest) {
+			request.CodeVerifier = ""
+		}, oauthErrors.ErrInvalidRequest},
+		{"other redirect_uri", func(e *testOAuth, request *oauth.TokenRequest) {
+			request.RedirectUri = "http://app/other"
+		}, oauthErrors.ErrInvalidGrant},
+		{"unknown code", func(e *testOAuth, request *oauth.TokenRequest) {
+			request.Code = "unknown"
+		}, oauthErrors.ErrInvalidGrant},
+		{"code used twice", func(e *testOAuth, request *oauth.TokenRequest) {
+			if _, err := e.o.Token(context.Background(), *request); err != nil {
+				panic(err)
+			}
+		}, oauthErrors.ErrInvalidGrant},
+		{"expired code", func(e *testOAuth, request *oauth.TokenRequest) {
+			for _, code := range e.codes.codes {
+				code.ExpiresAt = time.Now().Add(-time.Second)
+			}
+		}, oauthErrors.ErrInvalidGrant},
+		{"archived user", func(e *testOAuth, request *oauth.TokenRequest) {
+			e.users.users[1].IsArchived = true
+		}, oauthErrors.ErrInvalidGrant},
+		{"client without authorization_code grant", func(e *testOAuth, request *oauth.TokenRequest) {
+			request.ClientAuth = oauth.ClientAuth{ClientId: "worker", ClientSecret: e.workerSecret}
+		}, oauthErrors.ErrUnauthorizedClient},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestOAuth()
+			request := oauth.TokenRequest{
+				ClientAuth:   oauth.ClientAuth{ClientId: "spa"},
+				GrantType:    "authorization_code",
+				Code:         e.issueCode(t, "spa"),
+				RedirectUri:  testRedirectUri,
+				CodeVerifier: testCodeVerifier,
+			}
+			if tt.tweak != nil {
+				tt.tweak(e, &request)
+			}
+
+			response, err := e.o.Token(context.Background(), request)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Token: got %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			// scope openid - ID токен для клиента, которому выдан код
+			if response.IdToken != "id:spa" || response.Scope != "openid" {
+				t.Errorf("got id token %q and scope %q", response.IdToken, response.Scope)
+			}
+		})
+	}
+}
+
+func TestAuthenticateClient(t *testing.T) {
+	tests := []struct {
+		name       string
+		clientAuth func(e *testOAuth) oauth.ClientAuth
+		wantErr    error
+		wantClient string
+	}{
+		{"public client by client_id", func(e *testOAuth) oauth.ClientAuth {
+			return oauth.ClientAuth{ClientId: "spa"}
+		}, nil, "spa"},
+		{"without client_id", func(e *testOAuth) oauth.ClientAuth {
+			return oauth.ClientAuth{}
+		}, oauthErrors.ErrInvalidClient, ""},
+		{"unknown client", func(e *testOAuth) oauth.ClientAuth {
+			return oauth.ClientAuth{ClientId: "evil"}
+		}, oauthErrors.ErrInvalidClient, ""},
+		{"disabled client", func(e *testOAuth) oauth.ClientAuth {
+			return oauth.ClientAuth{ClientId: "old"}
+		}, oauthErrors.ErrInvalidClient, ""},
+		{"confidential client with secret", func(e *testOAuth) oauth.ClientAuth {
+			return oauth.ClientAuth{ClientId: "worker", ClientSecret: e.workerSecret}
+		}, nil, "worker"},
+		{"confidential client with wrong secret", func(e *testOAuth) oauth.ClientAuth {
+			return oauth.ClientAuth{ClientId: "worker", ClientSecret: "wrong"}
+		}, oauthErrors.ErrInvalidClient, ""},
+		// конфиденциальный клиент не может выдать себя за публичный
+		{"confidential client without secret", func(e *testOAuth) oauth.ClientAuth {
+			return oauth.ClientAuth{ClientId: "worker"}
+		}, oauthErrors.ErrInvalidClient, ""},
+		{"assertion", func(e *testOAuth) oauth.ClientAuth {
+			return assertion("backend", "1")
+		}, nil, "backend"},
+		{"assertion of wrong type", func(e *testOAuth) oauth.ClientAuth {
+			return oauth.ClientAuth{ClientAssertionType: "urn:unknown", ClientAssertion: "backend:1"}
+		}, oauthErrors.ErrInvalidClient, ""},
+		{"assertion for another client_id", func(e *testOAuth) oauth.ClientAuth {
+			clientAuth := assertion("backend", "1")
+			clientAuth.ClientId = "spa"
+			return clientAuth
+		}, oauthErrors.ErrInvalidClient, ""},
+		{"assertion of client without key", func(e *testOAuth) oauth.ClientAuth {
+			return assertion("spa", "1")
+		}, oauthErrors.ErrInvalidClient, ""},
+		{"assertion of disabled client", func(e *testOAuth) oauth.ClientAuth {
+			return assertion("old", "1")
+		}, oauthErrors.ErrInvalidClient, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestOAuth()
+			client, err := e.o.authenticateClient(context.Background(), tt.clientAuth(e))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("authenticateClient: got %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && client.Id != tt.wantClient {
+				t.Errorf("authenticated %q, want %q", client.Id, tt.wantClient)
+			}
+		})
+	}
+}
+
+// Каждая client_assertion принимается один раз: перехваченную assertion нельзя предъявить снова
+func TestClientAssertionReplay(t *testing.T) {
+	e := newTestOAuth()
+	steps := []struct {
+		name    string
+		jti     string
+		wantErr error
+	}{
+		{"first use", "1", nil},
+		{"replay", "1", oauthErrors.ErrInvalidClient},
+		{"new assertion", "2", nil},
+		{"replay of new assertion", "2", oauthErrors.ErrInvalidClient},
+	}
+	for _, step := range steps {
+		_, err := e.o.authenticateClient(context.Background(), assertion("backend", step.jti))
+		if !errors.Is(err, step.wantErr) {
+			t.Errorf("%s: got %v, want %v", step.name, err, step.wantErr)
+		}
+	}
+}
+
+func TestIntrospect(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare возвращает токен и подсказку его типа
+		prepare       func(e *testOAuth) (string, string)
+		caller        string
+		wantErr       error
+		wantActive    bool
+		wantTokenType string
+	}{
+		{"active access token", func(e *testOAuth) (string, string) {
+			return e.access("a1", "spa"), ""
+		}, "worker", nil, true, tokenTypeHintAccess},
+		{"revoked access token", func(e *testOAuth) (string, string) {
+			e.denylist.revoked["a1"] = true
+			return e.access("a1", "spa"), ""
+		}, "worker", nil, false, ""},
+		{"active refresh token", func(e *testOAuth) (string, string) {
+			return e.refresh("r1", "spa"), tokenTypeHintRefresh
+		}, "worker", nil, true, tokenTypeHintRefresh},
+		{"refresh token with wrong hint", func(e *testOAuth) (string, string) {
+			return e.refresh("r1", "spa"), tokenTypeHintAccess
+		}, "worker", nil, true, tokenTypeHintRefresh},
+		{"unknown token", func(e *testOAuth) (string, string) {
+			return "garbage", ""
+		}, "worker", nil, false, ""},
+		{"service token", func(e *testOAuth) (string, string) {
+			e.jwt.tokens["service"] = &claims.Claims{TokenType: claims.TokenTypeAccess, TokenId: "s1", ClientId: "worker",
+				IssuedAt: time.Now(), ExpiresAt: time.Now().Add(time.Minute)}
+			return "service", ""
+		}, "worker", nil, true, tokenTypeHintAccess},
+		{"token of archived user", func(e *testOAuth) (string, string) {
+			e.users.users[1].IsArchived = true
+			return e.access("a1", "spa"), ""
+		}, "worker", nil, false, ""},
+		{"public client asks", func(e *testOAuth) (string, string) {
+			return e.access("a1", "spa"), ""
+		}, "spa", oauthErrors.ErrConfidentialClientRequired, false, ""},
+		{"empty token", func(e *testOAuth) (string, string) {
+			return "", ""
+		}, "worker", oauthErrors.ErrInvalidRequest, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestOAuth()
+			token, hint := tt.prepare(e)
+			clientAuth := oauth.ClientAuth{ClientId: tt.caller}
+			if tt.caller == "worker" {
+				clientAuth.ClientSecret = e.workerSecret
+			}
+
+			response, err := e.o.Introspect(context.Background(), oauth.IntrospectRequest{ClientAuth: clientAuth, Token: token, TokenTypeHint: hint})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Introspect: got %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if response.Active != tt.wantActive || response.TokenType != tt.wantTokenType {
+				t.Errorf("got active %v type %q, want %v %q", response.Active, response.TokenType, tt.wantActive, tt.wantTokenType)
+			}
+			// у сервисного токена sub - сам клиент
+			wantSub, wantUsername := "1", "bob"
+			if response.ClientId == "worker" {
+				wantSub, wantUsername = "worker", ""
+			}
+			if response.Active && (response.Sub != wantSub || response.Username != wantUsername) {
+				t.Errorf("got sub %q username %q", response.Sub, response.Username)
+			}
+		})
+	}
+}
+
+// Роль в ответе - текущая из базы, а не та, что была при выпуске токена
+func TestIntrospectCurrentRole(t *testing.T) {
+	e := newTestOAuth()
+	token := e.access("a1", "spa")
+	e.users.users[1].RoleId = domain.RoleAdmin
+
+	response, err := e.o.Introspect(context.Background(), oauth.IntrospectRequest{
+		ClientAuth: oauth.ClientAuth{ClientId: "worker", ClientSecret: e.workerSecret},
+		Token:      token,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.RoleId != domain.RoleAdmin {
+		t.Errorf("role_id: got %d, want %d", response.RoleId, domain.RoleAdmin)
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	tests := []struct {
+		name          string
+		prepare       func(e *testOAuth) string
+		caller        oauth.ClientAuth
+		wantErr       error
+		wantRevoked   bool
+		wantLoggedOut bool
+	}{
+		{"own access token", func(e *testOAuth) string {
+			return e.access("a1", "spa")
+		}, oauth.ClientAuth{ClientId: "spa"}, nil, true, false},
+		// refresh токен завершает всю сессию
+		{"own refresh token", func(e *testOAuth) string {
+			return e.refresh("r1", "spa")
+		}, oauth.ClientAuth{ClientId: "spa"}, nil, false, true},
+		{"access token of another client", func(e *testOAuth) string {
+			return e.access("a1", "backend")
+		}, oauth.ClientAuth{ClientId: "spa"}, oauthErrors.ErrTokenNotOwned, false, false},
+		{"refresh token of another client", func(e *testOAuth) string {
+			return e.refresh("r1", "backend")
+		}, oauth.ClientAuth{ClientId: "spa"}, oauthErrors.ErrTokenNotOwned, false, false},
+		// недействительный токен - не ошибка
+		{"unknown token", func(e *testOAuth) string {
+			return "garbage"
+		}, oauth.ClientAuth{ClientId: "spa"}, nil, false, false},
+		{"empty token", func(e *testOAuth) string {
+			return ""
+		}, oauth.ClientAuth{ClientId: "spa"}, oauthErrors.ErrInvalidRequest, false, false},
+		{"unauthenticated client", func(e *testOAuth) string {
+			return e.access("a1", "spa")
+		}, oauth.ClientAuth{}, oauthErrors.ErrInvalidClient, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestOAuth()
+			token := tt.prepare(e)
+
+			err := e.o.Revoke(context.Background(), oauth.RevokeRequest{ClientAuth: tt.caller, Token: token})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Revoke: got %v, want %v", err, tt.wantErr)
+			}
+			if got := e.denylist.revoked["a1"]; got != tt.wantRevoked {
+				t.Errorf("access token revoked: got %v, want %v", got, tt.wantRevoked)
+			}
+			if got := len(e.auth.loggedOut) > 0; got != tt.wantLoggedOut {
+				t.Errorf("session ended: got %v, want %v", got, tt.wantLoggedOut)
+			}
+		})
+	}
+}
@@ -0,0 +1,400 @@
+package passkey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/internal/dto/auth"
+	passkeyModels "github.com/phenirain/sso/internal/dto/passkey"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	jwtLib "github.com/phenirain/sso/internal/lib/jwt"
+	"github.com/phenirain/sso/internal/lib/webauthn"
+)
+
+type Repository interface {
+	CreatePasskey(ctx context.Context, passkey *domain.Passkey) error
+	GetPasskey(ctx context.Context, id string) (*domain.Passkey, error)
+	GetUserPasskeys(ctx context.Context, userId int64) ([]domain.Passkey, error)
+	UsePasskey(ctx context.Context, passkey *domain.Passkey, previousSignCount int64) (bool, error)
+	DeletePasskey(ctx context.Context, userId int64, id string) (bool, error)
+	CreateChallenge(ctx context.Context, challenge *domain.PasskeyChallenge) error
+	UseChallenge(ctx context.Context, challengeHash string) (*domain.PasskeyChallenge, error)
+}
+
+type UserRepository interface {
+	GetUserWithId(ctx context.Context, uid int64) (*domain.User, error)
+}
+
+// Sessions — выпуск токенов после входа по ключу
+type Sessions interface {
+	CheckIdTokenClient(ctx context.Context, clientId string) error
+	IssueTokens(ctx context.Context, user *domain.User, client *domain.Client, scope string, idToken *jwtLib.IdTokenParams) (*auth.AuthResponse, error)
+}
+
+// challengeTTL — время на церемонию между begin и finish
+const challengeTTL = time.Minute * 5
+
+// defaultPasskeyName — название ключа, если пользователь его не задал
+const defaultPasskeyName = "Passkey"
+
+const (
+	credentialType = "public-key"
+	// ключ заменяет пароль, поэтому аутентификатор обязан проверить пользователя (PIN, биометрия)
+	userVerification = "required"
+	// вход без логина: ключ сам хранит пользователя (discoverable credential)
+	residentKey = "required"
+	attestation = "none"
+)
+
+type Passkey struct {
+	repo     Repository
+	users    UserRepository
+	sessions Sessions
+	rp       *webauthn.RelyingParty
+	// вход только с подтверждённой почтой или телефоном, как и по паролю
+	requireVerified bool
+}
+
+func New(repo Repository, users UserRepository, sessions Sessions, rp *webauthn.RelyingParty, requireVerified bool) *Passkey {
+	return &Passkey{
+		repo:            repo,
+		users:           users,
+		sessions:        sessions,
+		rp:              rp,
+		requireVerified: requireVerified,
+	}
+}
+
+// RegisterBegin выдаёт options для создания нового ключа текущего пользователя
+func (p *Passkey) RegisterBegin(ctx context.Context, userId int64) (*passkeyModels.CreationOptions, error) {
+	user, err := p.getUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	passkeys, err := p.repo.GetUserPasskeys(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := p.newChallenge(ctx, &userId, domain.CeremonyRegistration)
+	if err != nil {
+		return nil, err
+	}
+
+	params := make([]passkeyModels.CredentialParameter, 0, len(webauthn.Algorithms))
+	for _, alg := range webauthn.Algorithms {
+		params = append(params, passkeyModels.CredentialParameter{Type: credentialType, Alg: alg})
+	}
+	return &passkeyModels.CreationOptions{
+		Challenge: challenge,
+		Rp:        passkeyModels.RelyingParty{Id: p.rp.Id, Name: p.rp.Name},
+		User: passkeyModels.User{
+			Id:          userHandle(userId),
+			Name:        user.Login,
+			DisplayName: user.Login,
+		},
+		PubKeyCredParams:   params,
+		Timeout:            challengeTTL.Milliseconds(),
+		ExcludeCredentials: descriptors(passkeys),
+		AuthenticatorSelection: passkeyModels.AuthenticatorSelection{
+			ResidentKey:        residentKey,
+			RequireResidentKey: true,
+			UserVerification:   userVerification,
+		},
+		Attestation: attestation,
+	}, nil
+}
+
+// RegisterFinish проверяет ответ аутентификатора и сохраняет ключ
+func (p *Passkey) RegisterFinish(ctx context.Context, userId int64, request passkeyModels.RegistrationRequest) (*passkeyModels.PasskeyResponse, error) {
+	credential := request.Credential
+	if credential.Type != credentialType {
+		return nil, authErrors.ErrInvalidPasskey
+	}
+	clientDataJSON, err := decode(credential.Response.ClientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+	attestationObject, err := decode(credential.Response.AttestationObject)
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := p.useChallenge(ctx, clientDataJSON, domain.CeremonyRegistration, &userId)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := p.rp.VerifyRegistration(challenge, clientDataJSON, attestationObject, true)
+	if err != nil {
+		slog.Info("passkey registration rejected", "user_id", userId, "err", err)
+		return nil, authErrors.ErrInvalidPasskey
+	}
+	id := webauthn.Encoding.EncodeToString(result.Id)
+	existing, err := p.repo.GetPasskey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, authErrors.ErrInvalidPasskey
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		name = defaultPasskeyName
+	}
+	passkey := domain.NewPasskey(id, userId, name, result.PublicKey, result.SignCount, credential.Response.Transports)
+	if err := p.repo.CreatePasskey(ctx, passkey); err != nil {
+		return nil, err
+	}
+
+	slog.Info("passkey registered", "user_id", userId)
+	return toResponse(passkey), nil
+}
+
+// List возвращает ключи пользователя
+func (p *Passkey) List(ctx context.Context, userId int64) ([]passkeyModels.PasskeyResponse, error) {
+	passkeys, err := p.repo.GetUserPasskeys(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]passkeyModels.PasskeyResponse, 0, len(passkeys))
+	for i := range passkeys {
+		result = append(result, *toResponse(&passkeys[i]))
+	}
+	return result, nil
+}
+
+// Delete удаляет ключ пользователя
+func (p *Passkey) Delete(ctx context.Context, userId int64, id string) error {
+	deleted, err := p.repo.DeletePasskey(ctx, userId, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return authErrors.ErrPasskeyNotFound
+	}
+	slog.Info("passkey deleted", "user_id", userId)
+	return nil
+}
+
+// LoginBegin выдаёт options для входа по ключу. allowCredentials всегда пуст - браузер сам
+// предложит ключи сервиса: список ключей по логину выдавал бы, что логин зарегистрирован
+func (p *Passkey) LoginBegin(ctx context.Context) (*passkeyModels.RequestOptions, error) {
+	// пользователь определяется по ключу из ответа, поэтому challenge к нему не привязан
+	challenge, err := p.newChallenge(ctx, nil, domain.CeremonyLogin)
+	if err != nil {
+		return nil, err
+	}
+	return p.requestOptions(challenge, nil), nil
+}
+
+// LoginFinish — вход по ключу вместо пароля. Ключ с проверкой пользователя сам по себе
+// двухфакторный, поэтому код TOTP после него не спрашиваем
+func (p *Passkey) LoginFinish(ctx context.Context, request passkeyModels.LoginRequest) (*auth.AuthResponse, error) {
+	passkey, err := p.verifyAssertion(ctx, request.Credential, domain.CeremonyLogin, nil)
+	if err != nil {
+		return nil, err
+	}
+	user, err := p.getUser(ctx, passkey.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsArchived {
+		return nil, authErrors.ErrInvalidPasskey
+	}
+	if p.requireVerified && !user.IsVerified() {
+		return nil, authErrors.ErrContactNotVerified
+	}
+
+	// OpenID Connect: клиент просит ID токен
+	var idToken *jwtLib.IdTokenParams
+	if request.ClientId != "" {
+		if err := p.sessions.CheckIdTokenClient(ctx, request.ClientId); err != nil {
+			return nil, err
+		}
+		idToken = &jwtLib.IdTokenParams{
+			Audience: request.ClientId,
+			Nonce:    request.Nonce,
+			AuthTime: time.Now(),
+		}
+	}
+	return p.sessions.IssueTokens(ctx, user, nil, "", idToken)
+}
+
+// SecondFactorOptions выдаёт options для проверки ключа вторым фактором после пароля
+func (p *Passkey) SecondFactorOptions(ctx context.Context, user *domain.User) (*passkeyModels.RequestOptions, error) {
+	passkeys, err := p.repo.GetUserPasskeys(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+	if len(passkeys) == 0 {
+		return nil, authErrors.ErrPasskeyNotFound
+	}
+	challenge, err := p.newChallenge(ctx, &user.Id, domain.CeremonyMfa)
+	if err != nil {
+		return nil, err
+	}
+	return p.requestOptions(challenge, passkeys), nil
+}
+
+// VerifySecondFactor проверяет ответ ключа пользователя на challenge из SecondFactorOptions
+func (p *Passkey) VerifySecondFactor(ctx context.Context, user *domain.User, credential passkeyModels.Credential) error {
+	_, err := p.verifyAssertion(ctx, credential, domain.CeremonyMfa, &user.Id)
+	return err
+}
+
+// verifyAssertion проверяет подпись ключа и счётчик, userId - если пользователь известен заранее
+func (p *Passkey) verifyAssertion(ctx context.Context, credential passkeyModels.Credential, ceremony string, userId *int64) (*domain.Passkey, error) {
+	if credential.Type != credentialType {
+		return nil, authErrors.ErrInvalidPasskey
+	}
+	clientDataJSON, err := decode(credential.Response.ClientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+	authenticatorData, err := decode(credential.Response.AuthenticatorData)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := decode(credential.Response.Signature)
+	if err != nil {
+		return nil, err
+	}
+	rawId, err := decode(credential.RawId)
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := p.useChallenge(ctx, clientDataJSON, ceremony, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	passkey, err := p.repo.GetPasskey(ctx, webauthn.Encoding.EncodeToString(rawId))
+	if err != nil {
+		return nil, err
+	}
+	if passkey == nil || (userId != nil && passkey.UserId != *userId) {
+		return nil, authErrors.ErrInvalidPasskey
+	}
+	// discoverable ключ присылает user handle - он должен совпадать с владельцем ключа
+	if handle := credential.Response.UserHandle; handle != "" && handle != userHandle(passkey.UserId) {
+		return nil, authErrors.ErrInvalidPasskey
+	}
+
+	signCount, err := p.rp.VerifyAssertion(challenge, clientDataJSON, authenticatorData, signature, passkey.PublicKey, uint32(passkey.SignCount), true)
+	if err != nil {
+		if errors.Is(err, webauthn.ErrSignCount) {
+			slog.Warn("passkey sign count did not increase", "user_id", passkey.UserId, "passkey_id", passkey.Id)
+			return nil, authErrors.ErrPasskeyCloned
+		}
+		slog.Info("passkey assertion rejected", "user_id", passkey.UserId, "err", err)
+		return nil, authErrors.ErrInvalidPasskey
+	}
+
+	previousSignCount := passkey.SignCount
+	passkey.Use(signCount)
+	updated, err := p.repo.UsePasskey(ctx, passkey, previousSignCount)
+	if err != nil {
+		return nil, err
+	}
+	// счётчик успел измениться параллельным входом
+	if !updated {
+		return nil, authErrors.ErrInvalidPasskey
+	}
+	return passkey, nil
+}
+
+// newChallenge создаёт и сохраняет challenge церемонии
+func (p *Passkey) newChallenge(ctx context.Context, userId *int64, ceremony string) (string, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return "", err
+	}
+	if err := p.repo.CreateChallenge(ctx, domain.NewPasskeyChallenge(challenge, userId, ceremony, challengeTTL)); err != nil {
+		return "", err
+	}
+	return challenge, nil
+}
+
+// useChallenge находит и гасит challenge из clientDataJSON. Совпадение самого challenge
+// с подписанными данными дальше проверяет webauthn
+func (p *Passkey) useChallenge(ctx context.Context, clientDataJSON []byte, ceremony string, userId *int64) (string, error) {
+	challenge, err := webauthn.Challenge(clientDataJSON)
+	if err != nil {
+		return "", authErrors.ErrInvalidPasskey
+	}
+	stored, err := p.repo.UseChallenge(ctx, domain.HashToken(challenge))
+	if err != nil {
+		return "", err
+	}
+	if stored == nil || stored.IsExpired() || stored.Ceremony != ceremony {
+		return "", authErrors.ErrInvalidPasskey
+	}
+	if userId != nil && (stored.UserId == nil || *stored.UserId != *userId) {
+		return "", authErrors.ErrInvalidPasskey
+	}
+	return challenge, nil
+}
+
+func (p *Passkey) requestOptions(challenge string, passkeys []domain.Passkey) *passkeyModels.RequestOptions {
+	return &passkeyModels.RequestOptions{
+		Challenge:        challenge,
+		Timeout:          challengeTTL.Milliseconds(),
+		RpId:             p.rp.Id,
+		AllowCredentials: descriptors(passkeys),
+		UserVerification: userVerification,
+	}
+}
+
+func (p *Passkey) getUser(ctx context.Context, userId int64) (*domain.User, error) {
+	user, err := p.users.GetUserWithId(ctx, userId)
+	if err != nil {
+		errorText := fmt.Errorf("ошибка получения пользователя по идентфикатору: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+	if user == nil {
+		return nil, authErrors.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// userHandle — user.id в WebAuthn. Не должен содержать личных данных, поэтому не логин
+func userHandle(userId int64) string {
+	return webauthn.Encoding.EncodeToString([]byte(strconv.FormatInt(userId, 10)))
+}
+
+func descriptors(passkeys []domain.Passkey) []passkeyModels.CredentialDescriptor {
+	result := make([]passkeyModels.CredentialDescriptor, 0, len(passkeys))
+	for _, passkey := range passkeys {
+		result = append(result, passkeyModels.CredentialDescriptor{
+			Type:       credentialType,
+			Id:         passkey.Id,
+			Transports: passkey.Transports,
+		})
+	}
+	return result
+}
+
+func toResponse(passkey *domain.Passkey) *passkeyModels.PasskeyResponse {
+	return &passkeyModels.PasskeyResponse{
+		Id:           passkey.Id,
+		Name:         passkey.Name,
+		CreationTime: passkey.CreationTime,
+		LastUsedTime: passkey.LastUsedTime,
+	}
+}
+
+// decode — бинарное поле ответа. Некоторые библиотеки присылают base64url с выравниванием
+func decode(value string) ([]byte, error) {
+	data, err := webauthn.Encoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil || len(data) == 0 {
+		return nil, authErrors.ErrInvalidPasskey
+	}
+	return data, nil
+}
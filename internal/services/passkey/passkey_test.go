@@ -0,0 +1,424 @@
+package passkey
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/internal/dto/auth"
+	passkeyModels "github.com/phenirain/sso/internal/dto/passkey"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	jwtLib "github.com/phenirain/sso/internal/lib/jwt"
+	"github.com/phenirain/sso/internal/lib/webauthn"
+	"github.com/phenirain/sso/internal/lib/webauthn/webauthntest"
+)
+
+const (
+	testRpId   = "sso.example.com"
+	testOrigin = "https://sso.example.com"
+)
+
+// fakeRepo — ключи и challenge в памяти
+type fakeRepo struct {
+	mu         sync.Mutex
+	passkeys   map[string]domain.Passkey
+	challenges map[string]domain.PasskeyChallenge
+}
+
+func (r *fakeRepo) CreatePasskey(ctx context.Context, passkey *domain.Passkey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.passkeys[passkey.Id] = *passkey
+	return nil
+}
+
+func (r *fakeRepo) GetPasskey(ctx context.Context, id string) (*domain.Passkey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	passkey, ok := r.passkeys[id]
+	if !ok {
+		return nil, nil
+	}
+	return &passkey, nil
+}
+
+func (r *fakeRepo) GetUserPasskeys(ctx context.Context, userId int64) ([]domain.Passkey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []domain.Passkey
+	for _, passkey := range r.passkeys {
+		if passkey.UserId == userId {
+			result = append(result, passkey)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeRepo) UsePasskey(ctx context.Context, passkey *domain.Passkey, previousSignCount int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.passkeys[passkey.Id]
+	if !ok || stored.SignCount != previousSignCount {
+		return false, nil
+	}
+	r.passkeys[passkey.Id] = *passkey
+	return true, nil
+}
+
+func (r *fakeRepo) DeletePasskey(ctx context.Context, userId int64, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.passkeys[id]
+	if !ok || stored.UserId != userId {
+		return false, nil
+	}
+	delete(r.passkeys, id)
+	return true, nil
+}
+
+func (r *fakeRepo) CreateChallenge(ctx context.Context, challenge *domain.PasskeyChallenge) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.challenges[challenge.ChallengeHash] = *challenge
+	return nil
+}
+
+func (r *fakeRepo) UseChallenge(ctx context.Context, challengeHash string) (*domain.PasskeyChallenge, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	challenge, ok := r.challenges[challengeHash]
+	if !ok {
+		return nil, nil
+	}
+	delete(r.challenges, challengeHash)
+	return &challenge, nil
+}
+
+type fakeUsers map[int64]*domain.User
+
+func (u fakeUsers) GetUserWithId(ctx context.Context, uid int64) (*domain.User, error) {
+	return u[uid], nil
+}
+
+// fakeSessions выдаёт токен с логином пользователя вместо JWT
+type fakeSessions struct{}
+
+// CheckIdTokenClient знает только клиента grafana
+func (fakeSessions) CheckIdTokenClient(ctx context.Context, clientId string) error {
+	if clientId != "grafana" {
+		return authErrors.ErrUnknownClient
+	}
+	return nil
+}
+
+func (fakeSessions) IssueTokens(ctx context.Context, user *domain.User, client *domain.Client, scope string, idToken *jwtLib.IdTokenParams) (*auth.AuthResponse, error) {
+	return &auth.AuthResponse{AccessToken: user.Login}, nil
+}
+
+func newTestPasskey() (*Passkey, *fakeRepo) {
+	repo := &fakeRepo{passkeys: map[string]domain.Passkey{}, challenges: map[string]domain.PasskeyChallenge{}}
+	users := fakeUsers{
+		1: {Id: 1, Login: "first@example.com"},
+		2: {Id: 2, Login: "second@example.com"},
+	}
+	rp := webauthn.New(testRpId, "SSO", []string{testOrigin})
+	return New(repo, users, fakeSessions{}, rp, false), repo
+}
+
+// register добавляет пользователю ключ программного аутентификатора
+func register(t *testing.T, p *Passkey, userId int64, authenticator *webauthntest.Authenticator) {
+	t.Helper()
+	ctx := context.Background()
+	options, err := p.RegisterBegin(ctx, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.User.Id != userHandle(userId) {
+		t.Fatalf("user handle = %q, want %q", options.User.Id, userHandle(userId))
+	}
+	clientDataJSON, attestationObject := authenticator.Create(options.Challenge)
+	request := passkeyModels.RegistrationRequest{
+		Name: "laptop",
+		Credential: passkeyModels.Credential{
+			Id:    webauthntest.Encode(authenticator.Id),
+			RawId: webauthntest.Encode(authenticator.Id),
+			Type:  credentialType,
+			Response: passkeyModels.CredentialResponse{
+				ClientDataJSON:    webauthntest.Encode(clientDataJSON),
+				AttestationObject: webauthntest.Encode(attestationObject),
+			},
+		},
+	}
+	if _, err := p.RegisterFinish(ctx, userId, request); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+}
+
+// assertion — ответ аутентификатора на challenge
+func assertion(authenticator *webauthntest.Authenticator, challenge string, userId int64) passkeyModels.Credential {
+	clientDataJSON, authenticatorData, signature := authenticator.Get(challenge)
+	return passkeyModels.Credential{
+		Id:    webauthntest.Encode(authenticator.Id),
+		RawId: webauthntest.Encode(authenticator.Id),
+		Type:  credentialType,
+		Response: passkeyModels.CredentialResponse{
+			ClientDataJSON:    webauthntest.Encode(clientDataJSON),
+			AuthenticatorData: webauthntest.Encode(authenticatorData),
+			Signature:         webauthntest.Encode(signature),
+			UserHandle:        userHandle(userId),
+		},
+	}
+}
+
+func login(t *testing.T, p *Passkey, authenticator *webauthntest.Authenticator, userId int64) (*auth.AuthResponse, error) {
+	t.Helper()
+	options, err := p.LoginBegin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.LoginFinish(context.Background(), passkeyModels.LoginRequest{Credential: assertion(authenticator, options.Challenge, userId)})
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	tests := []struct {
+		name string
+		new  func(rpId, origin string) *webauthntest.Authenticator
+	}{
+		{"ES256", webauthntest.NewES256},
+		{"Ed25519", webauthntest.NewEd25519},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, repo := newTestPasskey()
+			authenticator := tt.new(testRpId, testOrigin)
+			register(t, p, 1, authenticator)
+
+			for i := 0; i < 2; i++ {
+				result, err := login(t, p, authenticator, 1)
+				if err != nil {
+					t.Fatalf("login %d: %v", i+1, err)
+				}
+				if result.AccessToken != "first@example.com" {
+					t.Fatalf("logged in as %q", result.AccessToken)
+				}
+			}
+			stored := repo.passkeys[webauthntest.Encode(authenticator.Id)]
+			if stored.SignCount != 3 || stored.LastUsedTime == nil {
+				t.Errorf("stored sign count = %d, last used %v", stored.SignCount, stored.LastUsedTime)
+			}
+		})
+	}
+}
+
+func TestLoginBeginDoesNotListCredentials(t *testing.T) {
+	p, _ := newTestPasskey()
+	register(t, p, 1, webauthntest.NewES256(testRpId, testOrigin))
+
+	options, err := p.LoginBegin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(options.AllowCredentials) != 0 {
+		t.Errorf("allowCredentials = %v, want empty", options.AllowCredentials)
+	}
+}
+
+func TestLoginChecksIdTokenClient(t *testing.T) {
+	p, _ := newTestPasskey()
+	authenticator := webauthntest.NewES256(testRpId, testOrigin)
+	register(t, p, 1, authenticator)
+	ctx := context.Background()
+
+	tests := []struct {
+		clientId string
+		wantErr  error
+	}{
+		{"", nil},
+		{"grafana", nil},
+		{"unknown", authErrors.ErrUnknownClient},
+	}
+	for _, tt := range tests {
+		t.Run(tt.clientId, func(t *testing.T) {
+			options, err := p.LoginBegin(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = p.LoginFinish(ctx, passkeyModels.LoginRequest{
+				Credential: assertion(authenticator, options.Challenge, 1),
+				ClientId:   tt.clientId,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("LoginFinish: got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegisterRejectsDuplicate(t *testing.T) {
+	p, _ := newTestPasskey()
+	authenticator := webauthntest.NewES256(testRpId, testOrigin)
+	register(t, p, 1, authenticator)
+
+	options, err := p.RegisterBegin(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientDataJSON, attestationObject := authenticator.Create(options.Challenge)
+	_, err = p.RegisterFinish(context.Background(), 2, passkeyModels.RegistrationRequest{Credential: passkeyModels.Credential{
+		Type: credentialType,
+		Response: passkeyModels.CredentialResponse{
+			ClientDataJSON:    webauthntest.Encode(clientDataJSON),
+			AttestationObject: webauthntest.Encode(attestationObject),
+		},
+	}})
+	if !errors.Is(err, authErrors.ErrInvalidPasskey) {
+		t.Errorf("got %v, want ErrInvalidPasskey", err)
+	}
+}
+
+func TestLoginRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(a *webauthntest.Authenticator)
+		// подменяет ответ после подписи
+		tamper  func(credential *passkeyModels.Credential)
+		wantErr error
+	}{
+		{
+			name:    "wrong origin",
+			prepare: func(a *webauthntest.Authenticator) { a.Origin = "https://evil.example.com" },
+			wantErr: authErrors.ErrInvalidPasskey,
+		},
+		{
+			name:    "wrong rpId",
+			prepare: func(a *webauthntest.Authenticator) { a.RpId = "evil.example.com" },
+			wantErr: authErrors.ErrInvalidPasskey,
+		},
+		{
+			name:    "user not verified",
+			prepare: func(a *webauthntest.Authenticator) { a.Flags = webauthntest.FlagUserPresent },
+			wantErr: authErrors.ErrInvalidPasskey,
+		},
+		{
+			name:    "counter regression",
+			prepare: func(a *webauthntest.Authenticator) { a.SignCount = 1 },
+			wantErr: authErrors.ErrPasskeyCloned,
+		},
+		{
+			name:    "other user handle",
+			tamper:  func(c *passkeyModels.Credential) { c.Response.UserHandle = userHandle(2) },
+			wantErr: authErrors.ErrInvalidPasskey,
+		},
+		{
+			name:    "unknown credential",
+			tamper:  func(c *passkeyModels.Credential) { c.RawId = webauthntest.Encode([]byte("unknown")) },
+			wantErr: authErrors.ErrInvalidPasskey,
+		},
+		{
+			name:    "not base64url",
+			tamper:  func(c *passkeyModels.Credential) { c.Response.Signature = "!!!" },
+			wantErr: authErrors.ErrInvalidPasskey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newTestPasskey()
+			authenticator := webauthntest.NewES256(testRpId, testOrigin)
+			register(t, p, 1, authenticator)
+			// один успешный вход: счётчик на сервере 2
+			if _, err := login(t, p, authenticator, 1); err != nil {
+				t.Fatal(err)
+			}
+			if tt.prepare != nil {
+				tt.prepare(authenticator)
+			}
+
+			options, err := p.LoginBegin(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			credential := assertion(authenticator, options.Challenge, 1)
+			if tt.tamper != nil {
+				tt.tamper(&credential)
+			}
+			_, err = p.LoginFinish(context.Background(), passkeyModels.LoginRequest{Credential: credential})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestChallengeIsSingleUseAndBoundToCeremony(t *testing.T) {
+	ctx := context.Background()
+	p, _ := newTestPasskey()
+	authenticator := webauthntest.NewES256(testRpId, testOrigin)
+	register(t, p, 1, authenticator)
+
+	options, err := p.LoginBegin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credential := assertion(authenticator, options.Challenge, 1)
+	if _, err := p.LoginFinish(ctx, passkeyModels.LoginRequest{Credential: credential}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.LoginFinish(ctx, passkeyModels.LoginRequest{Credential: credential}); !errors.Is(err, authErrors.ErrInvalidPasskey) {
+		t.Errorf("replay: got %v, want ErrInvalidPasskey", err)
+	}
+
+	// challenge регистрации не годится для входа
+	creation, err := p.RegisterBegin(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credential = assertion(authenticator, creation.Challenge, 1)
+	if _, err := p.LoginFinish(ctx, passkeyModels.LoginRequest{Credential: credential}); !errors.Is(err, authErrors.ErrInvalidPasskey) {
+		t.Errorf("registration challenge: got %v, want ErrInvalidPasskey", err)
+	}
+}
+
+func TestSecondFactor(t *testing.T) {
+	ctx := context.Background()
+	p, _ := newTestPasskey()
+	first := webauthntest.NewEd25519(testRpId, testOrigin)
+	second := webauthntest.NewES256(testRpId, testOrigin)
+	register(t, p, 1, first)
+	register(t, p, 2, second)
+	user := &domain.User{Id: 1, Login: "first@example.com"}
+
+	options, err := p.SecondFactorOptions(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(options.AllowCredentials) != 1 || options.AllowCredentials[0].Id != webauthntest.Encode(first.Id) {
+		t.Fatalf("allowCredentials = %v, want only the user's key", options.AllowCredentials)
+	}
+	// ключ другого пользователя не подходит
+	if err := p.VerifySecondFactor(ctx, user, assertion(second, options.Challenge, 2)); !errors.Is(err, authErrors.ErrInvalidPasskey) {
+		t.Errorf("other user's key: got %v, want ErrInvalidPasskey", err)
+	}
+
+	options, err = p.SecondFactorOptions(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.VerifySecondFactor(ctx, user, assertion(first, options.Challenge, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	// challenge второго фактора привязан к пользователю
+	options, err = p.SecondFactorOptions(ctx, &domain.User{Id: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.VerifySecondFactor(ctx, user, assertion(first, options.Challenge, 1)); !errors.Is(err, authErrors.ErrInvalidPasskey) {
+		t.Errorf("other user's challenge: got %v, want ErrInvalidPasskey", err)
+	}
+
+	if _, err := p.SecondFactorOptions(ctx, &domain.User{Id: 3}); !errors.Is(err, authErrors.ErrPasskeyNotFound) {
+		t.Errorf("user without keys: got %v, want ErrPasskeyNotFound", err)
+	}
+}
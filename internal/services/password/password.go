@@ -0,0 +1,189 @@
+package password
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/phenirain/sso/internal/domain"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	"github.com/phenirain/sso/internal/lib/i18n"
+	"github.com/phenirain/sso/internal/lib/notify"
+	"github.com/phenirain/sso/pkg/contextkeys"
+)
+
+type Repository interface {
+	GetUserByLogin(ctx context.Context, login string) (*domain.User, error)
+	GetUserWithId(ctx context.Context, uid int64) (*domain.User, error)
+	UpdatePassword(ctx context.Context, user *domain.User) error
+}
+
+type ResetTokenRepository interface {
+	CreateResetToken(ctx context.Context, token *domain.PasswordResetToken) error
+	UseResetToken(ctx context.Context, tokenHash string) (*domain.PasswordResetToken, error)
+	InvalidateUserTokens(ctx context.Context, userId int64) error
+}
+
+// Sessions — завершение сессий пользователя после смены пароля
+type Sessions interface {
+	LogoutAll(ctx context.Context, userId int64) error
+}
+
+// Lockout — запросы ссылки считаются в счётчике IP, как попытки входа; после сброса пароля
+// снимаем блокировку входа по логину
+type Lockout interface {
+	AttemptIp(ctx context.Context, ip string) error
+	Unlock(ctx context.Context, login, ip string) error
+}
+
+// Notifier доставляет письмо со ссылкой для сброса
+type Notifier interface {
+	Send(ctx context.Context, message notify.Message) error
+}
+
+// sendTimeout ограничивает отправку письма, которая идёт уже после ответа на запрос
+const sendTimeout = time.Minute
+
+type Password struct {
+	repo     Repository
+	tokens   ResetTokenRepository
+	sessions Sessions
+	lockout  Lockout
+	notifier Notifier
+	resetUrl string
+	ttl      time.Duration
+}
+
+func New(repo Repository, tokens ResetTokenRepository, sessions Sessions, lockout Lockout, notifier Notifier, resetUrl string, ttl time.Duration) *Password {
+	return &Password{
+		repo:     repo,
+		tokens:   tokens,
+		sessions: sessions,
+		lockout:  lockout,
+		notifier: notifier,
+		resetUrl: resetUrl,
+		ttl:      ttl,
+	}
+}
+
+// Forgot отправляет ссылку для сброса пароля на подтверждённую почту пользователя.
+// Для несуществующего пользователя и пользователя без подтверждённой почты ничего не делает
+// и ошибку не возвращает, как и при сбое отправки письма, - чтобы по ответу нельзя было узнать,
+// зарегистрирован ли логин. Письмо отправляется в фоне: иначе время ответа выдавало бы то же самое.
+// Запросы с одного адреса ограничены блокировкой по IP
+func (p *Password) Forgot(ctx context.Context, login string) error {
+	const op string = "Password.Forgot"
+
+	ip, _ := ctx.Value(contextkeys.ClientIPCtxKey).(string)
+	if err := p.lockout.AttemptIp(ctx, ip); err != nil {
+		return err
+	}
+
+	user, err := p.repo.GetUserByLogin(ctx, login)
+	if err != nil {
+		slog.Error("failed to get user", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if user == nil || user.IsArchived {
+		slog.Info("password reset requested for unknown user")
+		return nil
+	}
+	if user.Email == "" || !user.EmailVerified {
+		slog.Info("password reset requested, but user has no verified email", "user_id", user.Id)
+		return nil
+	}
+
+	token, value := domain.NewPasswordResetToken(user.Id, p.ttl)
+	if err := p.tokens.CreateResetToken(ctx, token); err != nil {
+		errorText := fmt.Errorf("ошибка сохранения токена сброса пароля: %w", err)
+		slog.Error(errorText.Error())
+		return errorText
+	}
+
+	link, err := url.Parse(p.resetUrl)
+	if err != nil {
+		errorText := fmt.Errorf("некорректный адрес страницы сброса пароля: %w", err)
+		slog.Error(errorText.Error())
+		return errorText
+	}
+	query := link.Query()
+	query.Set("token", value)
+	link.RawQuery = query.Encode()
+
+	lang := i18n.FromContext(ctx)
+	message := notify.Message{
+		To:      user.Email,
+		Subject: i18n.Text(lang, i18n.MsgResetEmailSubject),
+		Body:    fmt.Sprintf(i18n.Text(lang, i18n.MsgResetEmailBody), link.String(), int(p.ttl.Minutes())),
+	}
+	// контекст запроса отменится вместе с ответом, а значения из него (trace_id) нужны в логах
+	sendCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sendTimeout)
+	go func() {
+		defer cancel()
+		p.send(sendCtx, user.Id, message)
+	}()
+	return nil
+}
+
+// send отправляет ссылку для сброса. Ошибка только логируется: вернуть её некому
+func (p *Password) send(ctx context.Context, userId int64, message notify.Message) {
+	if err := p.notifier.Send(ctx, message); err != nil {
+		errorText := fmt.Errorf("ошибка отправки ссылки для сброса пароля: %w", err)
+		slog.Error(errorText.Error(), "user_id", userId)
+		return
+	}
+	slog.Info("password reset link sent", "user_id", userId)
+}
+
+// Reset задаёт новый пароль по токену из письма. Токен одноразовый; после сброса
+// остальные ссылки пользователя гаснут, а все его сессии завершаются
+func (p *Password) Reset(ctx context.Context, token, password string) error {
+	const op string = "Password.Reset"
+
+	// неподходящий пароль не должен сжигать одноразовый токен
+	if err := domain.ValidatePassword(password); err != nil {
+		return err
+	}
+
+	resetToken, err := p.tokens.UseResetToken(ctx, domain.HashToken(token))
+	if err != nil {
+		errorText := fmt.Errorf("ошибка получения токена сброса пароля: %w", err)
+		slog.Error(errorText.Error())
+		return errorText
+	}
+	if resetToken == nil || resetToken.IsExpired() {
+		return authErrors.ErrInvalidResetToken
+	}
+
+	user, err := p.repo.GetUserWithId(ctx, resetToken.UserId)
+	if err != nil {
+		slog.Error("failed to get user", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if user == nil || user.IsArchived {
+		return authErrors.ErrInvalidResetToken
+	}
+
+	if err := user.SetPassword(password); err != nil {
+		return err
+	}
+	if err := p.repo.UpdatePassword(ctx, user); err != nil {
+		errorText := fmt.Errorf("ошибка сохранения пароля: %w", err)
+		slog.Error(errorText.Error())
+		return errorText
+	}
+	if err := p.tokens.InvalidateUserTokens(ctx, user.Id); err != nil {
+		return err
+	}
+	if err := p.sessions.LogoutAll(ctx, user.Id); err != nil {
+		return err
+	}
+	if err := p.lockout.Unlock(ctx, user.Login, ""); err != nil {
+		return err
+	}
+
+	slog.Info("password reset", "user_id", user.Id)
+	return nil
+}
@@ -0,0 +1,215 @@
+package verification
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/phenirain/sso/internal/domain"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	"github.com/phenirain/sso/internal/lib/i18n"
+	"github.com/phenirain/sso/internal/lib/notify"
+	"github.com/phenirain/sso/pkg/contextkeys"
+)
+
+type Repository interface {
+	GetUserByLogin(ctx context.Context, login string) (*domain.User, error)
+	UpdateContactVerification(ctx context.Context, user *domain.User) error
+}
+
+type CodeRepository interface {
+	SaveVerification(ctx context.Context, verification *domain.ContactVerification) error
+	GetVerification(ctx context.Context, userId int64, channel string) (*domain.ContactVerification, error)
+	AddAttempt(ctx context.Context, userId int64, channel string) error
+	DeleteVerification(ctx context.Context, userId int64, channel string) error
+}
+
+// Lockout — повторные отправки кода считаются в счётчике IP, как попытки входа
+type Lockout interface {
+	AttemptIp(ctx context.Context, ip string) error
+}
+
+// Notifier доставляет код: на почту - ссылкой, на телефон - SMS
+type Notifier interface {
+	Send(ctx context.Context, message notify.Message) error
+}
+
+type Verification struct {
+	repo      Repository
+	codes     CodeRepository
+	lockout   Lockout
+	notifier  Notifier
+	verifyUrl string
+	emailTTL  time.Duration
+	phoneTTL  time.Duration
+	// Новый код в тот же канал - не чаще: каждый код даёт MaxVerificationAttempts попыток
+	resendInterval time.Duration
+}
+
+func New(repo Repository, codes CodeRepository, lockout Lockout, notifier Notifier, verifyUrl string, emailTTL, phoneTTL, resendInterval time.Duration) *Verification {
+	return &Verification{
+		repo:           repo,
+		codes:          codes,
+		lockout:        lockout,
+		notifier:       notifier,
+		verifyUrl:      verifyUrl,
+		emailTTL:       emailTTL,
+		phoneTTL:       phoneTTL,
+		resendInterval: resendInterval,
+	}
+}
+
+// Start отправляет коды на все неподтверждённые контакты нового пользователя
+func (v *Verification) Start(ctx context.Context, user *domain.User) error {
+	for _, channel := range []string{domain.ChannelEmail, domain.ChannelPhone} {
+		if user.Contact(channel) == "" || user.IsContactVerified(channel) {
+			continue
+		}
+		if err := v.send(ctx, user, channel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Resend отправляет новый код в канал channel. Для неизвестного пользователя, уже
+// подтверждённого контакта и кода, отправленного меньше resendInterval назад, ничего не делает,
+// чтобы по ответу нельзя было узнать о регистрации. Запросы с одного адреса ограничены блокировкой по IP
+func (v *Verification) Resend(ctx context.Context, login, channel string) error {
+	const op string = "Verification.Resend"
+
+	ip, _ := ctx.Value(contextkeys.ClientIPCtxKey).(string)
+	if err := v.lockout.AttemptIp(ctx, ip); err != nil {
+		return err
+	}
+
+	user, err := v.repo.GetUserByLogin(ctx, login)
+	if err != nil {
+		slog.Error("failed to get user", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if user == nil || user.IsArchived || user.Contact(channel) == "" || user.IsContactVerified(channel) {
+		return nil
+	}
+
+	// новый код заменяет старый вместе со счётчиком попыток: без паузы перебор не ограничен
+	previous, err := v.codes.GetVerification(ctx, user.Id, channel)
+	if err != nil {
+		errorText := fmt.Errorf("ошибка получения кода подтверждения: %w", err)
+		slog.Error(errorText.Error())
+		return errorText
+	}
+	if previous != nil && time.Since(previous.CreationTime) < v.resendInterval {
+		slog.Info("verification code resend skipped", "user_id", user.Id, "channel", channel)
+		return nil
+	}
+	return v.send(ctx, user, channel)
+}
+
+// Verify подтверждает контакт пользователя кодом из письма или SMS
+func (v *Verification) Verify(ctx context.Context, login, channel, code string) error {
+	const op string = "Verification.Verify"
+
+	user, err := v.repo.GetUserByLogin(ctx, login)
+	if err != nil {
+		slog.Error("failed to get user", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if user == nil || user.IsArchived || user.Contact(channel) == "" {
+		return authErrors.ErrInvalidVerificationCode
+	}
+	if user.IsContactVerified(channel) {
+		return nil
+	}
+
+	verification, err := v.codes.GetVerification(ctx, user.Id, channel)
+	if err != nil {
+		errorText := fmt.Errorf("ошибка получения кода подтверждения: %w", err)
+		slog.Error(errorText.Error())
+		return errorText
+	}
+	if verification == nil || !verification.IsUsable() {
+		return authErrors.ErrInvalidVerificationCode
+	}
+	if !verification.CheckCode(code) {
+		if err := v.codes.AddAttempt(ctx, user.Id, channel); err != nil {
+			return err
+		}
+		return authErrors.ErrInvalidVerificationCode
+	}
+
+	user.VerifyContact(channel)
+	if err := v.repo.UpdateContactVerification(ctx, user); err != nil {
+		errorText := fmt.Errorf("ошибка сохранения подтверждения контакта: %w", err)
+		slog.Error(errorText.Error())
+		return errorText
+	}
+	if err := v.codes.DeleteVerification(ctx, user.Id, channel); err != nil {
+		return err
+	}
+
+	slog.Info("contact verified", "user_id", user.Id, "channel", channel)
+	return nil
+}
+
+func (v *Verification) send(ctx context.Context, user *domain.User, channel string) error {
+	ttl := v.emailTTL
+	if channel == domain.ChannelPhone {
+		ttl = v.phoneTTL
+	}
+
+	verification, code := domain.NewContactVerification(user.Id, channel, ttl)
+	if err := v.codes.SaveVerification(ctx, verification); err != nil {
+		errorText := fmt.Errorf("ошибка сохранения кода подтверждения: %w", err)
+		slog.Error(errorText.Error())
+		return errorText
+	}
+
+	lang := i18n.FromContext(ctx)
+	var message notify.Message
+	if channel == domain.ChannelPhone {
+		message = notify.Message{
+			Channel: notify.SMS,
+			To:      user.Phone,
+			Body:    fmt.Sprintf(i18n.Text(lang, i18n.MsgVerifySmsBody), code, int(ttl.Minutes())),
+		}
+	} else {
+		link, err := v.link(user.Login, channel, code)
+		if err != nil {
+			return err
+		}
+		message = notify.Message{
+			Channel: notify.Email,
+			To:      user.Email,
+			Subject: i18n.Text(lang, i18n.MsgVerifyEmailSubject),
+			Body:    fmt.Sprintf(i18n.Text(lang, i18n.MsgVerifyEmailBody), link, int(ttl.Hours())),
+		}
+	}
+
+	if err := v.notifier.Send(ctx, message); err != nil {
+		errorText := fmt.Errorf("ошибка отправки кода подтверждения: %w", err)
+		slog.Error(errorText.Error())
+		return errorText
+	}
+
+	slog.Info("verification code sent", "user_id", user.Id, "channel", channel)
+	return nil
+}
+
+// link — ссылка на страницу подтверждения во фронтенде, она отправит данные в /auth/contact/verify
+func (v *Verification) link(login, channel, code string) (string, error) {
+	link, err := url.Parse(v.verifyUrl)
+	if err != nil {
+		errorText := fmt.Errorf("некорректный адрес страницы подтверждения: %w", err)
+		slog.Error(errorText.Error())
+		return "", errorText
+	}
+	query := link.Query()
+	query.Set("login", login)
+	query.Set("channel", channel)
+	query.Set("code", code)
+	link.RawQuery = query.Encode()
+	return link.String(), nil
+}
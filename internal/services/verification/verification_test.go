@@ -0,0 +1,133 @@
+package verification
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/phenirain/sso/internal/domain"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	"github.com/phenirain/sso/internal/lib/notify"
+	"github.com/phenirain/sso/pkg/contextkeys"
+)
+
+type fakeUsers struct {
+	user *domain.User
+}
+
+func (r *fakeUsers) GetUserByLogin(ctx context.Context, login string) (*domain.User, error) {
+	if r.user == nil || r.user.Login != login {
+		return nil, nil
+	}
+	return r.user, nil
+}
+
+func (r *fakeUsers) UpdateContactVerification(ctx context.Context, user *domain.User) error {
+	return nil
+}
+
+type fakeCodes struct {
+	saved map[string]*domain.ContactVerification
+}
+
+func (r *fakeCodes) SaveVerification(ctx context.Context, verification *domain.ContactVerification) error {
+	r.saved[verification.Channel] = verification
+	return nil
+}
+
+func (r *fakeCodes) GetVerification(ctx context.Context, userId int64, channel string) (*domain.ContactVerification, error) {
+	return r.saved[channel], nil
+}
+
+func (r *fakeCodes) AddAttempt(ctx context.Context, userId int64, channel string) error {
+	r.saved[channel].Attempts++
+	return nil
+}
+
+func (r *fakeCodes) DeleteVerification(ctx context.Context, userId int64, channel string) error {
+	delete(r.saved, channel)
+	return nil
+}
+
+// fakeLimiter пропускает limit запросов с одного IP
+type fakeLimiter struct {
+	limit    int
+	attempts map[string]int
+}
+
+func (l *fakeLimiter) AttemptIp(ctx context.Context, ip string) error {
+	l.attempts[ip]++
+	if l.attempts[ip] > l.limit {
+		return authErrors.ErrTooManyAttempts
+	}
+	return nil
+}
+
+type fakeNotifier struct {
+	sent int
+}
+
+func (n *fakeNotifier) Send(ctx context.Context, message notify.Message) error {
+	n.sent++
+	return nil
+}
+
+func newTestVerification(limit int) (*Verification, *fakeCodes, *fakeNotifier) {
+	users := &fakeUsers{user: &domain.User{Id: 1, Login: "user", Phone: "+79991234567"}}
+	codes := &fakeCodes{saved: map[string]*domain.ContactVerification{}}
+	notifier := &fakeNotifier{}
+	limiter := &fakeLimiter{limit: limit, attempts: map[string]int{}}
+	return New(users, codes, limiter, notifier, "http://localhost/verify", time.Hour, time.Minute*10, time.Minute), codes, notifier
+}
+
+func withIp(ip string) context.Context {
+	return context.WithValue(context.Background(), contextkeys.ClientIPCtxKey, ip)
+}
+
+func TestResendInterval(t *testing.T) {
+	v, codes, notifier := newTestVerification(100)
+	ctx := withIp("10.0.0.1")
+
+	if err := v.Resend(ctx, "user", domain.ChannelPhone); err != nil {
+		t.Fatal(err)
+	}
+	first := codes.saved[domain.ChannelPhone]
+	_ = codes.AddAttempt(ctx, 1, domain.ChannelPhone)
+
+	// сразу после отправки код не заменяется, счётчик попыток остаётся
+	if err := v.Resend(ctx, "user", domain.ChannelPhone); err != nil {
+		t.Fatal(err)
+	}
+	if notifier.sent != 1 || codes.saved[domain.ChannelPhone] != first || first.Attempts != 1 {
+		t.Fatalf("resend within interval: sent = %d, attempts = %d, want 1 message and kept code", notifier.sent, codes.saved[domain.ChannelPhone].Attempts)
+	}
+
+	first.CreationTime = time.Now().Add(-time.Minute)
+	if err := v.Resend(ctx, "user", domain.ChannelPhone); err != nil {
+		t.Fatal(err)
+	}
+	if notifier.sent != 2 || codes.saved[domain.ChannelPhone] == first {
+		t.Errorf("resend after interval: sent = %d, want 2 and new code", notifier.sent)
+	}
+}
+
+func TestResendLimitedByIp(t *testing.T) {
+	v, _, notifier := newTestVerification(2)
+
+	for i := range 2 {
+		if err := v.Resend(withIp("10.0.0.1"), "nobody", domain.ChannelPhone); err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+	}
+	// неизвестный логин тоже считается: иначе перебор логинов не ограничен
+	if err := v.Resend(withIp("10.0.0.1"), "user", domain.ChannelPhone); !errors.Is(err, authErrors.ErrTooManyAttempts) {
+		t.Fatalf("over limit: got %v, want ErrTooManyAttempts", err)
+	}
+	if err := v.Resend(withIp("10.0.0.2"), "user", domain.ChannelPhone); err != nil {
+		t.Fatalf("other ip: %v", err)
+	}
+	if notifier.sent != 1 {
+		t.Errorf("sent = %d, want 1", notifier.sent)
+	}
+}
@@ -0,0 +1,45 @@
+package claims
+
+import "time"
+
+// TokenType — назначение токена (claim token_use)
+type TokenType string
+
+const (
+	TokenTypeAccess  TokenType = "access"
+	TokenTypeRefresh TokenType = "refresh"
+	// Токен второго шага входа: пароль проверен, ждём код двухфакторной аутентификации
+	TokenTypeMfa TokenType = "mfa"
+)
+
+// Claims — данные пользователя, которые SSO кладёт в токен
+type Claims struct {
+	// Идентификатор пользователя (sub). 0 в токене сервиса - там sub это ClientId
+	UserId int64
+	// Роль пользователя (role_id)
+	RoleId int64
+	// Назначение токена (token_use)
+	TokenType TokenType
+	// Идентификатор токена (jti)
+	TokenId string
+	// Сессия, в которой выпущен токен (sid) - семейство refresh токенов. С отзывом сессии
+	// недействительны и её access токены
+	SessionId string
+	// Время выпуска (iat)
+	IssuedAt time.Time
+	// Время истечения (exp)
+	ExpiresAt time.Time
+	// Клиент OAuth, которому выдан токен (client_id)
+	ClientId string
+	// Для кого предназначен токен (aud)
+	Audience string
+	// Права через пробел (scope)
+	Scope string
+	// nonce OpenID Connect, переносится токеном mfa до выпуска ID токена
+	Nonce string
+}
+
+// IsService — токен выпущен сервису по client_credentials, а не пользователю
+func (c *Claims) IsService() bool {
+	return c.UserId == 0 && c.ClientId != ""
+}
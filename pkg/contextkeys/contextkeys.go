@@ -4,4 +4,8 @@ type CtxKey string
 
 const RequestIDCtxKey CtxKey = "request_id"
 const TraceIDCtxKey CtxKey = "trace_id"
-const UserIDCtxKey CtxKey = "user_id"
\ No newline at end of file
+const UserIDCtxKey CtxKey = "user_id"
+const RoleIDCtxKey CtxKey = "role_id"
+const ClientIDCtxKey CtxKey = "client_id"
+const LanguageCtxKey CtxKey = "language"
+const ClientIPCtxKey CtxKey = "client_ip"
@@ -0,0 +1,20 @@
+package echomiddleware
+
+import (
+	"context"
+
+	"github.com/labstack/echo/v4"
+	"github.com/phenirain/sso/pkg/contextkeys"
+)
+
+// ClientIP кладёт в контекст адрес клиента, как его определил echo.IPExtractor.
+// По нему сервисы считают неудачные попытки входа с одного адреса
+func ClientIP() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := context.WithValue(c.Request().Context(), contextkeys.ClientIPCtxKey, c.RealIP())
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
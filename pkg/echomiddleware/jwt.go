@@ -2,24 +2,58 @@ package echomiddleware
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/labstack/echo/v4"
+	"github.com/phenirain/sso/pkg/claims"
 	"github.com/phenirain/sso/pkg/contextkeys"
 )
 
+// ErrTokenRevoked — токен отозван до истечения срока
+var ErrTokenRevoked = errors.New("token revoked")
+
 type Jwt interface {
-	ParseToken(tokenString string) (int64, error)
+	ParseToken(tokenString string, tokenType claims.TokenType) (*claims.Claims, error)
+}
+
+// Denylist — список отозванных токенов
+type Denylist interface {
+	IsRevoked(ctx context.Context, tokenClaims *claims.Claims) (bool, error)
 }
 
-func JwtValidation(jwt Jwt) echo.MiddlewareFunc {
+// JwtValidation проверяет access токен. denylist необязателен - если nil,
+// отзыв токенов до истечения срока не проверяется
+func JwtValidation(jwt Jwt, denylist Denylist) echo.MiddlewareFunc {
 	skip := map[string]struct{}{
 		"/auth/logIn":   {},
 		"/auth/signUp":  {},
 		"/auth/refresh": {},
+		"/auth/logout":  {},
+		"/auth/verify":  {},
 		"/health":       {},
 		"/swagger/*":    {},
+
+		"/auth/password/forgot": {},
+		"/auth/password/reset":  {},
+		"/auth/contact/verify":  {},
+		"/auth/contact/resend":  {},
+		"/auth/mfa/verify":      {},
+
+		"/auth/mfa/passkey/begin":     {},
+		"/auth/mfa/passkey/finish":    {},
+		"/auth/passkeys/login/begin":  {},
+		"/auth/passkeys/login/finish": {},
+
+		"/oauth/authorize":  {},
+		"/oauth/token":      {},
+		"/oauth/introspect": {},
+		"/oauth/revoke":     {},
+
+		"/.well-known/jwks.json":            {},
+		"/.well-known/openid-configuration": {},
 	}
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -41,15 +75,32 @@ func JwtValidation(jwt Jwt) echo.MiddlewareFunc {
 			}
 			tokenString := parts[1]
 
-			userId, err := jwt.ParseToken(tokenString)
+			// refresh токен не должен работать как bearer.
+			// Причина отказа - во внутренней ошибке, текст парсера клиенту не отдаётся
+			tokenClaims, err := jwt.ParseToken(tokenString, claims.TokenTypeAccess)
 			if err != nil {
-				return c.JSON(http.StatusUnauthorized, map[string]string{
-					"error": err.Error(),
-				})
+				return echo.NewHTTPError(http.StatusUnauthorized).SetInternal(err)
 			}
 
 			ctx := c.Request().Context()
-			ctx = context.WithValue(ctx, contextkeys.UserIDCtxKey, userId)
+			if denylist != nil {
+				revoked, err := denylist.IsRevoked(ctx, tokenClaims)
+				if err != nil {
+					return fmt.Errorf("failed to check token denylist: %w", err)
+				}
+				if revoked {
+					return echo.NewHTTPError(http.StatusUnauthorized).SetInternal(ErrTokenRevoked)
+				}
+			}
+
+			// у токена сервиса нет пользователя и роли - только клиент
+			if !tokenClaims.IsService() {
+				ctx = context.WithValue(ctx, contextkeys.UserIDCtxKey, tokenClaims.UserId)
+				ctx = context.WithValue(ctx, contextkeys.RoleIDCtxKey, tokenClaims.RoleId)
+			}
+			if tokenClaims.ClientId != "" {
+				ctx = context.WithValue(ctx, contextkeys.ClientIDCtxKey, tokenClaims.ClientId)
+			}
 			c.SetRequest(c.Request().WithContext(ctx))
 
 			return next(c)
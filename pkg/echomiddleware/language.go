@@ -0,0 +1,74 @@
+package echomiddleware
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/phenirain/sso/pkg/contextkeys"
+)
+
+// Language выбирает язык ответа из Accept-Language среди supported и кладёт его в контекст.
+// Если ни один не подошёл - fallback
+func Language(supported []string, fallback string) echo.MiddlewareFunc {
+	allowed := make(map[string]struct{}, len(supported))
+	for _, lang := range supported {
+		allowed[lang] = struct{}{}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			lang := negotiate(c.Request().Header.Get("Accept-Language"), allowed, fallback)
+
+			ctx := context.WithValue(c.Request().Context(), contextkeys.LanguageCtxKey, lang)
+			c.SetRequest(c.Request().WithContext(ctx))
+			c.Response().Header().Set("Content-Language", lang)
+
+			return next(c)
+		}
+	}
+}
+
+type weightedLanguage struct {
+	tag string
+	q   float64
+}
+
+// negotiate разбирает "kk-KZ,ru;q=0.9,en;q=0.8" и возвращает первый поддерживаемый язык по убыванию q.
+// Регион не учитывается: kk-KZ - это kk
+func negotiate(header string, allowed map[string]struct{}, fallback string) string {
+	if header == "" {
+		return fallback
+	}
+
+	languages := make([]weightedLanguage, 0, 4)
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if tag == "" || q <= 0 {
+			continue
+		}
+		primary, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		languages = append(languages, weightedLanguage{tag: primary, q: q})
+	}
+	sort.SliceStable(languages, func(i, j int) bool { return languages[i].q > languages[j].q })
+
+	for _, lang := range languages {
+		if lang.tag == "*" {
+			return fallback
+		}
+		if _, ok := allowed[lang.tag]; ok {
+			return lang.tag
+		}
+	}
+	return fallback
+}
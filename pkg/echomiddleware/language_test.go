@@ -0,0 +1,31 @@
+package echomiddleware
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	allowed := map[string]struct{}{"ru": {}, "en": {}, "kk": {}}
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"empty header", "", "ru"},
+		{"single language", "en", "en"},
+		{"region is ignored", "kk-KZ,ru;q=0.9", "kk"},
+		{"tag case is ignored", "EN-us", "en"},
+		{"unsupported first", "fr, en;q=0.5", "en"},
+		{"higher q wins over order", "ru;q=0.1, en;q=0.9", "en"},
+		{"equal q keeps header order", "en, kk", "en"},
+		{"zero q is refused", "en;q=0", "ru"},
+		{"malformed q is skipped", "en;q=abc, kk;q=0.5", "kk"},
+		{"wildcard gives fallback", "*, en;q=0.5", "ru"},
+		{"nothing supported", "de, fr;q=0.8", "ru"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := negotiate(tt.header, allowed, "ru"); got != tt.want {
+				t.Errorf("negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
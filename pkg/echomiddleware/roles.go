@@ -0,0 +1,30 @@
+package echomiddleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/phenirain/sso/pkg/contextkeys"
+)
+
+// RequireRoles пропускает запрос дальше, только если роль из токена входит в roles.
+// Должен стоять после JwtValidation, которая кладёт роль в контекст
+func RequireRoles(roles ...int64) echo.MiddlewareFunc {
+	allowed := make(map[int64]struct{}, len(roles))
+	for _, role := range roles {
+		allowed[role] = struct{}{}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			roleId, ok := c.Request().Context().Value(contextkeys.RoleIDCtxKey).(int64)
+			if !ok {
+				return echo.ErrUnauthorized
+			}
+
+			if _, ok := allowed[roleId]; !ok {
+				return echo.ErrForbidden
+			}
+
+			return next(c)
+		}
+	}
+}
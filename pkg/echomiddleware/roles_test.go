@@ -0,0 +1,58 @@
+package echomiddleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/phenirain/sso/pkg/contextkeys"
+)
+
+func TestRequireRoles(t *testing.T) {
+	tests := []struct {
+		name string
+		// ctx — то, что положила в контекст JwtValidation
+		ctx     func(ctx context.Context) context.Context
+		wantErr error
+	}{
+		{"no role in context", func(ctx context.Context) context.Context {
+			return ctx
+		}, echo.ErrUnauthorized},
+		{"wrong role", func(ctx context.Context) context.Context {
+			return context.WithValue(ctx, contextkeys.RoleIDCtxKey, int64(1))
+		}, echo.ErrForbidden},
+		{"allowed role", func(ctx context.Context) context.Context {
+			return context.WithValue(ctx, contextkeys.RoleIDCtxKey, int64(2))
+		}, nil},
+		{"another allowed role", func(ctx context.Context) context.Context {
+			return context.WithValue(ctx, contextkeys.RoleIDCtxKey, int64(3))
+		}, nil},
+		// у сервисного токена нет пользователя и роли - только client_id
+		{"service token without role", func(ctx context.Context) context.Context {
+			return context.WithValue(ctx, contextkeys.ClientIDCtxKey, "worker")
+		}, echo.ErrUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req = req.WithContext(tt.ctx(req.Context()))
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+
+			called := false
+			handler := RequireRoles(2, 3)(func(c echo.Context) error {
+				called = true
+				return nil
+			})
+
+			if err := handler(c); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			if called != (tt.wantErr == nil) {
+				t.Errorf("next called: %v", called)
+			}
+		})
+	}
+}